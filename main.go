@@ -1,32 +1,110 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/goioc/di"
+	"github.com/sdedovic/wgsltoy-server/src/go/config"
 	"github.com/sdedovic/wgsltoy-server/src/go/db"
+	"github.com/sdedovic/wgsltoy-server/src/go/logging"
+	"github.com/sdedovic/wgsltoy-server/src/go/mail"
+	"github.com/sdedovic/wgsltoy-server/src/go/metrics"
 	shaderService "github.com/sdedovic/wgsltoy-server/src/go/service/shader"
 	userService "github.com/sdedovic/wgsltoy-server/src/go/service/user"
 	"github.com/sdedovic/wgsltoy-server/src/go/web"
 	"github.com/sdedovic/wgsltoy-server/src/go/web/shader"
 	"github.com/sdedovic/wgsltoy-server/src/go/web/user"
-	"log"
+	"github.com/sdedovic/wgsltoy-server/src/go/web/wgsl"
+	"github.com/sdedovic/wgsltoy-server/src/sql/migrations"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
 	"reflect"
+	"sync"
+	"syscall"
+	"time"
 )
 
 func run() error {
+	// load configuration, refusing to start when it is incomplete. Subcommands only need the database to be configured.
+	cfg, args, err := config.Load(os.Args[1:], os.Stderr, os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to load configuration caused by: %w", err)
+	}
+	if len(args) > 0 {
+		err = cfg.ValidateDatabase()
+	} else {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		return err
+	}
+
+	// log structured lines from here on, including those written through the standard log package
+	logger, err := logging.New(cfg.Log, os.Stderr)
+	if err != nil {
+		return fmt.Errorf("unable to configure logging caused by: %w", err)
+	}
+	slog.SetDefault(logger)
+
 	// set up Postgres connection
-	pgClient, err := db.InitializePgClient()
+	pgClient, err := db.InitializePgClient(cfg.Database)
 	if err != nil {
 		return fmt.Errorf("unable to connect to database caused by: %w", err)
 	}
 	defer db.CloseStorageDb(pgClient)
 
+	// run a subcommand instead of the server when one is given
+	if len(args) > 0 {
+		switch args[0] {
+		case "migrate":
+			return runMigrate(pgClient, args[1:])
+		default:
+			return fmt.Errorf("unknown command '%s'", args[0])
+		}
+	}
+
+	// bring the schema up to date, or refuse to start when it is behind
+	migrator, err := db.NewMigrator(pgClient, migrations.FS)
+	if err != nil {
+		return fmt.Errorf("unable to load migrations caused by: %w", err)
+	}
+	if cfg.Database.MigrateOnStart {
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			return fmt.Errorf("unable to migrate database caused by: %w", err)
+		}
+		for _, migration := range applied {
+			slog.Info("Applied migration", "version", migration.Version, "name", migration.Name)
+		}
+	}
+	if err = migrator.CheckSchema(context.Background()); err != nil {
+		return err
+	}
+
 	// Initialize IOC container
+	_, err = di.RegisterBeanInstance("Config", cfg)
+	if err != nil {
+		return fmt.Errorf("unable to register Config: %w", err)
+	}
 	_, err = di.RegisterBeanInstance("PgClient", &pgClient)
 	if err != nil {
 		return fmt.Errorf("unable to register PgClient: %w", err)
 	}
+	mailer, err := mail.NewMailer(cfg.Mail)
+	if err != nil {
+		return fmt.Errorf("unable to configure mail transport caused by: %w", err)
+	}
+	_, err = di.RegisterBeanInstance("Mailer", mailer)
+	if err != nil {
+		return fmt.Errorf("unable to register Mailer: %w", err)
+	}
 	_, err = di.RegisterBean("Repository", reflect.TypeOf((*db.Repository)(nil)))
 	if err != nil {
 		return fmt.Errorf("unable to register Repository: %w", err)
@@ -51,34 +129,127 @@ func run() error {
 		return fmt.Errorf("unable to connect to initialize application caused by: %w", err)
 	}
 
+	// resolve users of personal access tokens and reject access tokens whose session was revoked
+	web.SetAuthenticator(di.GetInstance("UserService").(*userService.Service))
+	web.SetRequestTimeout(cfg.Server.RequestTimeout)
+
 	// register route handlers
 	http.HandleFunc("/health", web.HealthCheck())
+	http.HandleFunc("/wgsl/validate", wgsl.Validate())
 
 	userController := di.GetInstance("UserController").(*user.Controller)
 	http.HandleFunc("/user/register", userController.UserRegister())
 	http.HandleFunc("/user/login", userController.UserLogin())
 	http.HandleFunc("/user/me", userController.UserMe())
+	http.HandleFunc("/user/verify-email", userController.UserVerifyEmail())
+	http.HandleFunc("/user/verify-email/resend", userController.UserVerifyEmailResend())
+	http.HandleFunc("/user/password/forgot", userController.UserPasswordForgot())
+	http.HandleFunc("/user/password/reset", userController.UserPasswordReset())
+	http.HandleFunc("/user/me/password", userController.UserMePassword())
+	http.HandleFunc("/user/token/refresh", userController.UserTokenRefresh())
+	http.HandleFunc("/user/logout", userController.UserLogout())
+	http.HandleFunc("/user/me/sessions", userController.UserMeSessions())
+	http.HandleFunc("/user/me/sessions/{id}", userController.UserMeSessionById())
+	http.HandleFunc("/user/me/tokens", userController.UserMeTokens())
+	http.HandleFunc("/user/me/tokens/{id}", userController.UserMeTokenById())
+	http.HandleFunc("/user/{username}", userController.UserProfile())
 
 	shaderController := di.GetInstance("ShaderController").(*shader.Controller)
-	http.HandleFunc("/shader", shaderController.ShaderCreate())
+	http.HandleFunc("/shader", shaderController.Shaders())
+	http.HandleFunc("/shader/search", web.WithTimeout(cfg.Server.SearchTimeout, shaderController.ShaderSearch()))
+	http.HandleFunc("/user/me/shader", shaderController.ShaderInfoListOwn())
 	http.HandleFunc("/user/me/shader/", shaderController.ShaderInfoListOwn())
+	http.HandleFunc("/user/me/shader/trash", shaderController.ShaderInfoListTrash())
+	http.HandleFunc("/user/me/likes", shaderController.ShaderInfoListLikes())
+	http.HandleFunc("/user/me/collections", shaderController.CollectionListOwn())
+	http.HandleFunc("/user/{username}/shader", shaderController.ShaderInfoListByUser())
 	http.HandleFunc("/shader/{id}", shaderController.ShaderById())
+	http.HandleFunc("/shader/{id}/restore", shaderController.ShaderRestore())
+	http.HandleFunc("/shader/{id}/fork", shaderController.ShaderFork())
+	http.HandleFunc("/shader/{id}/forks", shaderController.ShaderInfoListForks())
+	http.HandleFunc("/shader/{id}/like", shaderController.ShaderLike())
+	http.HandleFunc("/shader/{id}/collaborators", shaderController.ShaderCollaborators())
+	http.HandleFunc("/shader/{id}/collaborators/{username}", shaderController.ShaderCollaboratorByUsername())
+	http.HandleFunc("/shader/{id}/transfer", shaderController.ShaderTransfer())
+	http.HandleFunc("/shader/{id}/share-links", shaderController.ShaderShareLinks())
+	http.HandleFunc("/shader/{id}/share-links/{linkId}", shaderController.ShaderShareLinkById())
+	http.HandleFunc("/s/{token}", shaderController.ShaderByShareToken())
+	http.HandleFunc("/shader/{id}/comments", shaderController.ShaderComments())
+	http.HandleFunc("/shader/{id}/comments/{commentId}", shaderController.ShaderCommentById())
+	http.HandleFunc("/shader/{id}/comments/{commentId}/hidden", shaderController.ShaderCommentHidden())
+	http.HandleFunc("/shader/{id}/revision", shaderController.ShaderRevisionList())
+	http.HandleFunc("/shader/{id}/revision/{revision}", shaderController.ShaderRevisionById())
+	http.HandleFunc("/shader/{id}/revision/{revision}/revert", shaderController.ShaderRevert())
+	http.HandleFunc("/collection", shaderController.Collections())
+	http.HandleFunc("/collection/{id}", shaderController.CollectionById())
+	http.HandleFunc("/collection/{id}/items", shaderController.CollectionItems())
+	http.HandleFunc("/collection/{id}/items/{shaderId}", shaderController.CollectionItemById())
+
+	// stop on SIGINT or SIGTERM, as sent by container orchestrators
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	// start server
-	log.Println("INFO", "Starting server on 0.0.0.0:8080")
-	err = http.ListenAndServe(":8080", nil)
+	// start background jobs, waiting for them to stop before the database connection is closed
+	var jobs sync.WaitGroup
+	jobsCtx, cancelJobs := context.WithCancel(ctx)
+	defer jobs.Wait()
+	defer cancelJobs()
+
+	shaderSvc := di.GetInstance("ShaderService").(*shaderService.Service)
+	jobs.Add(1)
+	go func() {
+		defer jobs.Done()
+		shaderSvc.SweepTrash(jobsCtx, cfg.Shader.TrashRetention, time.Hour)
+	}()
+
+	// expose metrics, on a listener of their own when one is configured
+	db.RegisterPoolMetrics(pgClient)
+	var metricsServer *http.Server
+	if cfg.Server.MetricsAddress == "" {
+		http.Handle("/metrics", metrics.Handler(metrics.Default))
+	} else {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", metrics.Handler(metrics.Default))
+		metricsConfig := cfg.Server
+		metricsConfig.ListenAddress = cfg.Server.MetricsAddress
+		metricsServer = web.NewServer(metricsConfig, metricsMux)
+	}
+
+	// start servers, stopping both when either fails
+	serveCtx, cancelServe := context.WithCancel(ctx)
+	defer cancelServe()
+
+	metricsErr := make(chan error, 1)
+	if metricsServer != nil {
+		slog.Info("Starting metrics server", "address", cfg.Server.MetricsAddress)
+		go func() {
+			err := web.Serve(serveCtx, metricsServer, cfg.Server.ShutdownTimeout)
+			cancelServe()
+			metricsErr <- err
+		}()
+	} else {
+		metricsErr <- nil
+	}
+
+	slog.Info("Starting server", "address", cfg.Server.ListenAddress)
+	server := web.NewServer(cfg.Server, http.DefaultServeMux)
+	err = web.Serve(serveCtx, server, cfg.Server.ShutdownTimeout)
+	cancelServe()
+	if metricsServeErr := <-metricsErr; err == nil {
+		err = metricsServeErr
+	}
 	if err != nil {
 		return err
 	}
 
+	slog.Info("Server stopped")
 	return nil
 }
 
 func main() {
-	log.SetFlags(log.Lshortfile)
-
 	err := run()
 	if err != nil {
-		log.Println("FATAL", err)
+		slog.Error("Fatal error", "error", err)
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sdedovic/wgsltoy-server/src/go/db"
+	"github.com/sdedovic/wgsltoy-server/src/sql/migrations"
+	"log/slog"
+	"strconv"
+)
+
+const migrateUsage = "usage: wgsltoy-server [flags] migrate up | down [steps] | status | force <version>"
+
+// runMigrate runs the 'migrate' subcommand, managing the database schema with the migrations embedded in the binary
+func runMigrate(pgClient db.PgClient, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	migrator, err := db.NewMigrator(pgClient, migrations.FS)
+	if err != nil {
+		return fmt.Errorf("unable to load migrations caused by: %w", err)
+	}
+	ctx := context.Background()
+
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			slog.Info("Applied migration", "version", migration.Version, "name", migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			slog.Info("No pending migrations")
+		}
+		return nil
+
+	case args[0] == "down" && len(args) <= 2:
+		// revert a single migration unless told otherwise, reverting everything by accident loses all data
+		steps := 1
+		if len(args) == 2 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("steps must be a positive number, got '%s'", args[1])
+			}
+		}
+
+		reverted, err := migrator.Down(ctx, steps)
+		for _, migration := range reverted {
+			slog.Info("Reverted migration", "version", migration.Version, "name", migration.Name)
+		}
+		return err
+
+	case args[0] == "status" && len(args) == 1:
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		slog.Info("Schema version", "version", status.Version, "latest", status.Latest, "dirty", status.Dirty)
+		for _, migration := range status.Pending {
+			slog.Info("Pending migration", "version", migration.Version, "name", migration.Name)
+		}
+		return nil
+
+	case args[0] == "force" && len(args) == 2:
+		version, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("version must be a number, got '%s'", args[1])
+		}
+		err = migrator.Force(ctx, uint(version))
+		if err != nil {
+			return err
+		}
+		slog.Info("Forced schema version", "version", version)
+		return nil
+
+	default:
+		return errors.New(migrateUsage)
+	}
+}
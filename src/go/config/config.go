@@ -0,0 +1,435 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the configuration of the server. It is assembled from defaults, an optional YAML file, environment
+// variables and command line flags, each taking precedence over the ones before.
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	Auth     AuthConfig     `yaml:"auth"`
+	Mail     MailConfig     `yaml:"mail"`
+	Shader   ShaderConfig   `yaml:"shader"`
+	Limits   LimitsConfig   `yaml:"limits"`
+	Log      LogConfig      `yaml:"log"`
+}
+
+type ServerConfig struct {
+	ListenAddress string `yaml:"listenAddress" env:"LISTEN_ADDRESS"`
+
+	// MetricsAddress is where metrics are served on a listener of their own, so they need not be public. When empty
+	// they are served from ListenAddress instead.
+	MetricsAddress string `yaml:"metricsAddress" env:"METRICS_LISTEN_ADDRESS"`
+
+	// AppUrl is where the frontend is hosted, used to build links in emails
+	AppUrl string `yaml:"appUrl" env:"APP_URL"`
+
+	ReadTimeout       time.Duration `yaml:"readTimeout" env:"SERVER_READ_TIMEOUT"`
+	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"writeTimeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idleTimeout" env:"SERVER_IDLE_TIMEOUT"`
+	MaxHeaderBytes    int           `yaml:"maxHeaderBytes" env:"SERVER_MAX_HEADER_BYTES"`
+
+	// RequestTimeout bounds the processing of a request, cancelling its database queries once it passes. SearchTimeout
+	// replaces it for full text search, which is cheap to retry but expensive to run.
+	RequestTimeout time.Duration `yaml:"requestTimeout" env:"SERVER_REQUEST_TIMEOUT"`
+	SearchTimeout  time.Duration `yaml:"searchTimeout" env:"SERVER_SEARCH_TIMEOUT"`
+
+	// ShutdownTimeout is how long in-flight requests may take to finish once the server is asked to stop
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+}
+
+type DatabaseConfig struct {
+	Url             string        `yaml:"url" env:"DATABASE_URL"`
+	MaxConns        int32         `yaml:"maxConns" env:"DATABASE_MAX_CONNS"`
+	MinConns        int32         `yaml:"minConns" env:"DATABASE_MIN_CONNS"`
+	MaxConnLifetime time.Duration `yaml:"maxConnLifetime" env:"DATABASE_MAX_CONN_LIFETIME"`
+	MaxConnIdleTime time.Duration `yaml:"maxConnIdleTime" env:"DATABASE_MAX_CONN_IDLE_TIME"`
+	ConnectTimeout  time.Duration `yaml:"connectTimeout" env:"DATABASE_CONNECT_TIMEOUT"`
+
+	// OperationTimeout bounds every query the repository makes
+	OperationTimeout time.Duration `yaml:"operationTimeout" env:"DATABASE_OPERATION_TIMEOUT"`
+
+	// MigrateOnStart applies pending migrations before serving, otherwise the server refuses to start on an outdated schema
+	MigrateOnStart bool `yaml:"migrateOnStart" env:"DATABASE_MIGRATE_ON_START"`
+}
+
+type AuthConfig struct {
+	// AppSecret signs access and email verification tokens, it must be kept private
+	AppSecret string `yaml:"appSecret" env:"APP_SECRET"`
+
+	AccessTokenLifetime        time.Duration `yaml:"accessTokenLifetime" env:"ACCESS_TOKEN_LIFETIME"`
+	RefreshTokenLifetime       time.Duration `yaml:"refreshTokenLifetime" env:"REFRESH_TOKEN_LIFETIME"`
+	VerificationTokenLifetime  time.Duration `yaml:"verificationTokenLifetime" env:"VERIFICATION_TOKEN_LIFETIME"`
+	PasswordResetTokenLifetime time.Duration `yaml:"passwordResetTokenLifetime" env:"PASSWORD_RESET_TOKEN_LIFETIME"`
+}
+
+type MailConfig struct {
+	// Transport is one of 'log', 'file' or 'smtp'
+	Transport    string `yaml:"transport" env:"MAIL_TRANSPORT"`
+	From         string `yaml:"from" env:"MAIL_FROM"`
+	Dir          string `yaml:"dir" env:"MAIL_DIR"`
+	SmtpHost     string `yaml:"smtpHost" env:"SMTP_HOST"`
+	SmtpPort     string `yaml:"smtpPort" env:"SMTP_PORT"`
+	SmtpUsername string `yaml:"smtpUsername" env:"SMTP_USERNAME"`
+	SmtpPassword string `yaml:"smtpPassword" env:"SMTP_PASSWORD"`
+}
+
+type ShaderConfig struct {
+	// TrashRetention is how long a deleted shader stays in the trash before being purged permanently
+	TrashRetention                time.Duration `yaml:"trashRetention" env:"SHADER_TRASH_RETENTION"`
+	RequireVerifiedEmailToPublish bool          `yaml:"requireVerifiedEmailToPublish" env:"REQUIRE_VERIFIED_EMAIL_TO_PUBLISH"`
+}
+
+// LimitsConfig holds the limits user supplied inputs are validated against, lengths are counted in characters
+type LimitsConfig struct {
+	ShaderNameLength        int `yaml:"shaderNameLength" env:"LIMIT_SHADER_NAME_LENGTH"`
+	ShaderDescriptionLength int `yaml:"shaderDescriptionLength" env:"LIMIT_SHADER_DESCRIPTION_LENGTH"`
+	ShaderContentLength     int `yaml:"shaderContentLength" env:"LIMIT_SHADER_CONTENT_LENGTH"`
+	CommentLength           int `yaml:"commentLength" env:"LIMIT_COMMENT_LENGTH"`
+	CollectionItems         int `yaml:"collectionItems" env:"LIMIT_COLLECTION_ITEMS"`
+	BioLength               int `yaml:"bioLength" env:"LIMIT_BIO_LENGTH"`
+}
+
+type LogConfig struct {
+	// Level is the least severe level logged, one of 'debug', 'info', 'warn' or 'error'
+	Level string `yaml:"level" env:"LOG_LEVEL"`
+
+	// Format is one of 'json' or 'text'
+	Format string `yaml:"format" env:"LOG_FORMAT"`
+}
+
+// Default is the configuration used for anything not configured otherwise. It lacks the database URL and app secret,
+// which have no sensible defaults.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			ListenAddress:     ":8080",
+			AppUrl:            "https://wgsltoy.com",
+			ReadTimeout:       30 * time.Second,
+			ReadHeaderTimeout: 10 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			MaxHeaderBytes:    1 << 20,
+			RequestTimeout:    30 * time.Second,
+			SearchTimeout:     10 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Database: DatabaseConfig{
+			MaxConns:         10,
+			MinConns:         0,
+			MaxConnLifetime:  time.Hour,
+			MaxConnIdleTime:  30 * time.Minute,
+			ConnectTimeout:   40 * time.Second,
+			OperationTimeout: 40 * time.Second,
+		},
+		Auth: AuthConfig{
+			AccessTokenLifetime:        15 * time.Minute,
+			RefreshTokenLifetime:       30 * 24 * time.Hour,
+			VerificationTokenLifetime:  48 * time.Hour,
+			PasswordResetTokenLifetime: time.Hour,
+		},
+		Mail: MailConfig{
+			Transport: "log",
+			From:      "WGSL Toy <no-reply@wgsltoy.com>",
+			SmtpPort:  "587",
+		},
+		Shader: ShaderConfig{
+			TrashRetention: 30 * 24 * time.Hour,
+		},
+		Limits: LimitsConfig{
+			ShaderNameLength:        160,
+			ShaderDescriptionLength: 480,
+			ShaderContentLength:     5250,
+			CommentLength:           2000,
+			CollectionItems:         200,
+			BioLength:               500,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+	}
+}
+
+// Load assembles the configuration from the command line args, without the program name, and the environment. The
+// YAML file is read from the path given by the '-config' flag or the CONFIG_FILE environment variable, every other
+// setting has a flag named after its environment variable. The args remaining after the flags, such as a subcommand,
+// are returned as well. The configuration is not validated, as what it needs to hold depends on the subcommand.
+//
+// Problems with the flags are reported to output along with the usage, which is all '-h' prints. In that case the
+// error is flag.ErrHelp.
+func Load(args []string, output io.Writer, lookupEnv func(string) (string, bool)) (*Config, []string, error) {
+	config := Default()
+
+	flags := flag.NewFlagSet("wgsltoy-server", flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.Usage = func() {
+		fmt.Fprintf(output, "usage: wgsltoy-server [flags] [migrate up | down [steps] | status | force <version>]\n\nflags:\n")
+		flags.PrintDefaults()
+	}
+	configFile := flags.String("config", "", "path of a YAML configuration file")
+	var flagValues []func()
+	registerFlags(flags, reflect.ValueOf(config).Elem(), "", &flagValues)
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, fmt.Errorf("failed parsing flags caused by: %w", err)
+	}
+
+	path := *configFile
+	if path == "" {
+		path, _ = lookupEnv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := config.loadFile(path); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := loadEnv(reflect.ValueOf(config).Elem(), lookupEnv); err != nil {
+		return nil, nil, err
+	}
+
+	for _, apply := range flagValues {
+		apply()
+	}
+
+	return config, flags.Args(), nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed reading config file caused by: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err = decoder.Decode(c)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed parsing config file %s caused by: %w", path, err)
+	}
+	return nil
+}
+
+// loadEnv overrides the fields of value tagged with 'env' whose environment variable is set
+func loadEnv(value reflect.Value, lookupEnv func(string) (string, bool)) error {
+	for idx := 0; idx < value.NumField(); idx++ {
+		field := value.Field(idx)
+		structField := value.Type().Field(idx)
+
+		if field.Kind() == reflect.Struct && field.Type() != reflect.TypeOf(time.Duration(0)) {
+			if err := loadEnv(field, lookupEnv); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := structField.Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		raw, ok := lookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		if err := setField(field, raw); err != nil {
+			return fmt.Errorf("invalid value for %s caused by: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// flagAliases are the names flags had before every setting could be given on the command line, by environment variable
+var flagAliases = map[string]string{
+	"LISTEN_ADDRESS":            "listen",
+	"DATABASE_MIGRATE_ON_START": "migrate-on-start",
+}
+
+// registerFlags adds a flag for every field of value tagged with 'env', named after its environment variable in lower
+// case with dashes, such as '-smtp-host' for SMTP_HOST. Flags are parsed before the file and environment are loaded,
+// so the values given are appended to apply and set once those are.
+func registerFlags(flags *flag.FlagSet, value reflect.Value, path string, apply *[]func()) {
+	for idx := 0; idx < value.NumField(); idx++ {
+		field := value.Field(idx)
+		structField := value.Type().Field(idx)
+		fieldPath := path + structField.Tag.Get("yaml")
+
+		if field.Kind() == reflect.Struct && field.Type() != reflect.TypeOf(time.Duration(0)) {
+			registerFlags(flags, field, fieldPath+".", apply)
+			continue
+		}
+
+		env := structField.Tag.Get("env")
+		if env == "" {
+			continue
+		}
+
+		// the back-quoted kind names the value of the flag in its usage
+		usage := fmt.Sprintf("sets %s, overrides %s", fieldPath, env)
+		if field.Kind() != reflect.Bool {
+			kind := "string"
+			if field.Type() == reflect.TypeOf(time.Duration(0)) {
+				kind = "duration"
+			} else if field.Kind() != reflect.String {
+				kind = "int"
+			}
+			usage = fmt.Sprintf("sets %s to the given `%s`, overrides %s", fieldPath, kind, env)
+		}
+		set := func(raw string) error {
+			parsed := reflect.New(field.Type()).Elem()
+			if err := setField(parsed, raw); err != nil {
+				return err
+			}
+			*apply = append(*apply, func() { field.Set(parsed) })
+			return nil
+		}
+
+		names := []string{strings.ReplaceAll(strings.ToLower(env), "_", "-")}
+		if alias, ok := flagAliases[env]; ok {
+			names = append(names, alias)
+		}
+		for _, name := range names {
+			if field.Kind() == reflect.Bool {
+				flags.BoolFunc(name, usage, set)
+			} else {
+				flags.Func(name, usage, set)
+			}
+		}
+	}
+}
+
+func setField(field reflect.Value, raw string) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(duration))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(parsed)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(parsed)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// Validate checks the configuration is complete and consistent, so the server fails at startup rather than later
+func (c *Config) Validate() error {
+	var found problems
+	check := found.check
+	c.checkDatabase(check)
+
+	check(c.Server.ListenAddress != "", "server.listenAddress (LISTEN_ADDRESS) may not be empty")
+	check(c.Server.AppUrl != "", "server.appUrl (APP_URL) may not be empty")
+	check(c.Server.MetricsAddress != c.Server.ListenAddress, "server.metricsAddress (METRICS_LISTEN_ADDRESS) must differ from server.listenAddress")
+	check(c.Server.ReadTimeout > 0, "server.readTimeout (SERVER_READ_TIMEOUT) must be positive")
+	check(c.Server.ReadHeaderTimeout > 0, "server.readHeaderTimeout (SERVER_READ_HEADER_TIMEOUT) must be positive")
+	check(c.Server.WriteTimeout > 0, "server.writeTimeout (SERVER_WRITE_TIMEOUT) must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idleTimeout (SERVER_IDLE_TIMEOUT) must be positive")
+	check(c.Server.MaxHeaderBytes > 0, "server.maxHeaderBytes (SERVER_MAX_HEADER_BYTES) must be positive")
+	check(c.Server.RequestTimeout > 0 && c.Server.RequestTimeout <= c.Server.WriteTimeout, "server.requestTimeout (SERVER_REQUEST_TIMEOUT) must be positive and at most server.writeTimeout")
+	check(c.Server.SearchTimeout > 0 && c.Server.SearchTimeout <= c.Server.WriteTimeout, "server.searchTimeout (SERVER_SEARCH_TIMEOUT) must be positive and at most server.writeTimeout")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdownTimeout (SERVER_SHUTDOWN_TIMEOUT) must be positive")
+
+	check(c.Auth.AppSecret != "", "auth.appSecret (APP_SECRET) is required")
+	check(c.Auth.AccessTokenLifetime > 0, "auth.accessTokenLifetime (ACCESS_TOKEN_LIFETIME) must be positive")
+	check(c.Auth.RefreshTokenLifetime > 0, "auth.refreshTokenLifetime (REFRESH_TOKEN_LIFETIME) must be positive")
+	check(c.Auth.VerificationTokenLifetime > 0, "auth.verificationTokenLifetime (VERIFICATION_TOKEN_LIFETIME) must be positive")
+	check(c.Auth.PasswordResetTokenLifetime > 0, "auth.passwordResetTokenLifetime (PASSWORD_RESET_TOKEN_LIFETIME) must be positive")
+
+	switch c.Mail.Transport {
+	case "log":
+	case "file":
+		check(c.Mail.Dir != "", "mail.dir (MAIL_DIR) is required when mail.transport is 'file'")
+	case "smtp":
+		check(c.Mail.SmtpHost != "", "mail.smtpHost (SMTP_HOST) is required when mail.transport is 'smtp'")
+		check(c.Mail.SmtpPort != "", "mail.smtpPort (SMTP_PORT) is required when mail.transport is 'smtp'")
+	default:
+		check(false, "mail.transport (MAIL_TRANSPORT) must be one of 'log', 'file' or 'smtp'")
+	}
+	check(c.Mail.From != "", "mail.from (MAIL_FROM) may not be empty")
+
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		check(false, "log.level (LOG_LEVEL) must be one of 'debug', 'info', 'warn' or 'error'")
+	}
+	switch strings.ToLower(c.Log.Format) {
+	case "json", "text":
+	default:
+		check(false, "log.format (LOG_FORMAT) must be one of 'json' or 'text'")
+	}
+
+	check(c.Shader.TrashRetention > 0, "shader.trashRetention (SHADER_TRASH_RETENTION) must be positive")
+
+	check(c.Limits.ShaderNameLength > 0, "limits.shaderNameLength (LIMIT_SHADER_NAME_LENGTH) must be positive")
+	check(c.Limits.ShaderDescriptionLength > 0, "limits.shaderDescriptionLength (LIMIT_SHADER_DESCRIPTION_LENGTH) must be positive")
+	check(c.Limits.ShaderContentLength > 0, "limits.shaderContentLength (LIMIT_SHADER_CONTENT_LENGTH) must be positive")
+	check(c.Limits.CommentLength > 0, "limits.commentLength (LIMIT_COMMENT_LENGTH) must be positive")
+	check(c.Limits.CollectionItems > 0, "limits.collectionItems (LIMIT_COLLECTION_ITEMS) must be positive")
+	check(c.Limits.BioLength > 0, "limits.bioLength (LIMIT_BIO_LENGTH) must be positive")
+
+	return found.err()
+}
+
+// ValidateDatabase checks only the database settings, which is all commands other than serving depend on
+func (c *Config) ValidateDatabase() error {
+	var found problems
+	c.checkDatabase(found.check)
+	return found.err()
+}
+
+func (c *Config) checkDatabase(check func(ok bool, format string, args ...any)) {
+	check(c.Database.Url != "", "database.url (DATABASE_URL) is required")
+	check(c.Database.MaxConns > 0, "database.maxConns (DATABASE_MAX_CONNS) must be positive")
+	check(c.Database.MinConns >= 0 && c.Database.MinConns <= c.Database.MaxConns, "database.minConns (DATABASE_MIN_CONNS) must be between 0 and database.maxConns")
+	check(c.Database.MaxConnLifetime > 0, "database.maxConnLifetime (DATABASE_MAX_CONN_LIFETIME) must be positive")
+	check(c.Database.MaxConnIdleTime > 0, "database.maxConnIdleTime (DATABASE_MAX_CONN_IDLE_TIME) must be positive")
+	check(c.Database.ConnectTimeout > 0, "database.connectTimeout (DATABASE_CONNECT_TIMEOUT) must be positive")
+	check(c.Database.OperationTimeout > 0, "database.operationTimeout (DATABASE_OPERATION_TIMEOUT) must be positive")
+}
+
+// problems collects what is wrong with a configuration, to report everything at once
+type problems []error
+
+func (p *problems) check(ok bool, format string, args ...any) {
+	if !ok {
+		*p = append(*p, fmt.Errorf(format, args...))
+	}
+}
+
+func (p problems) err() error {
+	if len(p) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(p...))
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func envOf(values map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := values[name]
+		return value, ok
+	}
+}
+
+func TestLoad_Environment(t *testing.T) {
+	config, _, err := Load(nil, io.Discard, envOf(map[string]string{
+		"APP_SECRET":                 "secret",
+		"DATABASE_URL":               "postgres://localhost/wgsltoy",
+		"DATABASE_OPERATION_TIMEOUT": "5s",
+		"LIMIT_COMMENT_LENGTH":       "100",
+	}))
+	assert.NoError(t, err)
+	assert.NoError(t, config.Validate())
+	assert.Equal(t, "secret", config.Auth.AppSecret)
+	assert.Equal(t, 5*time.Second, config.Database.OperationTimeout)
+	assert.Equal(t, 100, config.Limits.CommentLength)
+	assert.Equal(t, Default().Server.ListenAddress, config.Server.ListenAddress)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("server:\n  listenAddress: ':9000'\n  appUrl: https://example.com\nauth:\n  appSecret: from-file\ndatabase:\n  url: postgres://file/wgsltoy\n"), 0o600)
+	assert.NoError(t, err)
+
+	config, args, err := Load([]string{"-config", path, "-listen", ":9100", "migrate", "status"}, io.Discard, envOf(map[string]string{
+		"APP_SECRET": "from-env",
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"migrate", "status"}, args)
+	assert.Equal(t, ":9100", config.Server.ListenAddress)
+	assert.Equal(t, "https://example.com", config.Server.AppUrl)
+	assert.Equal(t, "from-env", config.Auth.AppSecret)
+	assert.Equal(t, "postgres://file/wgsltoy", config.Database.Url)
+}
+
+func TestLoad_Flags(t *testing.T) {
+	config, args, err := Load([]string{
+		"-app-secret", "from-flag",
+		"-database-url", "postgres://flag/wgsltoy",
+		"-limit-comment-length", "100",
+		"-access-token-lifetime", "5m",
+		"-migrate-on-start",
+		"-mail-transport=smtp", "-smtp-host", "mail.example.com",
+		"migrate", "up",
+	}, io.Discard, envOf(map[string]string{
+		"APP_SECRET":           "from-env",
+		"LIMIT_COMMENT_LENGTH": "50",
+	}))
+	assert.NoError(t, err)
+	assert.NoError(t, config.Validate())
+	assert.Equal(t, []string{"migrate", "up"}, args)
+	assert.Equal(t, "from-flag", config.Auth.AppSecret)
+	assert.Equal(t, "postgres://flag/wgsltoy", config.Database.Url)
+	assert.Equal(t, 100, config.Limits.CommentLength)
+	assert.Equal(t, 5*time.Minute, config.Auth.AccessTokenLifetime)
+	assert.True(t, config.Database.MigrateOnStart)
+	assert.Equal(t, "smtp", config.Mail.Transport)
+	assert.Equal(t, "mail.example.com", config.Mail.SmtpHost)
+
+	_, _, err = Load([]string{"-access-token-lifetime", "soon"}, io.Discard, envOf(map[string]string{
+		"APP_SECRET":   "secret",
+		"DATABASE_URL": "postgres://localhost/wgsltoy",
+	}))
+	assert.Error(t, err)
+}
+
+func TestLoad_Help(t *testing.T) {
+	var output strings.Builder
+	_, _, err := Load([]string{"-h"}, &output, envOf(nil))
+	assert.True(t, errors.Is(err, flag.ErrHelp))
+	assert.Contains(t, output.String(), "-smtp-host string\n")
+	assert.Contains(t, output.String(), "sets mail.smtpHost to the given string, overrides SMTP_HOST")
+	assert.Contains(t, output.String(), "-access-token-lifetime duration\n")
+	assert.Contains(t, output.String(), "-migrate-on-start\n")
+
+	output.Reset()
+	_, _, err = Load([]string{"-no-such-flag"}, &output, envOf(nil))
+	assert.Error(t, err)
+	assert.Contains(t, output.String(), "flag provided but not defined: -no-such-flag")
+	assert.Contains(t, output.String(), "usage: wgsltoy-server")
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"empty secret", map[string]string{"APP_SECRET": "", "DATABASE_URL": "postgres://localhost/wgsltoy"}},
+		{"missing database", map[string]string{"APP_SECRET": "secret"}},
+		{"malformed duration", map[string]string{"APP_SECRET": "secret", "DATABASE_URL": "postgres://localhost/wgsltoy", "ACCESS_TOKEN_LIFETIME": "soon"}},
+		{"negative limit", map[string]string{"APP_SECRET": "secret", "DATABASE_URL": "postgres://localhost/wgsltoy", "LIMIT_BIO_LENGTH": "-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, _, err := Load(nil, io.Discard, envOf(tt.env))
+			if err == nil {
+				err = config.Validate()
+			}
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestValidateDatabase(t *testing.T) {
+	config, args, err := Load([]string{"migrate", "status"}, io.Discard, envOf(map[string]string{
+		"DATABASE_URL":   "postgres://localhost/wgsltoy",
+		"MAIL_TRANSPORT": "smtp",
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"migrate", "status"}, args)
+
+	// the app secret and mail settings only matter when serving
+	assert.NoError(t, config.ValidateDatabase())
+	assert.Error(t, config.Validate())
+
+	config.Database.Url = ""
+	assert.Error(t, config.ValidateDatabase())
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/sdedovic/wgsltoy-server/src/go/guid"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"time"
+)
+
+func (repo *Repository) AccessTokenCreate(ctx context.Context, userId string, name string, scopes []string, tokenHash string, expiresAt time.Time) (models.AccessToken, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	accessToken := models.AccessToken{
+		Id:        guid.New(),
+		UserId:    userId,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+		Name:      name,
+		Scopes:    scopes,
+		TokenHash: tokenHash,
+	}
+
+	sql, args, err := psql.Insert("access_tokens").
+		Columns("token_id", "user_id", "created_at", "expires_at", "name", "scopes", "token_hash").
+		Values(accessToken.Id, accessToken.UserId, accessToken.CreatedAt, accessToken.ExpiresAt, accessToken.Name, accessToken.Scopes, accessToken.TokenHash).
+		ToSql()
+	if err != nil {
+		return models.AccessToken{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	_, err = repo.pg.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return models.AccessToken{}, fmt.Errorf("failed inserting access token caused by: %w", err)
+	}
+
+	return accessToken, nil
+}
+
+// AccessTokenUse looks up the active access token with tokenHash, recording that it was used. It returns
+// infra.NotFoundError when the token does not exist, was revoked or has expired.
+func (repo *Repository) AccessTokenUse(ctx context.Context, tokenHash string) (models.AccessToken, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	now := time.Now()
+	sql, args, err := psql.Update("access_tokens").
+		Set("last_used_at", now).
+		Where(squirrel.Eq{"token_hash": tokenHash, "revoked_at": nil}).
+		Where(squirrel.Gt{"expires_at": now}).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return models.AccessToken{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	rows, _ := repo.pg.pool.Query(ctx, sql, args...)
+	accessToken, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.AccessToken])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.AccessToken{}, infra.NotFoundError
+		}
+		return models.AccessToken{}, fmt.Errorf("failed querying access token caused by: %w", err)
+	}
+
+	return accessToken, nil
+}
+
+// AccessTokenListActiveByUserId lists the access tokens of the user which were neither revoked nor have expired,
+// newest first
+func (repo *Repository) AccessTokenListActiveByUserId(ctx context.Context, userId string) ([]models.AccessToken, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.Select("*").
+		From("access_tokens").
+		Where(squirrel.Eq{"user_id": userId, "revoked_at": nil}).
+		Where(squirrel.Gt{"expires_at": time.Now()}).
+		OrderBy("created_at DESC").
+		Limit(100).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	rows, _ := repo.pg.pool.Query(ctx, sql, args...)
+	accessTokens, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.AccessToken])
+	if err != nil {
+		return nil, fmt.Errorf("failed querying access tokens caused by: %w", err)
+	}
+
+	return accessTokens, nil
+}
+
+// AccessTokenRevoke revokes an access token of the user, returning infra.NotFoundError when they have no such token
+func (repo *Repository) AccessTokenRevoke(ctx context.Context, tokenId string, userId string) error {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.Update("access_tokens").
+		Set("revoked_at", time.Now()).
+		Where(squirrel.Eq{"token_id": tokenId, "user_id": userId, "revoked_at": nil}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	tag, err := repo.pg.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed revoking access token caused by: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return infra.NotFoundError
+	}
+
+	return nil
+}
+
+// revokeAccessTokens revokes every active access token of the user
+func revokeAccessTokens(ctx context.Context, tx pgx.Tx, userId string, revokedAt time.Time) error {
+	sql, args, err := psql.Update("access_tokens").
+		Set("revoked_at", revokedAt).
+		Where(squirrel.Eq{"user_id": userId, "revoked_at": nil}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed revoking access tokens caused by: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"time"
+)
+
+func (repo *Repository) ShaderCollaboratorList(ctx context.Context, shaderId string) ([]models.Collaborator, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.
+		Select("shader_collaborators.user_id", "users.username", "shader_collaborators.role", "shader_collaborators.created_at").
+		From("shader_collaborators").
+		Join("users ON users.user_id = shader_collaborators.user_id").
+		Where(squirrel.Eq{"shader_collaborators.shader_id": shaderId}).
+		OrderBy("shader_collaborators.created_at ASC").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	rows, err := repo.pg.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed querying shader collaborators caused by: %w", err)
+	}
+
+	collaborators, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.Collaborator])
+	if err != nil {
+		return nil, fmt.Errorf("failed deserializing database rows caused by: %w", err)
+	}
+	return collaborators, nil
+}
+
+// ShaderCollaboratorGetRole returns the role of the user on the shader, infra.NotFoundError when they are not a
+// collaborator
+func (repo *Repository) ShaderCollaboratorGetRole(ctx context.Context, shaderId string, userId string) (string, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.
+		Select("role").
+		From("shader_collaborators").
+		Where(squirrel.Eq{"shader_id": shaderId, "user_id": userId}).
+		ToSql()
+	if err != nil {
+		return "", fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	var role string
+	err = repo.pg.pool.QueryRow(ctx, sql, args...).Scan(&role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", infra.NotFoundError
+		}
+		return "", fmt.Errorf("failed querying shader collaborator caused by: %w", err)
+	}
+	return role, nil
+}
+
+// ShaderCollaboratorUpsert shares the shader with the user, or changes their role when it already is. The caller is
+// responsible for checking the shader is owned by the current user.
+func (repo *Repository) ShaderCollaboratorUpsert(ctx context.Context, shaderId string, userId string, role string) error {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	return pgx.BeginFunc(ctx, repo.pg.pool, func(tx pgx.Tx) error {
+		return upsertCollaborator(ctx, tx, shaderId, userId, role)
+	})
+}
+
+func upsertCollaborator(ctx context.Context, tx pgx.Tx, shaderId string, userId string, role string) error {
+	sql, args, err := psql.
+		Insert("shader_collaborators").
+		Columns("shader_id", "user_id", "created_at", "role").
+		Values(shaderId, userId, time.Now(), role).
+		Suffix("ON CONFLICT (shader_id, user_id) DO UPDATE SET role = EXCLUDED.role").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed upserting shader collaborator caused by: %w", err)
+	}
+	return nil
+}
+
+func (repo *Repository) ShaderCollaboratorRemove(ctx context.Context, shaderId string, userId string) error {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.
+		Delete("shader_collaborators").
+		Where(squirrel.Eq{"shader_id": shaderId, "user_id": userId}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	tag, err := repo.pg.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed deleting shader collaborator caused by: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return infra.NotFoundError
+	}
+
+	return nil
+}
+
+// ShaderTransferOwnership hands a shader of ownerId over to newOwnerId. The previous owner stays on as an editor.
+func (repo *Repository) ShaderTransferOwnership(ctx context.Context, shaderId string, ownerId string, newOwnerId string) (models.Shader, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.
+		Update("shaders").
+		Set("created_by", newOwnerId).
+		Set("updated_at", time.Now()).
+		Set("version", squirrel.Expr("version + 1")).
+		Where(squirrel.Eq{"shader_id": shaderId, "created_by": ownerId, "deleted_at": nil}).
+		Suffix(returningShaderColumns("shaders")).
+		ToSql()
+	if err != nil {
+		return models.Shader{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	removeSql, removeArgs, err := psql.
+		Delete("shader_collaborators").
+		Where(squirrel.Eq{"shader_id": shaderId, "user_id": newOwnerId}).
+		ToSql()
+	if err != nil {
+		return models.Shader{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	var shader models.Shader
+	err = pgx.BeginFunc(ctx, repo.pg.pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, sql, args...)
+		if err != nil {
+			return fmt.Errorf("failed transferring shader caused by: %w", err)
+		}
+
+		shader, err = pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.Shader])
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return infra.NotFoundError
+			}
+			return fmt.Errorf("failed transferring shader caused by: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, removeSql, removeArgs...)
+		if err != nil {
+			return fmt.Errorf("failed deleting shader collaborator caused by: %w", err)
+		}
+
+		return upsertCollaborator(ctx, tx, shaderId, ownerId, models.CollaboratorRoleEditor)
+	})
+	if err != nil {
+		return models.Shader{}, err
+	}
+
+	return shader, nil
+}
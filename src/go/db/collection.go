@@ -0,0 +1,373 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/sdedovic/wgsltoy-server/src/go/guid"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"time"
+)
+
+// collectionColumns are the columns of the collections table read into models.Collection
+var collectionColumns = []string{
+	"collection_id", "created_by", "created_at", "updated_at", "name", "visibility", "description",
+}
+
+// collectionVisibleTo matches the collections currentUser may open by id, following the same rules as shaderVisibleTo
+func collectionVisibleTo(currentUser string) squirrel.Sqlizer {
+	return squirrel.Or{
+		squirrel.Eq{"collections.visibility": []string{"public", "unlisted"}},
+		squirrel.Eq{"collections.visibility": "private", "collections.created_by": currentUser},
+	}
+}
+
+func (repo *Repository) CollectionCreate(ctx context.Context, name string, visibility string, description string, createdBy string) (models.Collection, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	collection := models.Collection{
+		Id:          guid.New(),
+		CreatedBy:   createdBy,
+		CreatedAt:   time.Now(),
+		Name:        name,
+		Visibility:  visibility,
+		Description: description,
+	}
+	collection.UpdatedAt = collection.CreatedAt
+
+	sql, args, err := psql.
+		Insert("collections").
+		Columns(collectionColumns...).
+		Values(collection.Id, collection.CreatedBy, collection.CreatedAt, collection.UpdatedAt, collection.Name, collection.Visibility, collection.Description).
+		ToSql()
+	if err != nil {
+		return models.Collection{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	_, err = repo.pg.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return models.Collection{}, fmt.Errorf("failed inserting collection caused by: %w", err)
+	}
+
+	return collection, nil
+}
+
+// CollectionGetVisibleById looks up a collection if it is visible to currentUser, empty for anonymous visitors
+func (repo *Repository) CollectionGetVisibleById(ctx context.Context, collectionId string, currentUser string) (models.Collection, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.
+		Select(collectionColumns...).
+		From("collections").
+		Where(squirrel.And{
+			squirrel.Eq{"collection_id": collectionId},
+			collectionVisibleTo(currentUser),
+		}).
+		ToSql()
+	if err != nil {
+		return models.Collection{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	rows, _ := repo.pg.pool.Query(ctx, sql, args...)
+	collection, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.Collection])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Collection{}, infra.NotFoundError
+		}
+		return models.Collection{}, fmt.Errorf("failed querying collection caused by: %w", err)
+	}
+
+	return collection, nil
+}
+
+func (repo *Repository) CollectionListByCreatedBy(ctx context.Context, createdBy string) ([]models.Collection, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.
+		Select(collectionColumns...).
+		From("collections").
+		Where(squirrel.Eq{"created_by": createdBy}).
+		OrderBy("updated_at DESC").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	rows, err := repo.pg.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed querying collections by user caused by: %w", err)
+	}
+
+	collections, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.Collection])
+	if err != nil {
+		return nil, fmt.Errorf("failed deserializing database rows caused by: %w", err)
+	}
+	return collections, nil
+}
+
+func (repo *Repository) CollectionPartialUpdate(ctx context.Context, collectionId string, createdBy string, name *string, visibility *string, description *string) (models.Collection, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	builder := psql.
+		Update("collections").
+		Set("updated_at", time.Now())
+
+	if name != nil {
+		builder = builder.Set("name", name)
+	}
+
+	if visibility != nil {
+		builder = builder.Set("visibility", visibility)
+	}
+
+	if description != nil {
+		builder = builder.Set("description", description)
+	}
+
+	sql, args, err := builder.
+		Where(squirrel.Eq{"collection_id": collectionId, "created_by": createdBy}).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return models.Collection{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	rows, _ := repo.pg.pool.Query(ctx, sql, args...)
+	collection, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.Collection])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Collection{}, infra.NotFoundError
+		}
+		return models.Collection{}, fmt.Errorf("failed updating collection caused by: %w", err)
+	}
+
+	return collection, nil
+}
+
+func (repo *Repository) CollectionDelete(ctx context.Context, collectionId string, createdBy string) error {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.
+		Delete("collections").
+		Where(squirrel.Eq{"collection_id": collectionId, "created_by": createdBy}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	tag, err := repo.pg.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed deleting collection caused by: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return infra.NotFoundError
+	}
+
+	return nil
+}
+
+// CollectionItemList lists the shaders of a collection in order, skipping those currentUser can not see
+func (repo *Repository) CollectionItemList(ctx context.Context, collectionId string, currentUser string) ([]models.ShaderInfo, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.
+		Select(qualifiedColumns("shaders", shaderInfoColumns)...).
+		From("collection_items").
+		Join("shaders ON shaders.shader_id = collection_items.shader_id").
+		Where(squirrel.Eq{"collection_items.collection_id": collectionId, "shaders.deleted_at": nil}).
+		Where(shaderVisibleTo(currentUser)).
+		OrderBy("collection_items.position ASC").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	rows, err := repo.pg.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed querying collection items caused by: %w", err)
+	}
+
+	shaders, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.ShaderInfo])
+	if err != nil {
+		return nil, fmt.Errorf("failed deserializing database rows caused by: %w", err)
+	}
+	return shaders, nil
+}
+
+// lockCollection locks a collection of createdBy for changes to its items and marks it as updated
+func lockCollection(ctx context.Context, tx pgx.Tx, collectionId string, createdBy string) error {
+	sql, args, err := psql.
+		Update("collections").
+		Set("updated_at", time.Now()).
+		Where(squirrel.Eq{"collection_id": collectionId, "created_by": createdBy}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed locking collection caused by: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return infra.NotFoundError
+	}
+	return nil
+}
+
+// CollectionItemAdd appends the shader to a collection of createdBy, unless it is already included. It fails with a
+// validation error when the collection already holds maxItems shaders. The caller is responsible for checking the
+// shader is visible to the user.
+func (repo *Repository) CollectionItemAdd(ctx context.Context, collectionId string, createdBy string, shaderId string, maxItems int) error {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	countSql, countArgs, err := psql.
+		Select("count(*)", "coalesce(max(position) + 1, 0)").
+		From("collection_items").
+		Where(squirrel.Eq{"collection_id": collectionId}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, repo.pg.pool, func(tx pgx.Tx) error {
+		if err := lockCollection(ctx, tx, collectionId, createdBy); err != nil {
+			return err
+		}
+
+		var count, position int
+		if err := tx.QueryRow(ctx, countSql, countArgs...).Scan(&count, &position); err != nil {
+			return fmt.Errorf("failed counting collection items caused by: %w", err)
+		}
+		if count >= maxItems {
+			return infra.NewValidationError(fmt.Sprintf("Collections may hold at most %d shaders!", maxItems))
+		}
+
+		sql, args, err := psql.
+			Insert("collection_items").
+			Columns("collection_id", "shader_id", "position", "added_at").
+			Values(collectionId, shaderId, position, time.Now()).
+			Suffix("ON CONFLICT DO NOTHING").
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("failed building sql caused by: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, sql, args...)
+		if err != nil {
+			return fmt.Errorf("failed inserting collection item caused by: %w", err)
+		}
+		return nil
+	})
+}
+
+func (repo *Repository) CollectionItemRemove(ctx context.Context, collectionId string, createdBy string, shaderId string) error {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.
+		Delete("collection_items").
+		Where(squirrel.Eq{"collection_id": collectionId, "shader_id": shaderId}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, repo.pg.pool, func(tx pgx.Tx) error {
+		if err := lockCollection(ctx, tx, collectionId, createdBy); err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, sql, args...)
+		if err != nil {
+			return fmt.Errorf("failed deleting collection item caused by: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return infra.NotFoundError
+		}
+		return nil
+	})
+}
+
+// CollectionItemsReorder moves the shaders to the front of a collection of createdBy in the given order, keeping the
+// relative order of the remaining items. It fails with a validation error when a shader is not in the collection.
+func (repo *Repository) CollectionItemsReorder(ctx context.Context, collectionId string, createdBy string, shaderIds []string) error {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	listSql, listArgs, err := psql.
+		Select("shader_id").
+		From("collection_items").
+		Where(squirrel.Eq{"collection_id": collectionId}).
+		OrderBy("position ASC").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, repo.pg.pool, func(tx pgx.Tx) error {
+		if err := lockCollection(ctx, tx, collectionId, createdBy); err != nil {
+			return err
+		}
+
+		rows, _ := tx.Query(ctx, listSql, listArgs...)
+		current, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return fmt.Errorf("failed querying collection items caused by: %w", err)
+		}
+
+		ordered, err := reorderItems(current, shaderIds)
+		if err != nil {
+			return err
+		}
+
+		sql, args, err := psql.
+			Update("collection_items").
+			Set("position", squirrel.Expr("array_position(?::text[], shader_id::text) - 1", ordered)).
+			Where(squirrel.Eq{"collection_id": collectionId}).
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("failed building sql caused by: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, sql, args...)
+		if err != nil {
+			return fmt.Errorf("failed reordering collection items caused by: %w", err)
+		}
+		return nil
+	})
+}
+
+// reorderItems places moved at the front of current, followed by the remaining items of current in their order
+func reorderItems(current []string, moved []string) ([]string, error) {
+	remaining := make(map[string]bool, len(current))
+	for _, shaderId := range current {
+		remaining[shaderId] = true
+	}
+
+	ordered := make([]string, 0, len(current))
+	for idx, shaderId := range moved {
+		if !remaining[shaderId] {
+			return nil, infra.NewValidationError(fmt.Sprintf("Field 'shaderIds[%d]' is not an item of the collection or repeated!", idx))
+		}
+		remaining[shaderId] = false
+		ordered = append(ordered, shaderId)
+	}
+
+	for _, shaderId := range current {
+		if remaining[shaderId] {
+			ordered = append(ordered, shaderId)
+		}
+	}
+	return ordered, nil
+}
@@ -0,0 +1,212 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/sdedovic/wgsltoy-server/src/go/guid"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"time"
+)
+
+// commentCursorSort marks cursors handed out by comment listings, which are always ordered oldest first
+const commentCursorSort = "comments"
+
+func (repo *Repository) CommentCreate(ctx context.Context, shaderId string, parentId *string, createdBy string, body string) (models.Comment, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	comment := models.Comment{
+		Id:        guid.New(),
+		ShaderId:  shaderId,
+		ParentId:  parentId,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+		Body:      body,
+	}
+	comment.UpdatedAt = comment.CreatedAt
+
+	sql, args, err := psql.
+		Insert("shader_comments").
+		Columns("comment_id", "shader_id", "parent_id", "created_by", "created_at", "updated_at", "body").
+		Values(comment.Id, comment.ShaderId, comment.ParentId, comment.CreatedBy, comment.CreatedAt, comment.UpdatedAt, comment.Body).
+		ToSql()
+	if err != nil {
+		return models.Comment{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	_, err = repo.pg.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return models.Comment{}, fmt.Errorf("failed inserting comment caused by: %w", err)
+	}
+
+	return comment, nil
+}
+
+func (repo *Repository) CommentGetById(ctx context.Context, shaderId string, commentId string) (models.Comment, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.
+		Select("*").
+		From("shader_comments").
+		Where(squirrel.Eq{"comment_id": commentId, "shader_id": shaderId}).
+		ToSql()
+	if err != nil {
+		return models.Comment{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	rows, _ := repo.pg.pool.Query(ctx, sql, args...)
+	comment, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.Comment])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Comment{}, infra.NotFoundError
+		}
+		return models.Comment{}, fmt.Errorf("failed querying comment caused by: %w", err)
+	}
+
+	return comment, nil
+}
+
+// CommentListByShaderId lists the comments on the shader oldest first, including hidden and deleted ones
+func (repo *Repository) CommentListByShaderId(ctx context.Context, shaderId string, page models.PageRequest) (models.Page[models.Comment], error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	builder := psql.
+		Select("*").
+		From("shader_comments").
+		Where(squirrel.Eq{"shader_id": shaderId})
+
+	if page.Cursor != "" {
+		c, err := decodeCursor(page.Cursor, commentCursorSort)
+		if err != nil {
+			return models.Page[models.Comment]{}, err
+		}
+		createdAt, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return models.Page[models.Comment]{}, invalidCursorError
+		}
+
+		builder = builder.Where("(created_at, comment_id) > (?, ?)", createdAt, c.Id)
+	}
+
+	sql, args, err := builder.
+		OrderBy("created_at ASC", "comment_id ASC").
+		Limit(uint64(page.Limit + 1)).
+		ToSql()
+	if err != nil {
+		return models.Page[models.Comment]{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	rows, err := repo.pg.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return models.Page[models.Comment]{}, fmt.Errorf("failed querying comments caused by: %w", err)
+	}
+
+	comments, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.Comment])
+	if err != nil {
+		return models.Page[models.Comment]{}, fmt.Errorf("failed deserializing database rows caused by: %w", err)
+	}
+
+	if len(comments) <= page.Limit {
+		return models.Page[models.Comment]{Items: comments}, nil
+	}
+
+	comments = comments[:page.Limit]
+	last := comments[len(comments)-1]
+	next, err := encodeCursor(cursor{Sort: commentCursorSort, Value: last.CreatedAt.Format(time.RFC3339Nano), Id: last.Id})
+	if err != nil {
+		return models.Page[models.Comment]{}, err
+	}
+
+	return models.Page[models.Comment]{Items: comments, Next: next}, nil
+}
+
+// CommentUpdate replaces the body of a comment, provided it was written by createdBy and is not deleted
+func (repo *Repository) CommentUpdate(ctx context.Context, shaderId string, commentId string, createdBy string, body string) (models.Comment, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.
+		Update("shader_comments").
+		Set("body", body).
+		Set("updated_at", time.Now()).
+		Where(squirrel.Eq{"comment_id": commentId, "shader_id": shaderId, "created_by": createdBy, "deleted_at": nil}).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return models.Comment{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	rows, _ := repo.pg.pool.Query(ctx, sql, args...)
+	comment, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.Comment])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Comment{}, infra.NotFoundError
+		}
+		return models.Comment{}, fmt.Errorf("failed updating comment caused by: %w", err)
+	}
+
+	return comment, nil
+}
+
+// CommentSoftDelete marks a comment written by createdBy as deleted, keeping it in place so replies stay threaded
+func (repo *Repository) CommentSoftDelete(ctx context.Context, shaderId string, commentId string, createdBy string) error {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.
+		Update("shader_comments").
+		Set("deleted_at", time.Now()).
+		Where(squirrel.Eq{"comment_id": commentId, "shader_id": shaderId, "created_by": createdBy, "deleted_at": nil}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	tag, err := repo.pg.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed deleting comment caused by: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return infra.NotFoundError
+	}
+
+	return nil
+}
+
+// CommentSetHidden hides or reveals a comment. The caller is responsible for checking the user owns the shader.
+func (repo *Repository) CommentSetHidden(ctx context.Context, shaderId string, commentId string, hidden bool) (models.Comment, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	var hiddenAt any
+	if hidden {
+		hiddenAt = squirrel.Expr("coalesce(hidden_at, ?)", time.Now())
+	}
+
+	sql, args, err := psql.
+		Update("shader_comments").
+		Set("hidden_at", hiddenAt).
+		Where(squirrel.Eq{"comment_id": commentId, "shader_id": shaderId}).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return models.Comment{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	rows, _ := repo.pg.pool.Query(ctx, sql, args...)
+	comment, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.Comment])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Comment{}, infra.NotFoundError
+		}
+		return models.Comment{}, fmt.Errorf("failed updating comment caused by: %w", err)
+	}
+
+	return comment, nil
+}
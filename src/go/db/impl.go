@@ -7,25 +7,52 @@ import (
 	"github.com/Masterminds/squirrel"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sdedovic/wgsltoy-server/src/go/config"
 	"github.com/sdedovic/wgsltoy-server/src/go/guid"
 	"github.com/sdedovic/wgsltoy-server/src/go/infra"
 	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"strings"
 	"time"
 )
 
-const OperationTimeout = 40
+// shaderColumns are the columns of the shaders table read into models.Shader
+var shaderColumns = []string{
+	"shader_id", "created_at", "updated_at", "created_by", "name", "visibility", "description", "tags", "content",
+	"revision", "version", "forked_from", "like_count", "deleted_at",
+}
+
+// shaderInfoColumns are the columns of the shaders table read into models.ShaderInfo
+var shaderInfoColumns = []string{
+	"shader_id", "created_at", "updated_at", "created_by", "name", "visibility", "description", "tags", "like_count",
+	"deleted_at",
+}
+
+// qualifiedColumns prefixes each column with the table name, for use in statements joining multiple tables
+func qualifiedColumns(table string, columns []string) []string {
+	qualified := make([]string, len(columns))
+	for idx, column := range columns {
+		qualified[idx] = table + "." + column
+	}
+	return qualified
+}
+
+// returningShaderColumns is the RETURNING clause for statements which read back a models.Shader from table
+func returningShaderColumns(table string) string {
+	return "RETURNING " + strings.Join(qualifiedColumns(table, shaderColumns), ", ")
+}
 
 type Repository struct {
-	pg *PgClient `di.inject:"PgClient"`
+	pg     *PgClient      `di.inject:"PgClient"`
+	config *config.Config `di.inject:"Config"`
 }
 
-func (repo *Repository) UserCreate(username string, email string, hashedPassword string) (models.User, error) {
-	ctx, cancelFunc := context.WithTimeout(context.Background(), OperationTimeout*time.Second)
+func (repo *Repository) UserCreate(ctx context.Context, username string, email string, hashedPassword string) (models.User, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
 	defer cancelFunc()
 
 	createdAt := time.Now()
 	userId := guid.New()
-	emailVerification := "pending"
+	emailVerification := models.EmailVerificationPending
 
 	sql, args, err := psql.
 		Insert("users").Columns("user_id", "username", "email", "email_verification", "password", "created_at", "updated_at").
@@ -66,8 +93,8 @@ func (repo *Repository) UserCreate(username string, email string, hashedPassword
 	}, nil
 }
 
-func (repo *Repository) UserGetByUsername(username string) (models.User, error) {
-	ctx, cancelFunc := context.WithTimeout(context.Background(), OperationTimeout*time.Second)
+func (repo *Repository) UserGetByUsername(ctx context.Context, username string) (models.User, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
 	defer cancelFunc()
 
 	sql, args, err := psql.Select("*").
@@ -90,8 +117,8 @@ func (repo *Repository) UserGetByUsername(username string) (models.User, error)
 	return user, nil
 }
 
-func (repo *Repository) UserGetById(userId string) (models.User, error) {
-	ctx, cancelFunc := context.WithTimeout(context.Background(), OperationTimeout*time.Second)
+func (repo *Repository) UserGetById(ctx context.Context, userId string) (models.User, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
 	defer cancelFunc()
 
 	sql, args, err := psql.Select("*").
@@ -114,8 +141,97 @@ func (repo *Repository) UserGetById(userId string) (models.User, error) {
 	return user, nil
 }
 
-func (repo *Repository) ShaderCreate(name string, visibility string, description string, tags []string, content string, createdBy string) (models.Shader, error) {
-	ctx, cancelFunc := context.WithTimeout(context.Background(), OperationTimeout*time.Second)
+func (repo *Repository) UserGetByEmail(ctx context.Context, email string) (models.User, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.Select("*").
+		From("users").
+		Where("email = ?", email).
+		ToSql()
+	if err != nil {
+		return models.User{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	rows, _ := repo.pg.pool.Query(ctx, sql, args...)
+	user, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.User])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.User{}, infra.NotFoundError
+		}
+		return models.User{}, fmt.Errorf("failed querying user caused by: %w", err)
+	}
+
+	return user, nil
+}
+
+// UserClaimVerificationEmail records that a verification email is being sent to the user, provided their email is not
+// verified yet and no email was sent since sentBefore. It returns infra.NotFoundError when either does not hold.
+func (repo *Repository) UserClaimVerificationEmail(ctx context.Context, userId string, sentBefore time.Time) (models.User, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.Update("users").
+		Set("verification_sent_at", time.Now()).
+		Where(squirrel.Eq{"user_id": userId, "email_verification": models.EmailVerificationPending}).
+		Where(squirrel.Or{
+			squirrel.Eq{"verification_sent_at": nil},
+			squirrel.Lt{"verification_sent_at": sentBefore},
+		}).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return models.User{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	rows, _ := repo.pg.pool.Query(ctx, sql, args...)
+	user, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.User])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.User{}, infra.NotFoundError
+		}
+		return models.User{}, fmt.Errorf("failed updating user caused by: %w", err)
+	}
+
+	return user, nil
+}
+
+// UserCompleteEmailVerification marks email as verified for the user, provided it is still their email address
+func (repo *Repository) UserCompleteEmailVerification(ctx context.Context, userId string, email string) error {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.Update("users").
+		Set("email_verification", models.EmailVerificationCompleted).
+		Set("updated_at", time.Now()).
+		Where(squirrel.Eq{"user_id": userId, "email": email}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	tag, err := repo.pg.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed updating user caused by: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return infra.NotFoundError
+	}
+
+	return nil
+}
+
+func (repo *Repository) ShaderCreate(ctx context.Context, name string, visibility string, description string, tags []string, content string, createdBy string) (models.Shader, error) {
+	return repo.shaderInsert(ctx, name, visibility, description, tags, content, createdBy, nil)
+}
+
+// ShaderCreateFork stores a private copy of parent owned by createdBy, attributed to the parent
+func (repo *Repository) ShaderCreateFork(ctx context.Context, parent models.Shader, createdBy string) (models.Shader, error) {
+	return repo.shaderInsert(ctx, parent.Name, "private", parent.Description, parent.Tags, parent.Content, createdBy, &parent.Id)
+}
+
+func (repo *Repository) shaderInsert(ctx context.Context, name string, visibility string, description string, tags []string, content string, createdBy string, forkedFrom *string) (models.Shader, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
 	defer cancelFunc()
 
 	createdAt := time.Now()
@@ -123,38 +239,54 @@ func (repo *Repository) ShaderCreate(name string, visibility string, description
 
 	sql, args, err := psql.
 		Insert("shaders").
-		Columns("created_at", "updated_at", "created_by", "visibility", "name", "description", "content", "tags", "shader_id").
-		Values(createdAt, createdAt, createdBy, visibility, name, description, content, tags, shaderId).
+		Columns("created_at", "updated_at", "created_by", "visibility", "name", "description", "content", "tags", "shader_id", "revision", "forked_from").
+		Values(createdAt, createdAt, createdBy, visibility, name, description, content, tags, shaderId, 1, forkedFrom).
 		ToSql()
 	if err != nil {
 		return models.Shader{}, err
 	}
 
-	_, err = repo.pg.pool.Exec(ctx, sql, args...)
+	err = pgx.BeginFunc(ctx, repo.pg.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, sql, args...)
+		if err != nil {
+			return fmt.Errorf("failed inserting shader caused by: %w", err)
+		}
+
+		return insertShaderRevision(ctx, tx, shaderId, 1, createdAt, createdBy, content, nil)
+	})
 	if err != nil {
-		return models.Shader{}, fmt.Errorf("failed inserting shader caused by: %w", err)
+		return models.Shader{}, err
 	}
 
 	return models.Shader{
-		Id:          shaderId,
-		CreatedAt:   createdAt,
-		UpdatedAt:   createdAt,
-		Name:        name,
-		Visibility:  visibility,
-		Description: description,
-		Tags:        tags,
+		Id:           shaderId,
+		CreatedAt:    createdAt,
+		UpdatedAt:    createdAt,
+		CreatedBy:    createdBy,
+		Name:         name,
+		Visibility:   visibility,
+		Description:  description,
+		Tags:         tags,
+		Content:      content,
+		Revision:     1,
+		Version:      1,
+		ForkedFromId: forkedFrom,
 	}, nil
 }
 
-func (repo *Repository) ShaderPartialUpdate(shaderId string, createdBy string, name *string, visibility *string, description *string, tags *[]string, content *string) (models.Shader, error) {
-	ctx, cancelFunc := context.WithTimeout(context.Background(), OperationTimeout*time.Second)
+// ShaderPartialUpdate changes the shader, provided updatedBy owns it or is an editor of it. Only the owner may change
+// the visibility. When expectedVersions is not nil the shader must currently be at one of them, otherwise
+// infra.PreconditionFailedError is returned.
+func (repo *Repository) ShaderPartialUpdate(ctx context.Context, shaderId string, updatedBy string, expectedVersions []int, name *string, visibility *string, description *string, tags *[]string, content *string) (models.Shader, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
 	defer cancelFunc()
 
 	updatedAt := time.Now()
 
 	builder := psql.
 		Update("shaders").
-		Set("updated_at", updatedAt)
+		Set("updated_at", updatedAt).
+		Set("version", squirrel.Expr("version + 1"))
 
 	if name != nil {
 		builder = builder.Set("name", name)
@@ -168,8 +300,11 @@ func (repo *Repository) ShaderPartialUpdate(shaderId string, createdBy string, n
 		builder = builder.Set("visibility", visibility)
 	}
 
+	// every content change is recorded as a new revision
 	if content != nil {
-		builder = builder.Set("content", content)
+		builder = builder.
+			Set("content", content).
+			Set("revision", squirrel.Expr("revision + 1"))
 	}
 
 	// nil means do not change, empty means set to empty
@@ -177,37 +312,86 @@ func (repo *Repository) ShaderPartialUpdate(shaderId string, createdBy string, n
 		builder = builder.Set("tags", tags)
 	}
 
+	if visibility != nil {
+		builder = builder.Where(squirrel.Eq{"created_by": updatedBy})
+	}
+
+	if expectedVersions != nil {
+		builder = builder.Where(squirrel.Eq{"version": expectedVersions})
+	}
+
 	sql, args, err := builder.
-		Where(squirrel.Eq{"shader_id": shaderId, "created_by": createdBy}).
-		Suffix("RETURNING *").
+		Where(squirrel.Eq{"shader_id": shaderId, "deleted_at": nil}).
+		Where(shaderEditableBy(updatedBy)).
+		Suffix(returningShaderColumns("shaders")).
 		ToSql()
-
-	rows, err := repo.pg.pool.Query(ctx, sql, args...)
 	if err != nil {
-		return models.Shader{}, fmt.Errorf("failed updating shader caused by: %w", err)
+		return models.Shader{}, fmt.Errorf("failed building sql caused by: %w", err)
 	}
 
-	shader, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.Shader])
+	// tells apart a shader which changed since it was read from one which can not be updated at all
+	existsSql, existsArgs, err := psql.
+		Select("1").
+		From("shaders").
+		Where(squirrel.Eq{"shader_id": shaderId, "deleted_at": nil}).
+		Where(shaderEditableBy(updatedBy)).
+		Prefix("SELECT EXISTS (").
+		Suffix(")").
+		ToSql()
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return models.Shader{}, infra.NotFoundError
+		return models.Shader{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	var shader models.Shader
+	err = pgx.BeginFunc(ctx, repo.pg.pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, sql, args...)
+		if err != nil {
+			return fmt.Errorf("failed updating shader caused by: %w", err)
+		}
+
+		shader, err = pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.Shader])
+		if err != nil {
+			if !errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("failed updating shader caused by: %w", err)
+			}
+			if expectedVersions == nil {
+				return infra.NotFoundError
+			}
+
+			var exists bool
+			err = tx.QueryRow(ctx, existsSql, existsArgs...).Scan(&exists)
+			if err != nil {
+				return fmt.Errorf("failed querying shader caused by: %w", err)
+			}
+			if exists {
+				return infra.PreconditionFailedError
+			}
+			return infra.NotFoundError
+		}
+
+		if content == nil {
+			return nil
 		}
-		return models.Shader{}, fmt.Errorf("failed updating shader caused by: %w", err)
+		return insertShaderRevision(ctx, tx, shaderId, shader.Revision, updatedAt, updatedBy, *content, nil)
+	})
+	if err != nil {
+		return models.Shader{}, err
 	}
 
 	return shader, nil
 }
 
-func (repo *Repository) ShaderGetPubliclyVisibleById(shaderId string) (models.Shader, error) {
-	ctx, cancelFunc := context.WithTimeout(context.Background(), OperationTimeout*time.Second)
+func (repo *Repository) ShaderGetPubliclyVisibleById(ctx context.Context, shaderId string) (models.Shader, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
 	defer cancelFunc()
 
 	sql, args, err := psql.
-		Select("*").
+		Select(shaderColumns...).
 		From("shaders").
 		Where(squirrel.Eq{
 			"shader_id":  shaderId,
 			"visibility": []string{"public", "unlisted"},
+			"deleted_at": nil,
 		}).
 		Limit(1).
 		ToSql()
@@ -227,19 +411,16 @@ func (repo *Repository) ShaderGetPubliclyVisibleById(shaderId string) (models.Sh
 	return shader, nil
 }
 
-func (repo *Repository) ShaderGetVisibleByIdAndLoggedInUser(shaderId string, currentUser string) (models.Shader, error) {
-	ctx, cancelFunc := context.WithTimeout(context.Background(), OperationTimeout*time.Second)
+func (repo *Repository) ShaderGetVisibleByIdAndLoggedInUser(ctx context.Context, shaderId string, currentUser string) (models.Shader, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
 	defer cancelFunc()
 
 	sql, args, err := psql.
-		Select("*").
+		Select(shaderColumns...).
 		From("shaders").
 		Where(squirrel.And{
-			squirrel.Eq{"shader_id": shaderId},
-			squirrel.Or{
-				squirrel.Eq{"visibility": []string{"public", "unlisted"}},
-				squirrel.Eq{"visibility": "private", "created_by": currentUser},
-			},
+			squirrel.Eq{"shader_id": shaderId, "deleted_at": nil},
+			shaderVisibleTo(currentUser),
 		}).
 		Limit(1).
 		ToSql()
@@ -259,16 +440,221 @@ func (repo *Repository) ShaderGetVisibleByIdAndLoggedInUser(shaderId string, cur
 	return shader, nil
 }
 
-func (repo *Repository) ShaderInfoListByCreatedBy(createdBy string) ([]models.ShaderInfo, error) {
-	ctx, cancelFunc := context.WithTimeout(context.Background(), OperationTimeout*time.Second)
+func (repo *Repository) ShaderInfoListByCreatedBy(ctx context.Context, createdBy string, page models.PageRequest) (models.Page[models.ShaderInfo], error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	builder, err := paginateShaders(psql.
+		Select(shaderInfoColumns...).
+		From("shaders").
+		Where(squirrel.Eq{"created_by": createdBy, "deleted_at": nil}), page)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	sql, args, err := builder.ToSql()
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	rows, err := repo.pg.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, fmt.Errorf("failed querying shaders by user caused by: %w", err)
+	}
+
+	return collectShaderInfoPage(rows, page)
+}
+
+func (repo *Repository) ShaderInfoListPublic(ctx context.Context, page models.PageRequest) (models.Page[models.ShaderInfo], error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	builder, err := paginateShaders(psql.
+		Select(shaderInfoColumns...).
+		From("shaders").
+		Where(squirrel.Eq{"visibility": "public", "deleted_at": nil}), page)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	sql, args, err := builder.ToSql()
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	rows, err := repo.pg.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, fmt.Errorf("failed querying public shaders caused by: %w", err)
+	}
+
+	return collectShaderInfoPage(rows, page)
+}
+
+func (repo *Repository) ShaderSoftDelete(ctx context.Context, shaderId string, createdBy string) error {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
 	defer cancelFunc()
 
 	sql, args, err := psql.
-		Select("shader_id", "created_at", "updated_at", "created_by", "name", "visibility", "description", "tags").
+		Update("shaders").
+		Set("deleted_at", time.Now()).
+		Where(squirrel.Eq{"shader_id": shaderId, "created_by": createdBy, "deleted_at": nil}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	tag, err := repo.pg.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed deleting shader caused by: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return infra.NotFoundError
+	}
+
+	return nil
+}
+
+func (repo *Repository) ShaderRestore(ctx context.Context, shaderId string, createdBy string) (models.Shader, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.
+		Update("shaders").
+		Set("deleted_at", nil).
+		Set("version", squirrel.Expr("version + 1")).
+		Where(squirrel.And{
+			squirrel.Eq{"shader_id": shaderId, "created_by": createdBy},
+			squirrel.NotEq{"deleted_at": nil},
+		}).
+		Suffix(returningShaderColumns("shaders")).
+		ToSql()
+	if err != nil {
+		return models.Shader{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	rows, err := repo.pg.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return models.Shader{}, fmt.Errorf("failed restoring shader caused by: %w", err)
+	}
+
+	shader, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.Shader])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Shader{}, infra.NotFoundError
+		}
+		return models.Shader{}, fmt.Errorf("failed restoring shader caused by: %w", err)
+	}
+
+	return shader, nil
+}
+
+func (repo *Repository) ShaderInfoListDeletedByCreatedBy(ctx context.Context, createdBy string, page models.PageRequest) (models.Page[models.ShaderInfo], error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	builder, err := paginateShaders(psql.
+		Select(shaderInfoColumns...).
 		From("shaders").
-		Where("created_by = ?", createdBy).
-		OrderBy("updated_at DESC").
-		Limit(100).
+		Where(squirrel.And{
+			squirrel.Eq{"created_by": createdBy},
+			squirrel.NotEq{"deleted_at": nil},
+		}), page)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	sql, args, err := builder.ToSql()
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	rows, err := repo.pg.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, fmt.Errorf("failed querying deleted shaders by user caused by: %w", err)
+	}
+
+	return collectShaderInfoPage(rows, page)
+}
+
+// ShaderInfoListForks lists the shaders forked from shaderId that are listed to currentUser
+func (repo *Repository) ShaderInfoListForks(ctx context.Context, shaderId string, currentUser string, page models.PageRequest) (models.Page[models.ShaderInfo], error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	builder, err := paginateShaders(psql.
+		Select(shaderInfoColumns...).
+		From("shaders").
+		Where(squirrel.And{
+			squirrel.Eq{"forked_from": shaderId, "deleted_at": nil},
+			shaderListedTo(currentUser),
+		}), page)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	sql, args, err := builder.ToSql()
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	rows, err := repo.pg.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, fmt.Errorf("failed querying shader forks caused by: %w", err)
+	}
+
+	return collectShaderInfoPage(rows, page)
+}
+
+// ShaderPurgeDeletedBefore permanently removes shaders which were soft-deleted before the supplied cutoff, returning
+// the number of removed rows.
+func (repo *Repository) ShaderPurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.
+		Delete("shaders").
+		Where(squirrel.Lt{"deleted_at": cutoff}).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	tag, err := repo.pg.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed purging deleted shaders caused by: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// insertShaderRevision records an entry in the content history of a shader as part of an ongoing transaction
+func insertShaderRevision(ctx context.Context, tx pgx.Tx, shaderId string, revision int, createdAt time.Time, createdBy string, content string, revertedFrom *int) error {
+	sql, args, err := psql.
+		Insert("shader_revisions").
+		Columns("shader_id", "revision", "created_at", "created_by", "content", "reverted_from").
+		Values(shaderId, revision, createdAt, createdBy, content, revertedFrom).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed inserting shader revision caused by: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *Repository) ShaderRevisionInfoList(ctx context.Context, shaderId string) ([]models.ShaderRevisionInfo, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.
+		Select("revision", "created_at", "created_by", "reverted_from").
+		From("shader_revisions").
+		Where(squirrel.Eq{"shader_id": shaderId}).
+		OrderBy("revision DESC").
 		ToSql()
 	if err != nil {
 		return nil, err
@@ -276,13 +662,90 @@ func (repo *Repository) ShaderInfoListByCreatedBy(createdBy string) ([]models.Sh
 
 	rows, err := repo.pg.pool.Query(ctx, sql, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed querying shaders by user caused by: %w", err)
+		return nil, fmt.Errorf("failed querying shader revisions caused by: %w", err)
 	}
 
-	shaders, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.ShaderInfo])
+	revisions, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.ShaderRevisionInfo])
 	if err != nil {
 		return nil, fmt.Errorf("failed deserializing database rows caused by: %w", err)
 	}
 
-	return shaders, nil
+	return revisions, nil
+}
+
+func (repo *Repository) ShaderRevisionGet(ctx context.Context, shaderId string, revision int) (models.ShaderRevision, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.
+		Select("revision", "created_at", "created_by", "reverted_from", "content").
+		From("shader_revisions").
+		Where(squirrel.Eq{"shader_id": shaderId, "revision": revision}).
+		ToSql()
+	if err != nil {
+		return models.ShaderRevision{}, err
+	}
+
+	rows, _ := repo.pg.pool.Query(ctx, sql, args...)
+	shaderRevision, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.ShaderRevision])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ShaderRevision{}, infra.NotFoundError
+		}
+		return models.ShaderRevision{}, fmt.Errorf("failed deserializing database rows caused by: %w", err)
+	}
+
+	return shaderRevision, nil
+}
+
+// ShaderRevert restores the content of a past revision as a new revision, leaving the history intact. This is
+// permitted for the owner and editors of the shader.
+func (repo *Repository) ShaderRevert(ctx context.Context, shaderId string, createdBy string, revision int) (models.Shader, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	updatedAt := time.Now()
+
+	sql, args, err := psql.
+		Update("shaders").
+		Set("updated_at", updatedAt).
+		Set("revision", squirrel.Expr("shaders.revision + 1")).
+		Set("version", squirrel.Expr("shaders.version + 1")).
+		Set("content", squirrel.Expr("shader_revisions.content")).
+		From("shader_revisions").
+		Where(squirrel.Eq{
+			"shaders.shader_id":          shaderId,
+			"shaders.deleted_at":         nil,
+			"shader_revisions.shader_id": shaderId,
+			"shader_revisions.revision":  revision,
+		}).
+		Where(shaderEditableBy(createdBy)).
+		Suffix(returningShaderColumns("shaders")).
+		ToSql()
+	if err != nil {
+		return models.Shader{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	var shader models.Shader
+	err = pgx.BeginFunc(ctx, repo.pg.pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, sql, args...)
+		if err != nil {
+			return fmt.Errorf("failed reverting shader caused by: %w", err)
+		}
+
+		shader, err = pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.Shader])
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return infra.NotFoundError
+			}
+			return fmt.Errorf("failed reverting shader caused by: %w", err)
+		}
+
+		return insertShaderRevision(ctx, tx, shaderId, shader.Revision, updatedAt, createdBy, shader.Content, &revision)
+	})
+	if err != nil {
+		return models.Shader{}, err
+	}
+
+	return shader, nil
 }
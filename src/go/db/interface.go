@@ -1,15 +1,90 @@
 package db
 
-import "github.com/sdedovic/wgsltoy-server/src/go/models"
+import (
+	"context"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"time"
+)
 
 type IRepository interface {
-	UserCreate(username string, email string, hashedPassword string) (models.User, error)
-	UserGetByUsername(username string) (models.User, error)
-	UserGetById(userId string) (models.User, error)
-
-	ShaderCreate(name string, visibility string, description string, tags []string, content string, createdBy string) (models.Shader, error)
-	ShaderPartialUpdate(shaderId string, createdBy string, name *string, visibility *string, description *string, tags *[]string, content *string) (models.Shader, error)
-	ShaderGetPubliclyVisibleById(shaderId string) (models.Shader, error)
-	ShaderGetVisibleByIdAndLoggedInUser(shaderId string, currentUser string) (models.Shader, error)
-	ShaderInfoListByCreatedBy(createdBy string) ([]models.ShaderInfo, error)
+	UserCreate(ctx context.Context, username string, email string, hashedPassword string) (models.User, error)
+	UserGetByUsername(ctx context.Context, username string) (models.User, error)
+	UserGetById(ctx context.Context, userId string) (models.User, error)
+	UserClaimVerificationEmail(ctx context.Context, userId string, sentBefore time.Time) (models.User, error)
+	UserCompleteEmailVerification(ctx context.Context, userId string, email string) error
+	UserGetByEmail(ctx context.Context, email string) (models.User, error)
+	UserUpdatePassword(ctx context.Context, userId string, hashedPassword string, keepSessionId string) error
+	UserGetProfileByUsername(ctx context.Context, username string) (models.UserPublicProfile, error)
+	UserUpdateProfile(ctx context.Context, userId string, bio *string, links *[]string) (models.User, error)
+
+	PasswordResetCreate(ctx context.Context, userId string, tokenHash string, expiresAt time.Time, createdAfter time.Time) (bool, error)
+	PasswordResetConsume(ctx context.Context, tokenHash string, hashedPassword string) error
+
+	SessionCreate(ctx context.Context, userId string, refreshTokenHash string, userAgent string, expiresAt time.Time) (models.Session, error)
+	SessionGetActiveById(ctx context.Context, sessionId string) (models.Session, error)
+	SessionRotate(ctx context.Context, refreshTokenHash string, newRefreshTokenHash string, expiresAt time.Time) (models.Session, error)
+	SessionListActiveByUserId(ctx context.Context, userId string) ([]models.Session, error)
+	SessionRevoke(ctx context.Context, sessionId string, userId string) error
+	SessionRevokeAllByUserId(ctx context.Context, userId string, exceptSessionId string) error
+
+	AccessTokenCreate(ctx context.Context, userId string, name string, scopes []string, tokenHash string, expiresAt time.Time) (models.AccessToken, error)
+	AccessTokenUse(ctx context.Context, tokenHash string) (models.AccessToken, error)
+	AccessTokenListActiveByUserId(ctx context.Context, userId string) ([]models.AccessToken, error)
+	AccessTokenRevoke(ctx context.Context, tokenId string, userId string) error
+
+	ShaderCreate(ctx context.Context, name string, visibility string, description string, tags []string, content string, createdBy string) (models.Shader, error)
+	ShaderCreateFork(ctx context.Context, parent models.Shader, createdBy string) (models.Shader, error)
+	ShaderPartialUpdate(ctx context.Context, shaderId string, updatedBy string, expectedVersions []int, name *string, visibility *string, description *string, tags *[]string, content *string) (models.Shader, error)
+	ShaderGetPubliclyVisibleById(ctx context.Context, shaderId string) (models.Shader, error)
+	ShaderGetVisibleByIdAndLoggedInUser(ctx context.Context, shaderId string, currentUser string) (models.Shader, error)
+	ShaderInfoListByCreatedBy(ctx context.Context, createdBy string, page models.PageRequest) (models.Page[models.ShaderInfo], error)
+	ShaderInfoListPublic(ctx context.Context, page models.PageRequest) (models.Page[models.ShaderInfo], error)
+	ShaderInfoListPublicByCreatedBy(ctx context.Context, createdBy string, page models.PageRequest) (models.Page[models.ShaderInfo], error)
+	ShaderCountByCreatedBy(ctx context.Context, createdBy string) (models.UserShaderCounts, error)
+	ShaderInfoSearch(ctx context.Context, query string, tags []string, currentUser string, page models.PageRequest) (models.Page[models.ShaderSearchResult], error)
+	ShaderInfoListForks(ctx context.Context, shaderId string, currentUser string, page models.PageRequest) (models.Page[models.ShaderInfo], error)
+	ShaderSoftDelete(ctx context.Context, shaderId string, createdBy string) error
+	ShaderRestore(ctx context.Context, shaderId string, createdBy string) (models.Shader, error)
+	ShaderInfoListDeletedByCreatedBy(ctx context.Context, createdBy string, page models.PageRequest) (models.Page[models.ShaderInfo], error)
+	ShaderPurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error)
+
+	ShaderLike(ctx context.Context, shaderId string, userId string) error
+	ShaderUnlike(ctx context.Context, shaderId string, userId string) error
+	ShaderLikedByUser(ctx context.Context, userId string, shaderIds []string) ([]string, error)
+	ShaderInfoListLikedByUser(ctx context.Context, userId string, page models.PageRequest) (models.Page[models.ShaderInfo], error)
+
+	ShaderCollaboratorList(ctx context.Context, shaderId string) ([]models.Collaborator, error)
+	ShaderCollaboratorGetRole(ctx context.Context, shaderId string, userId string) (string, error)
+	ShaderCollaboratorUpsert(ctx context.Context, shaderId string, userId string, role string) error
+	ShaderCollaboratorRemove(ctx context.Context, shaderId string, userId string) error
+	ShaderTransferOwnership(ctx context.Context, shaderId string, ownerId string, newOwnerId string) (models.Shader, error)
+
+	ShareLinkCreate(ctx context.Context, shaderId string, createdBy string, tokenHash string, expiresAt *time.Time) (models.ShareLink, error)
+	ShareLinkUse(ctx context.Context, tokenHash string) (models.ShareLink, error)
+	ShareLinkListActiveByShaderId(ctx context.Context, shaderId string) ([]models.ShareLink, error)
+	ShareLinkRevoke(ctx context.Context, linkId string, shaderId string) error
+	ShaderGetById(ctx context.Context, shaderId string) (models.Shader, error)
+
+	CommentCreate(ctx context.Context, shaderId string, parentId *string, createdBy string, body string) (models.Comment, error)
+	CommentGetById(ctx context.Context, shaderId string, commentId string) (models.Comment, error)
+	CommentListByShaderId(ctx context.Context, shaderId string, page models.PageRequest) (models.Page[models.Comment], error)
+	CommentUpdate(ctx context.Context, shaderId string, commentId string, createdBy string, body string) (models.Comment, error)
+	CommentSoftDelete(ctx context.Context, shaderId string, commentId string, createdBy string) error
+	CommentSetHidden(ctx context.Context, shaderId string, commentId string, hidden bool) (models.Comment, error)
+
+	CollectionCreate(ctx context.Context, name string, visibility string, description string, createdBy string) (models.Collection, error)
+	CollectionGetVisibleById(ctx context.Context, collectionId string, currentUser string) (models.Collection, error)
+	CollectionListByCreatedBy(ctx context.Context, createdBy string) ([]models.Collection, error)
+	CollectionPartialUpdate(ctx context.Context, collectionId string, createdBy string, name *string, visibility *string, description *string) (models.Collection, error)
+	CollectionDelete(ctx context.Context, collectionId string, createdBy string) error
+	CollectionItemList(ctx context.Context, collectionId string, currentUser string) ([]models.ShaderInfo, error)
+	CollectionItemAdd(ctx context.Context, collectionId string, createdBy string, shaderId string, maxItems int) error
+	CollectionItemRemove(ctx context.Context, collectionId string, createdBy string, shaderId string) error
+	CollectionItemsReorder(ctx context.Context, collectionId string, createdBy string, shaderIds []string) error
+
+	ShaderRevisionInfoList(ctx context.Context, shaderId string) ([]models.ShaderRevisionInfo, error)
+	ShaderRevisionGet(ctx context.Context, shaderId string, revision int) (models.ShaderRevision, error)
+	ShaderRevert(ctx context.Context, shaderId string, createdBy string, revision int) (models.Shader, error)
 }
+
+var _ IRepository = (*Repository)(nil)
@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"time"
+)
+
+// ShaderLike records that the user likes the shader, incrementing its like count. Liking a shader twice has no effect.
+// The caller is responsible for checking the shader is visible to the user.
+func (repo *Repository) ShaderLike(ctx context.Context, shaderId string, userId string) error {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.
+		Insert("shader_likes").
+		Columns("shader_id", "user_id", "created_at").
+		Values(shaderId, userId, time.Now()).
+		Suffix("ON CONFLICT DO NOTHING").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, repo.pg.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, sql, args...)
+		if err != nil {
+			return fmt.Errorf("failed inserting shader like caused by: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return nil
+		}
+
+		return updateLikeCount(ctx, tx, shaderId, 1)
+	})
+}
+
+// ShaderUnlike removes the like of the user from the shader, decrementing its like count. Unliking a shader that is not
+// liked has no effect.
+func (repo *Repository) ShaderUnlike(ctx context.Context, shaderId string, userId string) error {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.
+		Delete("shader_likes").
+		Where(squirrel.Eq{"shader_id": shaderId, "user_id": userId}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, repo.pg.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, sql, args...)
+		if err != nil {
+			return fmt.Errorf("failed deleting shader like caused by: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return nil
+		}
+
+		return updateLikeCount(ctx, tx, shaderId, -1)
+	})
+}
+
+// updateLikeCount adjusts the denormalized like count of the shader by delta
+func updateLikeCount(ctx context.Context, tx pgx.Tx, shaderId string, delta int) error {
+	sql, args, err := psql.
+		Update("shaders").
+		Set("like_count", squirrel.Expr("like_count + ?", delta)).
+		Where(squirrel.Eq{"shader_id": shaderId}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed updating shader like count caused by: %w", err)
+	}
+	return nil
+}
+
+// ShaderLikedByUser returns which of the shaders the user likes
+func (repo *Repository) ShaderLikedByUser(ctx context.Context, userId string, shaderIds []string) ([]string, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.
+		Select("shader_id").
+		From("shader_likes").
+		Where(squirrel.Eq{"user_id": userId, "shader_id": shaderIds}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	rows, err := repo.pg.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed querying shader likes caused by: %w", err)
+	}
+
+	liked, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed deserializing database rows caused by: %w", err)
+	}
+	return liked, nil
+}
+
+// likedShaderRow is a liked shader along with when it was liked, which the listing is ordered by
+type likedShaderRow struct {
+	models.ShaderInfo
+	LikedAt time.Time `db:"liked_at"`
+}
+
+// ShaderInfoListLikedByUser lists the shaders the user likes, as far as they are still visible to them, the most
+// recently liked first
+func (repo *Repository) ShaderInfoListLikedByUser(ctx context.Context, userId string, page models.PageRequest) (models.Page[models.ShaderInfo], error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	builder, err := likedShadersQuery(userId, page)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	sql, args, err := builder.ToSql()
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	rows, err := repo.pg.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, fmt.Errorf("failed querying liked shaders caused by: %w", err)
+	}
+
+	liked, err := pgx.CollectRows(rows, pgx.RowToStructByName[likedShaderRow])
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, fmt.Errorf("failed deserializing database rows caused by: %w", err)
+	}
+
+	var next string
+	if len(liked) > page.Limit {
+		liked = liked[:page.Limit]
+		last := liked[len(liked)-1]
+		next, err = encodeCursor(cursor{Sort: models.ShaderSortLiked, Value: last.LikedAt.Format(time.RFC3339Nano), Id: last.Id})
+		if err != nil {
+			return models.Page[models.ShaderInfo]{}, err
+		}
+	}
+
+	shaders := make([]models.ShaderInfo, len(liked))
+	for idx, row := range liked {
+		shaders[idx] = row.ShaderInfo
+	}
+	return models.Page[models.ShaderInfo]{Items: shaders, Next: next}, nil
+}
+
+// likedShadersQuery selects a page of the shaders userId likes, keyed by when they liked them so the listing follows
+// the shader_likes_user_id_idx index
+func likedShadersQuery(userId string, page models.PageRequest) (squirrel.SelectBuilder, error) {
+	builder := psql.
+		Select(qualifiedColumns("shaders", shaderInfoColumns)...).
+		Column("shader_likes.created_at AS liked_at").
+		From("shader_likes").
+		Join("shaders ON shaders.shader_id = shader_likes.shader_id").
+		Where(squirrel.Eq{"shader_likes.user_id": userId, "shaders.deleted_at": nil}).
+		Where(shaderVisibleTo(userId))
+
+	if page.Cursor != "" {
+		c, err := decodeCursor(page.Cursor, models.ShaderSortLiked)
+		if err != nil {
+			return builder, err
+		}
+		likedAt, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return builder, invalidCursorError
+		}
+		builder = builder.Where("(shader_likes.created_at, shader_likes.shader_id) < (?, ?)", likedAt, c.Id)
+	}
+
+	return builder.
+		OrderBy("shader_likes.created_at DESC", "shader_likes.shader_id DESC").
+		Limit(uint64(page.Limit + 1)), nil
+}
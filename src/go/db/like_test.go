@@ -0,0 +1,33 @@
+package db
+
+import (
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLikedShadersQuery(t *testing.T) {
+	page := models.PageRequest{Sort: models.ShaderSortLiked, Limit: 10}
+	builder, err := likedShadersQuery("user", page)
+	assert.NoError(t, err)
+
+	sql, _, err := builder.ToSql()
+	assert.NoError(t, err)
+	assert.Contains(t, sql, "FROM shader_likes JOIN shaders ON shaders.shader_id = shader_likes.shader_id")
+	assert.Contains(t, sql, "ORDER BY shader_likes.created_at DESC, shader_likes.shader_id DESC LIMIT 11")
+
+	page.Cursor, err = encodeCursor(cursor{Sort: models.ShaderSortLiked, Value: "2024-10-01T12:00:00.000001Z", Id: "AAAAAAAAAAAAAAAAAAAAAA"})
+	assert.NoError(t, err)
+	builder, err = likedShadersQuery("user", page)
+	assert.NoError(t, err)
+
+	sql, _, err = builder.ToSql()
+	assert.NoError(t, err)
+	assert.Contains(t, sql, "(shader_likes.created_at, shader_likes.shader_id) < ($")
+
+	// cursors of the other shader listings do not apply
+	page.Cursor, err = encodeCursor(cursor{Sort: models.ShaderSortNewest, Value: "2024-10-01T12:00:00.000001Z", Id: "AAAAAAAAAAAAAAAAAAAAAA"})
+	assert.NoError(t, err)
+	_, err = likedShadersQuery("user", page)
+	assert.Equal(t, invalidCursorError, err)
+}
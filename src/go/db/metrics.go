@@ -0,0 +1,32 @@
+package db
+
+import "github.com/sdedovic/wgsltoy-server/src/go/metrics"
+
+// RegisterPoolMetrics reports the connection pool stats of pgClient with the default metrics registry
+func RegisterPoolMetrics(pgClient PgClient) {
+	pool := pgClient.pool
+	metrics.NewGaugeFunc("pgxpool_acquired_conns", "Connections currently in use.", func() float64 {
+		return float64(pool.Stat().AcquiredConns())
+	})
+	metrics.NewGaugeFunc("pgxpool_idle_conns", "Connections currently idle.", func() float64 {
+		return float64(pool.Stat().IdleConns())
+	})
+	metrics.NewGaugeFunc("pgxpool_total_conns", "Connections currently open, including those being established.", func() float64 {
+		return float64(pool.Stat().TotalConns())
+	})
+	metrics.NewGaugeFunc("pgxpool_max_conns", "Maximum size of the pool.", func() float64 {
+		return float64(pool.Stat().MaxConns())
+	})
+	metrics.NewCounterFunc("pgxpool_acquires_total", "Connections acquired from the pool.", func() float64 {
+		return float64(pool.Stat().AcquireCount())
+	})
+	metrics.NewCounterFunc("pgxpool_acquire_duration_seconds_total", "Time spent acquiring connections from the pool.", func() float64 {
+		return pool.Stat().AcquireDuration().Seconds()
+	})
+	metrics.NewCounterFunc("pgxpool_empty_acquires_total", "Acquires which had to wait for a connection because none was idle.", func() float64 {
+		return float64(pool.Stat().EmptyAcquireCount())
+	})
+	metrics.NewCounterFunc("pgxpool_canceled_acquires_total", "Acquires cancelled by their context while waiting for a connection.", func() float64 {
+		return float64(pool.Stat().CanceledAcquireCount())
+	})
+}
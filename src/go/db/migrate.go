@@ -0,0 +1,313 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// migrationLockKey identifies the advisory lock held while changing the schema, so replicas starting at once take turns
+const migrationLockKey int64 = 0x7767736c746f79
+
+// migrationFilePattern matches the file names used by the migrate CLI, '<version>_<name>.<up|down>.sql'
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus describes how far the database schema is migrated
+type MigrationStatus struct {
+	// Version is the last migration applied, zero when none were
+	Version uint
+
+	// Dirty is set when a migration failed part way, which has to be resolved by hand and then forced
+	Dirty bool
+
+	// Latest is the version of the last migration known to the binary
+	Latest uint
+
+	Pending []Migration
+}
+
+// LoadMigrations reads the migrations in source, ordered by version. Every migration needs both an up and a down file.
+func LoadMigrations(source fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed listing migrations caused by: %w", err)
+	}
+
+	byVersion := map[uint]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+		content, err := fs.ReadFile(source, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed reading migration %s caused by: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[uint(version)]
+		if !ok {
+			migration = &Migration{Version: uint(version), Name: match[2]}
+			byVersion[uint(version)] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", migration.Name, match[2], version)
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return migrations, nil
+}
+
+// Migrator applies migrations to the database. It records the schema version in the 'schema_migrations' table the
+// same way the migrate CLI does, so databases migrated by either are interchangeable.
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+}
+
+func NewMigrator(pgClient PgClient, source fs.FS) (*Migrator, error) {
+	migrations, err := LoadMigrations(source)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{pool: pgClient.pool, migrations: migrations}, nil
+}
+
+// Latest is the version of the last migration known to the binary, the schema version it expects
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) Status(ctx context.Context) (MigrationStatus, error) {
+	version, dirty, err := readSchemaVersion(ctx, m.pool)
+	if err != nil {
+		return MigrationStatus{}, err
+	}
+	return m.status(version, dirty), nil
+}
+
+func (m *Migrator) status(version uint, dirty bool) MigrationStatus {
+	status := MigrationStatus{Version: version, Dirty: dirty, Latest: m.Latest()}
+	for _, migration := range m.migrations {
+		if migration.Version > version {
+			status.Pending = append(status.Pending, migration)
+		}
+	}
+	return status
+}
+
+// CheckSchema returns an error when the schema is behind the version the binary expects or a migration failed part way.
+// A schema ahead of the binary is accepted, as happens while a newer release is rolled out.
+func (m *Migrator) CheckSchema(ctx context.Context) error {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if status.Dirty {
+		return fmt.Errorf("database schema is dirty at version %d, fix it by hand and run 'migrate force'", status.Version)
+	}
+	if status.Version < status.Latest {
+		return fmt.Errorf("database schema is at version %d but version %d is required, run 'migrate up' or start with '-migrate-on-start'", status.Version, status.Latest)
+	}
+	return nil
+}
+
+// Up applies every pending migration, returning the ones applied
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		version, dirty, err := readSchemaVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("database schema is dirty at version %d, fix it by hand and run 'migrate force'", version)
+		}
+
+		for _, migration := range m.status(version, dirty).Pending {
+			err = applyMigration(ctx, conn, migration.Up, migration.Version)
+			if err != nil {
+				return fmt.Errorf("failed applying migration %d_%s caused by: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps migrations applied, returning the ones reverted
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		version, dirty, err := readSchemaVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("database schema is dirty at version %d, fix it by hand and run 'migrate force'", version)
+		}
+
+		for ; steps > 0 && version > 0; steps-- {
+			idx := slices.IndexFunc(m.migrations, func(migration Migration) bool {
+				return migration.Version == version
+			})
+			if idx < 0 {
+				return fmt.Errorf("database schema version %d is unknown to this binary", version)
+			}
+
+			var previous uint
+			if idx > 0 {
+				previous = m.migrations[idx-1].Version
+			}
+			migration := m.migrations[idx]
+			err = applyMigration(ctx, conn, migration.Down, previous)
+			if err != nil {
+				return fmt.Errorf("failed reverting migration %d_%s caused by: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+			version = previous
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Force records version as applied and clears the dirty flag, without running any migration. It is used after
+// resolving a failed migration by hand.
+func (m *Migrator) Force(ctx context.Context, version uint) error {
+	if version != 0 && !slices.ContainsFunc(m.migrations, func(migration Migration) bool { return migration.Version == version }) {
+		return fmt.Errorf("migration version %d is unknown to this binary", version)
+	}
+
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			return writeSchemaVersion(ctx, tx, version)
+		})
+	})
+}
+
+// withLock runs fn on a connection holding the migration advisory lock, creating the version table if needed
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed acquiring connection caused by: %w", err)
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey)
+	if err != nil {
+		return fmt.Errorf("failed acquiring migration lock caused by: %w", err)
+	}
+	defer func() {
+		// unlock even when ctx was cancelled, the lock is held by the session and would outlive the migration
+		_, _ = conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey)
+	}()
+
+	_, err = conn.Exec(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)")
+	if err != nil {
+		return fmt.Errorf("failed creating schema_migrations caused by: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// applyMigration runs the sql of a migration and records the resulting version in a single transaction, so a failed
+// migration leaves the schema as it was
+func applyMigration(ctx context.Context, conn *pgxpool.Conn, sql string, version uint) error {
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, sql)
+		if err != nil {
+			return err
+		}
+		return writeSchemaVersion(ctx, tx, version)
+	})
+}
+
+func readSchemaVersion(ctx context.Context, querier interface {
+	QueryRow(context.Context, string, ...any) pgx.Row
+}) (uint, bool, error) {
+	sql, args, err := psql.Select("version", "dirty").From("schema_migrations").Limit(1).ToSql()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	var version int64
+	var dirty bool
+	err = querier.QueryRow(ctx, sql, args...).Scan(&version, &dirty)
+	if err != nil {
+		var pgErr *pgconn.PgError
+
+		// no migration applied yet, or the table does not exist
+		if errors.Is(err, pgx.ErrNoRows) || (errors.As(err, &pgErr) && pgErr.Code == "42P01") {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed reading schema version caused by: %w", err)
+	}
+
+	// the migrate CLI records -1 when every migration was reverted
+	if version < 0 {
+		return 0, dirty, nil
+	}
+	return uint(version), dirty, nil
+}
+
+func writeSchemaVersion(ctx context.Context, tx pgx.Tx, version uint) error {
+	_, err := tx.Exec(ctx, "DELETE FROM schema_migrations")
+	if err != nil {
+		return fmt.Errorf("failed clearing schema version caused by: %w", err)
+	}
+	if version == 0 {
+		return nil
+	}
+
+	sql, args, err := psql.Insert("schema_migrations").Columns("version", "dirty").Values(version, false).ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+	_, err = tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed recording schema version caused by: %w", err)
+	}
+	return nil
+}
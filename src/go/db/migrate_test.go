@@ -0,0 +1,48 @@
+package db
+
+import (
+	"github.com/sdedovic/wgsltoy-server/src/sql/migrations"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	source := fstest.MapFS{
+		"000002_add_shaders.up.sql":          {Data: []byte("CREATE TABLE shaders ();")},
+		"000002_add_shaders.down.sql":        {Data: []byte("DROP TABLE shaders;")},
+		"000001_create_users_table.up.sql":   {Data: []byte("CREATE TABLE users ();")},
+		"000001_create_users_table.down.sql": {Data: []byte("DROP TABLE users;")},
+		"migrations.go":                      {Data: []byte("package migrations")},
+	}
+
+	loaded, err := LoadMigrations(source)
+	assert.NoError(t, err)
+	if assert.Len(t, loaded, 2) {
+		assert.Equal(t, Migration{Version: 1, Name: "create_users_table", Up: "CREATE TABLE users ();", Down: "DROP TABLE users;"}, loaded[0])
+		assert.Equal(t, uint(2), loaded[1].Version)
+	}
+
+	_, err = LoadMigrations(fstest.MapFS{
+		"000001_create_users_table.up.sql": {Data: []byte("CREATE TABLE users ();")},
+	})
+	assert.Error(t, err, "a migration without a down file is rejected")
+}
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	loaded, err := LoadMigrations(migrations.FS)
+	assert.NoError(t, err)
+	for idx, migration := range loaded {
+		assert.Equal(t, uint(idx+1), migration.Version, "migration versions are sequential")
+	}
+}
+
+func TestMigratorStatus(t *testing.T) {
+	migrator := &Migrator{migrations: []Migration{{Version: 1}, {Version: 2}, {Version: 3}}}
+
+	status := migrator.status(1, false)
+	assert.Equal(t, uint(3), status.Latest)
+	assert.Equal(t, []Migration{{Version: 2}, {Version: 3}}, status.Pending)
+
+	assert.Empty(t, migrator.status(3, false).Pending)
+}
@@ -0,0 +1,128 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"time"
+)
+
+// cursor is the position of the last row of a page in keyset pagination. It is handed out to clients opaquely.
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	Id    string `json:"i"`
+}
+
+func encodeCursor(c cursor) (string, error) {
+	bytes, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed encoding cursor caused by: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+var invalidCursorError = infra.NewValidationError("Parameter 'cursor' is not valid!")
+
+func decodeCursor(value string, sort string) (cursor, error) {
+	bytes, err := base64.RawURLEncoding.Strict().DecodeString(value)
+	if err != nil {
+		return cursor{}, invalidCursorError
+	}
+
+	var c cursor
+	if err = json.Unmarshal(bytes, &c); err != nil || c.Sort != sort || c.Id == "" {
+		return cursor{}, invalidCursorError
+	}
+
+	return c, nil
+}
+
+// shaderSortKey returns the column a shader listing is ordered by and whether it is ordered descending
+func shaderSortKey(sort string) (string, bool, error) {
+	switch sort {
+	case models.ShaderSortNewest:
+		return "created_at", true, nil
+	case models.ShaderSortUpdated:
+		return "updated_at", true, nil
+	case models.ShaderSortName:
+		return "name", false, nil
+	case models.ShaderSortDeleted:
+		return "deleted_at", true, nil
+	default:
+		return "", false, fmt.Errorf("unknown shader sort: %s", sort)
+	}
+}
+
+// paginateShaders applies ordering, the position of the cursor and the page size to a shader listing. One row more
+// than requested is selected to determine whether a following page exists.
+func paginateShaders(builder squirrel.SelectBuilder, page models.PageRequest) (squirrel.SelectBuilder, error) {
+	column, descending, err := shaderSortKey(page.Sort)
+	if err != nil {
+		return builder, err
+	}
+
+	direction, comparison := "ASC", ">"
+	if descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	if page.Cursor != "" {
+		c, err := decodeCursor(page.Cursor, page.Sort)
+		if err != nil {
+			return builder, err
+		}
+
+		var value any = c.Value
+		if column != "name" {
+			value, err = time.Parse(time.RFC3339Nano, c.Value)
+			if err != nil {
+				return builder, invalidCursorError
+			}
+		}
+
+		builder = builder.Where(fmt.Sprintf("(%s, shader_id) %s (?, ?)", column, comparison), value, c.Id)
+	}
+
+	return builder.
+		OrderBy(fmt.Sprintf("%s %s", column, direction), fmt.Sprintf("shader_id %s", direction)).
+		Limit(uint64(page.Limit + 1)), nil
+}
+
+// collectShaderInfoPage reads the rows of a listing built with paginateShaders into a page
+func collectShaderInfoPage(rows pgx.Rows, page models.PageRequest) (models.Page[models.ShaderInfo], error) {
+	shaders, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.ShaderInfo])
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, fmt.Errorf("failed deserializing database rows caused by: %w", err)
+	}
+
+	if len(shaders) <= page.Limit {
+		return models.Page[models.ShaderInfo]{Items: shaders}, nil
+	}
+
+	shaders = shaders[:page.Limit]
+	last := shaders[len(shaders)-1]
+
+	c := cursor{Sort: page.Sort, Id: last.Id}
+	switch page.Sort {
+	case models.ShaderSortNewest:
+		c.Value = last.CreatedAt.Format(time.RFC3339Nano)
+	case models.ShaderSortUpdated:
+		c.Value = last.UpdatedAt.Format(time.RFC3339Nano)
+	case models.ShaderSortName:
+		c.Value = last.Name
+	case models.ShaderSortDeleted:
+		c.Value = last.DeletedAt.Format(time.RFC3339Nano)
+	}
+
+	next, err := encodeCursor(c)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	return models.Page[models.ShaderInfo]{Items: shaders, Next: next}, nil
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	encoded, err := encodeCursor(cursor{Sort: models.ShaderSortName, Value: "Raymarching, part 2", Id: "AAAAAAAAAAAAAAAAAAAAAA"})
+	assert.NoError(t, err)
+
+	decoded, err := decodeCursor(encoded, models.ShaderSortName)
+	assert.NoError(t, err)
+	assert.Equal(t, cursor{Sort: models.ShaderSortName, Value: "Raymarching, part 2", Id: "AAAAAAAAAAAAAAAAAAAAAA"}, decoded)
+}
+
+func TestCursor_Invalid(t *testing.T) {
+	sortedByName, err := encodeCursor(cursor{Sort: models.ShaderSortName, Value: "Raymarching", Id: "AAAAAAAAAAAAAAAAAAAAAA"})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "!!!"},
+		{"not json", "bm90IGpzb24"},
+		{"other sort", sortedByName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeCursor(tt.cursor, models.ShaderSortNewest)
+			assert.IsType(t, infra.ValidationError{}, err)
+		})
+	}
+}
+
+func TestPaginateShaders(t *testing.T) {
+	page := models.PageRequest{Sort: models.ShaderSortNewest, Limit: 10}
+	builder, err := paginateShaders(psql.Select("shader_id").From("shaders"), page)
+	assert.NoError(t, err)
+
+	sql, _, err := builder.ToSql()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT shader_id FROM shaders ORDER BY created_at DESC, shader_id DESC LIMIT 11", sql)
+
+	page.Cursor, err = encodeCursor(cursor{Sort: models.ShaderSortNewest, Value: "2024-10-01T12:00:00.000001Z", Id: "AAAAAAAAAAAAAAAAAAAAAA"})
+	assert.NoError(t, err)
+	builder, err = paginateShaders(psql.Select("shader_id").From("shaders"), page)
+	assert.NoError(t, err)
+
+	sql, args, err := builder.ToSql()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT shader_id FROM shaders WHERE (created_at, shader_id) < ($1, $2) ORDER BY created_at DESC, shader_id DESC LIMIT 11", sql)
+	assert.Len(t, args, 2)
+}
+
+func TestPaginateShaders_Deleted(t *testing.T) {
+	page := models.PageRequest{Sort: models.ShaderSortDeleted, Limit: 10}
+	page.Cursor, _ = encodeCursor(cursor{Sort: models.ShaderSortDeleted, Value: "2024-10-01T12:00:00.000001Z", Id: "AAAAAAAAAAAAAAAAAAAAAA"})
+	builder, err := paginateShaders(psql.Select("shader_id").From("shaders"), page)
+	assert.NoError(t, err)
+
+	sql, args, err := builder.ToSql()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT shader_id FROM shaders WHERE (deleted_at, shader_id) < ($1, $2) ORDER BY deleted_at DESC, shader_id DESC LIMIT 11", sql)
+	assert.Len(t, args, 2)
+}
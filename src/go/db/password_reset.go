@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"time"
+)
+
+// PasswordResetCreate stores the hash of a password reset token for the user, unless another one was created for them
+// after createdAfter. It reports whether the token was stored.
+func (repo *Repository) PasswordResetCreate(ctx context.Context, userId string, tokenHash string, expiresAt time.Time, createdAfter time.Time) (bool, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	recent := squirrel.Select("1").
+		From("password_resets").
+		Where(squirrel.Eq{"user_id": userId}).
+		Where(squirrel.Gt{"created_at": createdAfter})
+
+	values := squirrel.Select().
+		Column("?::text, ?::character(22), ?::timestamptz, ?::timestamptz", tokenHash, userId, time.Now(), expiresAt).
+		Where(squirrel.Expr("NOT EXISTS (?)", recent))
+
+	sql, args, err := psql.Insert("password_resets").
+		Columns("token_hash", "user_id", "created_at", "expires_at").
+		Select(values).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	tag, err := repo.pg.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed inserting password reset caused by: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// PasswordResetConsume uses up an unexpired password reset token to set the password of the user it was issued for,
+// returning infra.NotFoundError when there is no such token. Resetting a password recovers an account that may be in
+// the wrong hands, so every session and personal access token of the user is revoked along with it.
+func (repo *Repository) PasswordResetConsume(ctx context.Context, tokenHash string, hashedPassword string) error {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	now := time.Now()
+	sql, args, err := psql.Update("password_resets").
+		Set("used_at", now).
+		Where(squirrel.Eq{"token_hash": tokenHash, "used_at": nil}).
+		Where(squirrel.Gt{"expires_at": now}).
+		Suffix("RETURNING user_id").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, repo.pg.pool, func(tx pgx.Tx) error {
+		var userId string
+		err := tx.QueryRow(ctx, sql, args...).Scan(&userId)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return infra.NotFoundError
+			}
+			return fmt.Errorf("failed consuming password reset caused by: %w", err)
+		}
+
+		err = updatePassword(ctx, tx, userId, hashedPassword, "", now)
+		if err != nil {
+			return err
+		}
+
+		return revokeAccessTokens(ctx, tx, userId, now)
+	})
+}
+
+// UserUpdatePassword sets the password of the user, revoking their outstanding password reset tokens and every session
+// except keepSessionId. Personal access tokens are kept, the user proved to hold the account by knowing its password.
+func (repo *Repository) UserUpdatePassword(ctx context.Context, userId string, hashedPassword string, keepSessionId string) error {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	return pgx.BeginFunc(ctx, repo.pg.pool, func(tx pgx.Tx) error {
+		return updatePassword(ctx, tx, userId, hashedPassword, keepSessionId, time.Now())
+	})
+}
+
+// updatePassword sets the password of the user, revoking their outstanding password reset tokens and every session
+// except keepSessionId, which may be empty
+func updatePassword(ctx context.Context, tx pgx.Tx, userId string, hashedPassword string, keepSessionId string, changedAt time.Time) error {
+	sql, args, err := psql.Update("users").
+		Set("password", hashedPassword).
+		Set("updated_at", changedAt).
+		Where(squirrel.Eq{"user_id": userId}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed updating password caused by: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return infra.NotFoundError
+	}
+
+	sql, args, err = psql.Update("password_resets").
+		Set("used_at", changedAt).
+		Where(squirrel.Eq{"user_id": userId, "used_at": nil}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed revoking password resets caused by: %w", err)
+	}
+
+	return revokeSessions(ctx, tx, userId, keepSessionId, changedAt)
+}
@@ -4,8 +4,7 @@ import (
 	"context"
 	"github.com/Masterminds/squirrel"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"os"
-	"time"
+	"github.com/sdedovic/wgsltoy-server/src/go/config"
 )
 
 var psql = squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
@@ -23,21 +22,23 @@ func CloseStorageDb(db PgClient) {
 	}()
 }
 
-func InitializePgClient() (PgClient, error) {
-	ctx, cancelFunc := context.WithTimeout(context.Background(), OperationTimeout*time.Second)
+func InitializePgClient(databaseConfig config.DatabaseConfig) (PgClient, error) {
+	ctx, cancelFunc := context.WithTimeout(context.Background(), databaseConfig.ConnectTimeout)
 	defer cancelFunc()
 
-	// TODO: replace with configs
-	storageDbUrl := os.Getenv("DATABASE_URL")
-
-	config, err := pgxpool.ParseConfig(storageDbUrl)
+	poolConfig, err := pgxpool.ParseConfig(databaseConfig.Url)
 	if err != nil {
 		return PgClient{}, err
 	}
+	poolConfig.MaxConns = databaseConfig.MaxConns
+	poolConfig.MinConns = databaseConfig.MinConns
+	poolConfig.MaxConnLifetime = databaseConfig.MaxConnLifetime
+	poolConfig.MaxConnIdleTime = databaseConfig.MaxConnIdleTime
+	poolConfig.ConnConfig.ConnectTimeout = databaseConfig.ConnectTimeout
 
 	// TODO: handle TLS
 
-	pool, err := pgxpool.NewWithConfig(ctx, config)
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return PgClient{}, err
 	}
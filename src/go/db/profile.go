@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"time"
+)
+
+func (repo *Repository) UserGetProfileByUsername(ctx context.Context, username string) (models.UserPublicProfile, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.Select("user_id", "created_at", "username", "bio", "links").
+		From("users").
+		Where("username = ?", username).
+		ToSql()
+	if err != nil {
+		return models.UserPublicProfile{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	rows, _ := repo.pg.pool.Query(ctx, sql, args...)
+	profile, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.UserPublicProfile])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.UserPublicProfile{}, infra.NotFoundError
+		}
+		return models.UserPublicProfile{}, fmt.Errorf("failed querying user profile caused by: %w", err)
+	}
+
+	return profile, nil
+}
+
+// UserUpdateProfile sets the bio and links of the user, leaving either unchanged when nil
+func (repo *Repository) UserUpdateProfile(ctx context.Context, userId string, bio *string, links *[]string) (models.User, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	builder := psql.Update("users").
+		Set("updated_at", time.Now()).
+		Where(squirrel.Eq{"user_id": userId}).
+		Suffix("RETURNING *")
+	if bio != nil {
+		builder = builder.Set("bio", *bio)
+	}
+	if links != nil {
+		builder = builder.Set("links", *links)
+	}
+
+	sql, args, err := builder.ToSql()
+	if err != nil {
+		return models.User{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	rows, _ := repo.pg.pool.Query(ctx, sql, args...)
+	user, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.User])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.User{}, infra.NotFoundError
+		}
+		return models.User{}, fmt.Errorf("failed updating user profile caused by: %w", err)
+	}
+
+	return user, nil
+}
+
+// ShaderCountByCreatedBy counts the shaders of the user that are not in the trash, by visibility
+func (repo *Repository) ShaderCountByCreatedBy(ctx context.Context, createdBy string) (models.UserShaderCounts, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.
+		Select(
+			"count(*) FILTER (WHERE visibility = 'public')",
+			"count(*) FILTER (WHERE visibility = 'unlisted')",
+			"count(*) FILTER (WHERE visibility = 'private')",
+		).
+		From("shaders").
+		Where(squirrel.Eq{"created_by": createdBy, "deleted_at": nil}).
+		ToSql()
+	if err != nil {
+		return models.UserShaderCounts{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	var public, unlisted, private int
+	err = repo.pg.pool.QueryRow(ctx, sql, args...).Scan(&public, &unlisted, &private)
+	if err != nil {
+		return models.UserShaderCounts{}, fmt.Errorf("failed counting shaders by user caused by: %w", err)
+	}
+
+	return models.UserShaderCounts{Public: public, Unlisted: &unlisted, Private: &private}, nil
+}
+
+func (repo *Repository) ShaderInfoListPublicByCreatedBy(ctx context.Context, createdBy string, page models.PageRequest) (models.Page[models.ShaderInfo], error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	builder, err := paginateShaders(psql.
+		Select(shaderInfoColumns...).
+		From("shaders").
+		Where(squirrel.Eq{"created_by": createdBy, "visibility": "public", "deleted_at": nil}), page)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	sql, args, err := builder.ToSql()
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	rows, err := repo.pg.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, fmt.Errorf("failed querying public shaders by user caused by: %w", err)
+	}
+
+	return collectShaderInfoPage(rows, page)
+}
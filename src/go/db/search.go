@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// searchCursorSort marks cursors handed out by search, which are positioned by offset since ranking is not stable
+// enough for keyset pagination
+const searchCursorSort = "relevance"
+
+// The snippets returned by ts_headline are delimited with control characters. Those never pass validation of stored
+// fields, which allows escaping the snippets as HTML before swapping the delimiters for tags.
+const highlightStart = "\x02"
+const highlightStop = "\x03"
+
+const nameHeadlineOptions = `StartSel="` + highlightStart + `", StopSel="` + highlightStop + `", HighlightAll=true`
+const fragmentHeadlineOptions = `StartSel="` + highlightStart + `", StopSel="` + highlightStop + `", MaxFragments=3, MaxWords=12, MinWords=4, FragmentDelimiter=" … "`
+
+type shaderSearchRow struct {
+	models.ShaderInfo
+	Rank                 float32 `db:"rank"`
+	NameHighlight        string  `db:"name_highlight"`
+	DescriptionHighlight string  `db:"description_highlight"`
+	ContentHighlight     string  `db:"content_highlight"`
+}
+
+// renderHighlight turns a ts_headline snippet into HTML, returning an empty string when nothing matched
+func renderHighlight(snippet string) string {
+	if !strings.Contains(snippet, highlightStart) {
+		return ""
+	}
+
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, highlightStart, "<mark>")
+	escaped = strings.ReplaceAll(escaped, highlightStop, "</mark>")
+	return escaped
+}
+
+// ShaderInfoSearch ranks the shaders listed to currentUser against a full-text query, optionally restricted to
+// shaders carrying every one of tags. Without a query, matching shaders are ordered newest first.
+func (repo *Repository) ShaderInfoSearch(ctx context.Context, query string, tags []string, currentUser string, page models.PageRequest) (models.Page[models.ShaderSearchResult], error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	var offset int
+	if page.Cursor != "" {
+		c, err := decodeCursor(page.Cursor, searchCursorSort)
+		if err != nil {
+			return models.Page[models.ShaderSearchResult]{}, err
+		}
+		offset, err = strconv.Atoi(c.Value)
+		if err != nil || offset < 0 {
+			return models.Page[models.ShaderSearchResult]{}, invalidCursorError
+		}
+	}
+
+	sql, args, err := shaderSearchQuery(query, tags, currentUser).
+		Offset(uint64(offset)).
+		Limit(uint64(page.Limit + 1)).
+		ToSql()
+	if err != nil {
+		return models.Page[models.ShaderSearchResult]{}, err
+	}
+
+	rows, err := repo.pg.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return models.Page[models.ShaderSearchResult]{}, fmt.Errorf("failed searching shaders caused by: %w", err)
+	}
+
+	matches, err := pgx.CollectRows(rows, pgx.RowToStructByName[shaderSearchRow])
+	if err != nil {
+		return models.Page[models.ShaderSearchResult]{}, fmt.Errorf("failed deserializing database rows caused by: %w", err)
+	}
+
+	var next string
+	if len(matches) > page.Limit {
+		matches = matches[:page.Limit]
+		next, err = encodeCursor(cursor{
+			Sort:  searchCursorSort,
+			Value: strconv.Itoa(offset + page.Limit),
+			Id:    matches[len(matches)-1].Id,
+		})
+		if err != nil {
+			return models.Page[models.ShaderSearchResult]{}, err
+		}
+	}
+
+	results := make([]models.ShaderSearchResult, len(matches))
+	for idx, match := range matches {
+		results[idx] = models.ShaderSearchResult{
+			ShaderInfo: match.ShaderInfo,
+			Rank:       match.Rank,
+			Highlights: models.ShaderSearchHighlights{
+				Name:        renderHighlight(match.NameHighlight),
+				Description: renderHighlight(match.DescriptionHighlight),
+				Content:     renderHighlight(match.ContentHighlight),
+			},
+		}
+	}
+
+	return models.Page[models.ShaderSearchResult]{Items: results, Next: next}, nil
+}
+
+// shaderSearchQuery selects the shaders listed to currentUser which match query and carry every one of tags, along with
+// their rank and highlighted snippets
+func shaderSearchQuery(query string, tags []string, currentUser string) squirrel.SelectBuilder {
+	builder := psql.
+		Select(qualifiedColumns("shaders", shaderInfoColumns)...).
+		From("shaders")
+
+	if query != "" {
+		builder = builder.
+			Prefix("WITH search AS (SELECT websearch_to_tsquery('english', ?) AS text_query, websearch_to_tsquery('simple', ?) AS content_query)", query, query).
+			Column("ts_rank(shaders.search_text, search.text_query) + 0.5 * ts_rank(shaders.search_content, search.content_query) AS rank").
+			Column("ts_headline('english', shaders.name, search.text_query, ?) AS name_highlight", nameHeadlineOptions).
+			Column("ts_headline('english', shaders.description, search.text_query, ?) AS description_highlight", fragmentHeadlineOptions).
+			Column("ts_headline('simple', shaders.content, search.content_query, ?) AS content_highlight", fragmentHeadlineOptions).
+			CrossJoin("search").
+			Where("(shaders.search_text @@ search.text_query OR shaders.search_content @@ search.content_query)").
+			OrderBy("rank DESC", "shaders.shader_id DESC")
+	} else {
+		builder = builder.
+			Column("0::real AS rank").
+			Column("'' AS name_highlight").
+			Column("'' AS description_highlight").
+			Column("'' AS content_highlight").
+			OrderBy("shaders.created_at DESC", "shaders.shader_id DESC")
+	}
+
+	if len(tags) > 0 {
+		builder = builder.Where("shaders.tags @> ?", tags)
+	}
+
+	return builder.
+		Where(squirrel.Eq{"shaders.deleted_at": nil}).
+		Where(shaderListedTo(currentUser))
+}
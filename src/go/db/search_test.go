@@ -0,0 +1,33 @@
+package db
+
+import (
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRenderHighlight(t *testing.T) {
+	tests := []struct {
+		name     string
+		snippet  string
+		expected string
+	}{
+		{"no match", "fn main() {}", ""},
+		{"match", "a \x02raymarching\x03 study", "a <mark>raymarching</mark> study"},
+		{"escapes markup", "let v = vec3<f32>(\x02noise\x03);", "let v = vec3&lt;f32&gt;(<mark>noise</mark>);"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, renderHighlight(tt.snippet))
+		})
+	}
+}
+
+func TestShaderSearchQuery(t *testing.T) {
+	sql, args, err := shaderSearchQuery("", []string{"noise"}, "collaborator").ToSql()
+	assert.NoError(t, err)
+
+	// shaders shared with the user are found alongside public shaders and their own, unlisted ones of others are not
+	assert.Contains(t, sql, "WHERE shaders.tags @> $1 AND shaders.deleted_at IS NULL AND (shaders.visibility = $2 OR shaders.created_by = $3 OR EXISTS (SELECT 1 FROM shader_collaborators WHERE shader_collaborators.shader_id = shaders.shader_id AND shader_collaborators.role IN ($4,$5) AND shader_collaborators.user_id = $6))")
+	assert.Equal(t, []any{[]string{"noise"}, "public", "collaborator", models.CollaboratorRoleViewer, models.CollaboratorRoleEditor, "collaborator"}, args)
+}
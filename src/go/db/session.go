@@ -0,0 +1,202 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/sdedovic/wgsltoy-server/src/go/guid"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"log/slog"
+	"time"
+)
+
+func (repo *Repository) SessionCreate(ctx context.Context, userId string, refreshTokenHash string, userAgent string, expiresAt time.Time) (models.Session, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	session := models.Session{
+		Id:               guid.New(),
+		UserId:           userId,
+		CreatedAt:        time.Now(),
+		ExpiresAt:        expiresAt,
+		UserAgent:        userAgent,
+		RefreshTokenHash: refreshTokenHash,
+	}
+	session.LastUsedAt = session.CreatedAt
+
+	sql, args, err := psql.Insert("sessions").
+		Columns("session_id", "user_id", "created_at", "last_used_at", "expires_at", "user_agent", "refresh_token_hash").
+		Values(session.Id, session.UserId, session.CreatedAt, session.LastUsedAt, session.ExpiresAt, session.UserAgent, session.RefreshTokenHash).
+		ToSql()
+	if err != nil {
+		return models.Session{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	_, err = repo.pg.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return models.Session{}, fmt.Errorf("failed inserting session caused by: %w", err)
+	}
+
+	return session, nil
+}
+
+// SessionGetActiveById returns the session unless it was revoked or has expired
+func (repo *Repository) SessionGetActiveById(ctx context.Context, sessionId string) (models.Session, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.Select("*").
+		From("sessions").
+		Where(squirrel.Eq{"session_id": sessionId, "revoked_at": nil}).
+		Where(squirrel.Gt{"expires_at": time.Now()}).
+		ToSql()
+	if err != nil {
+		return models.Session{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	rows, _ := repo.pg.pool.Query(ctx, sql, args...)
+	session, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.Session])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Session{}, infra.NotFoundError
+		}
+		return models.Session{}, fmt.Errorf("failed querying session caused by: %w", err)
+	}
+
+	return session, nil
+}
+
+// SessionRotate replaces the refresh token of the active session holding refreshTokenHash, extending it until
+// expiresAt. Presenting a refresh token which was already rotated away means it leaked, so the session it belongs to
+// is revoked. Either way infra.NotFoundError is returned when no active session holds refreshTokenHash.
+func (repo *Repository) SessionRotate(ctx context.Context, refreshTokenHash string, newRefreshTokenHash string, expiresAt time.Time) (models.Session, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	now := time.Now()
+	sql, args, err := psql.Update("sessions").
+		Set("refresh_token_hash", newRefreshTokenHash).
+		Set("previous_token_hash", refreshTokenHash).
+		Set("last_used_at", now).
+		Set("expires_at", expiresAt).
+		Where(squirrel.Eq{"refresh_token_hash": refreshTokenHash, "revoked_at": nil}).
+		Where(squirrel.Gt{"expires_at": now}).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return models.Session{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	rows, _ := repo.pg.pool.Query(ctx, sql, args...)
+	session, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.Session])
+	if err == nil {
+		return session, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return models.Session{}, fmt.Errorf("failed rotating session caused by: %w", err)
+	}
+
+	sql, args, err = psql.Update("sessions").
+		Set("revoked_at", now).
+		Where(squirrel.Eq{"previous_token_hash": refreshTokenHash, "revoked_at": nil}).
+		Suffix("RETURNING session_id").
+		ToSql()
+	if err != nil {
+		return models.Session{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	var sessionId string
+	err = repo.pg.pool.QueryRow(ctx, sql, args...).Scan(&sessionId)
+	if err == nil {
+		slog.WarnContext(ctx, "Refresh token reused, revoked session", "sessionId", sessionId)
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return models.Session{}, fmt.Errorf("failed revoking session caused by: %w", err)
+	}
+
+	return models.Session{}, infra.NotFoundError
+}
+
+// SessionListActiveByUserId lists the sessions of the user which were neither revoked nor have expired, most recently
+// used first
+func (repo *Repository) SessionListActiveByUserId(ctx context.Context, userId string) ([]models.Session, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.Select("*").
+		From("sessions").
+		Where(squirrel.Eq{"user_id": userId, "revoked_at": nil}).
+		Where(squirrel.Gt{"expires_at": time.Now()}).
+		OrderBy("last_used_at DESC").
+		Limit(100).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	rows, _ := repo.pg.pool.Query(ctx, sql, args...)
+	sessions, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.Session])
+	if err != nil {
+		return nil, fmt.Errorf("failed querying sessions caused by: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// SessionRevoke revokes a session of the user, returning infra.NotFoundError when they have no such active session
+func (repo *Repository) SessionRevoke(ctx context.Context, sessionId string, userId string) error {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.Update("sessions").
+		Set("revoked_at", time.Now()).
+		Where(squirrel.Eq{"session_id": sessionId, "user_id": userId, "revoked_at": nil}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	tag, err := repo.pg.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed revoking session caused by: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return infra.NotFoundError
+	}
+
+	return nil
+}
+
+// SessionRevokeAllByUserId revokes every session of the user except exceptSessionId, which may be empty
+func (repo *Repository) SessionRevokeAllByUserId(ctx context.Context, userId string, exceptSessionId string) error {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	return pgx.BeginFunc(ctx, repo.pg.pool, func(tx pgx.Tx) error {
+		return revokeSessions(ctx, tx, userId, exceptSessionId, time.Now())
+	})
+}
+
+// revokeSessions revokes every session of the user except exceptSessionId, which may be empty
+func revokeSessions(ctx context.Context, tx pgx.Tx, userId string, exceptSessionId string, revokedAt time.Time) error {
+	builder := psql.Update("sessions").
+		Set("revoked_at", revokedAt).
+		Where(squirrel.Eq{"user_id": userId, "revoked_at": nil})
+	if exceptSessionId != "" {
+		builder = builder.Where(squirrel.NotEq{"session_id": exceptSessionId})
+	}
+
+	sql, args, err := builder.ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed revoking sessions caused by: %w", err)
+	}
+
+	return nil
+}
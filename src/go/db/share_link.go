@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/sdedovic/wgsltoy-server/src/go/guid"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"time"
+)
+
+func (repo *Repository) ShareLinkCreate(ctx context.Context, shaderId string, createdBy string, tokenHash string, expiresAt *time.Time) (models.ShareLink, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	shareLink := models.ShareLink{
+		Id:        guid.New(),
+		ShaderId:  shaderId,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+		TokenHash: tokenHash,
+	}
+
+	sql, args, err := psql.Insert("share_links").
+		Columns("link_id", "shader_id", "created_by", "created_at", "expires_at", "token_hash").
+		Values(shareLink.Id, shareLink.ShaderId, shareLink.CreatedBy, shareLink.CreatedAt, shareLink.ExpiresAt, shareLink.TokenHash).
+		ToSql()
+	if err != nil {
+		return models.ShareLink{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	_, err = repo.pg.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return models.ShareLink{}, fmt.Errorf("failed inserting share link caused by: %w", err)
+	}
+
+	return shareLink, nil
+}
+
+// ShareLinkUse looks up the active share link with tokenHash, recording that it was used. It returns
+// infra.NotFoundError when the link does not exist, was revoked or has expired.
+func (repo *Repository) ShareLinkUse(ctx context.Context, tokenHash string) (models.ShareLink, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	now := time.Now()
+	sql, args, err := psql.Update("share_links").
+		Set("last_used_at", now).
+		Where(squirrel.Eq{"token_hash": tokenHash, "revoked_at": nil}).
+		Where(squirrel.Or{
+			squirrel.Eq{"expires_at": nil},
+			squirrel.Gt{"expires_at": now},
+		}).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return models.ShareLink{}, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	rows, _ := repo.pg.pool.Query(ctx, sql, args...)
+	shareLink, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.ShareLink])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ShareLink{}, infra.NotFoundError
+		}
+		return models.ShareLink{}, fmt.Errorf("failed querying share link caused by: %w", err)
+	}
+
+	return shareLink, nil
+}
+
+// ShareLinkListActiveByShaderId lists the share links of the shader which were not revoked, newest first
+func (repo *Repository) ShareLinkListActiveByShaderId(ctx context.Context, shaderId string) ([]models.ShareLink, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.Select("*").
+		From("share_links").
+		Where(squirrel.Eq{"shader_id": shaderId, "revoked_at": nil}).
+		OrderBy("created_at DESC").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	rows, err := repo.pg.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed querying share links caused by: %w", err)
+	}
+
+	shareLinks, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.ShareLink])
+	if err != nil {
+		return nil, fmt.Errorf("failed deserializing database rows caused by: %w", err)
+	}
+	return shareLinks, nil
+}
+
+func (repo *Repository) ShareLinkRevoke(ctx context.Context, linkId string, shaderId string) error {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.Update("share_links").
+		Set("revoked_at", time.Now()).
+		Where(squirrel.Eq{"link_id": linkId, "shader_id": shaderId, "revoked_at": nil}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed building sql caused by: %w", err)
+	}
+
+	tag, err := repo.pg.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed revoking share link caused by: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return infra.NotFoundError
+	}
+
+	return nil
+}
+
+// ShaderGetById looks up a shader regardless of its visibility, for access granted by other means such as share links
+func (repo *Repository) ShaderGetById(ctx context.Context, shaderId string) (models.Shader, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, repo.config.Database.OperationTimeout)
+	defer cancelFunc()
+
+	sql, args, err := psql.
+		Select(shaderColumns...).
+		From("shaders").
+		Where(squirrel.Eq{"shader_id": shaderId, "deleted_at": nil}).
+		ToSql()
+	if err != nil {
+		return models.Shader{}, err
+	}
+
+	rows, _ := repo.pg.pool.Query(ctx, sql, args...)
+	shader, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.Shader])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Shader{}, infra.NotFoundError
+		}
+		return models.Shader{}, fmt.Errorf("failed deserializing database rows caused by: %w", err)
+	}
+
+	return shader, nil
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"github.com/Masterminds/squirrel"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+)
+
+// shaderVisibleTo matches the shaders currentUser may open by id: anything public or unlisted, their own private
+// shaders and those shared with them. An empty currentUser stands for an anonymous visitor.
+func shaderVisibleTo(currentUser string) squirrel.Sqlizer {
+	return squirrel.Or{
+		squirrel.Eq{"shaders.visibility": []string{"public", "unlisted"}},
+		squirrel.Eq{"shaders.visibility": "private", "shaders.created_by": currentUser},
+		shaderSharedWith(currentUser, models.CollaboratorRoleViewer, models.CollaboratorRoleEditor),
+	}
+}
+
+// shaderListedTo matches the shaders that may appear in listings and search results for currentUser: anything public,
+// their own shaders and those shared with them. This is narrower than shaderVisibleTo only in that unlisted shaders of
+// other users are reachable by id alone.
+func shaderListedTo(currentUser string) squirrel.Sqlizer {
+	return squirrel.Or{
+		squirrel.Eq{"shaders.visibility": "public"},
+		squirrel.Eq{"shaders.created_by": currentUser},
+		shaderSharedWith(currentUser, models.CollaboratorRoleViewer, models.CollaboratorRoleEditor),
+	}
+}
+
+// shaderEditableBy matches the shaders currentUser may change: their own and those they are an editor of
+func shaderEditableBy(currentUser string) squirrel.Sqlizer {
+	return squirrel.Or{
+		squirrel.Eq{"shaders.created_by": currentUser},
+		shaderSharedWith(currentUser, models.CollaboratorRoleEditor),
+	}
+}
+
+// shaderSharedWith matches the shaders currentUser collaborates on in one of roles
+func shaderSharedWith(currentUser string, roles ...string) squirrel.Sqlizer {
+	return squirrel.Expr("EXISTS (?)", squirrel.
+		Select("1").
+		From("shader_collaborators").
+		Where("shader_collaborators.shader_id = shaders.shader_id").
+		Where(squirrel.Eq{"shader_collaborators.user_id": currentUser, "shader_collaborators.role": roles}))
+}
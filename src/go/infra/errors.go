@@ -3,6 +3,7 @@ package infra
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 //==== JSON Parsing ====\\
@@ -39,10 +40,6 @@ func NewValidationError(message string) error {
 	return ValidationError{message}
 }
 
-func (e ValidationError) Is() {
-
-}
-
 //==== Misc. ====\\
 
 // BadLoginError occurs when the provided credentials fail to authenticate
@@ -51,5 +48,35 @@ var BadLoginError = errors.New("bad login")
 // UnauthorizedError occurs when a user lacks access while attempting to perform an operation
 var UnauthorizedError = errors.New("unauthorized")
 
+// InsufficientScopeError occurs when a user authenticated with a token which does not permit the operation
+var InsufficientScopeError = errors.New("insufficient scope")
+
+// ForbiddenError occurs when a user may see a resource but lacks permission to perform the operation on it
+var ForbiddenError = errors.New("forbidden")
+
 // NotFoundError occurs when a resource is not found
 var NotFoundError = errors.New("not found")
+
+// PreconditionFailedError occurs when a conditional request's precondition, such as an If-Match header, does not hold
+var PreconditionFailedError = errors.New("precondition failed")
+
+// ConflictError occurs when a resource keeps changing concurrently, so an unconditional update of it could not be applied
+var ConflictError = errors.New("conflict")
+
+// EmailNotVerifiedError occurs when an operation requires the user to have verified their email address
+var EmailNotVerifiedError = errors.New("email not verified")
+
+//==== Rate Limiting ====\\
+
+// TooManyRequestsError occurs when an operation is repeated too soon, it may be retried after RetryAfter
+type TooManyRequestsError struct {
+	RetryAfter time.Duration
+}
+
+func (e TooManyRequestsError) Error() string {
+	return fmt.Sprintf("Too many requests, retry after %s.", e.RetryAfter.Round(time.Second))
+}
+
+func NewTooManyRequestsError(retryAfter time.Duration) error {
+	return TooManyRequestsError{retryAfter}
+}
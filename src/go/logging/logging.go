@@ -0,0 +1,82 @@
+// Package logging sets up structured logging with log/slog. Records logged with a context carrying a request ID are
+// tagged with it, so every line written while handling a request can be correlated.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"github.com/sdedovic/wgsltoy-server/src/go/config"
+	"github.com/sdedovic/wgsltoy-server/src/go/guid"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+type ContextKeyType string
+
+const RequestIdContextKey ContextKeyType = "REQUEST_ID"
+
+// maxRequestIdLength bounds request IDs accepted from clients, longer ones are replaced
+const maxRequestIdLength = 128
+
+func ExtractRequestIdFromContext(ctx context.Context) string {
+	requestId, _ := ctx.Value(RequestIdContextKey).(string)
+	return requestId
+}
+
+func InsertRequestIdIntoContext(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, RequestIdContextKey, requestId)
+}
+
+// RequestId returns the request ID supplied by a client or proxy when it is safe to log, otherwise a new one
+func RequestId(supplied string) string {
+	if supplied == "" || len(supplied) > maxRequestIdLength {
+		return guid.New()
+	}
+	for _, r := range supplied {
+		if r <= ' ' || r > '~' {
+			return guid.New()
+		}
+	}
+	return supplied
+}
+
+// New creates a logger writing to w in the format and from the level given by logConfig
+func New(logConfig config.LogConfig, w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(logConfig.Level)); err != nil {
+		return nil, fmt.Errorf("invalid log level caused by: %w", err)
+	}
+	options := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch strings.ToLower(logConfig.Format) {
+	case "json":
+		handler = slog.NewJSONHandler(w, options)
+	case "text":
+		handler = slog.NewTextHandler(w, options)
+	default:
+		return nil, fmt.Errorf("unknown log format '%s'", logConfig.Format)
+	}
+	return slog.New(contextHandler{handler}), nil
+}
+
+// contextHandler adds the request ID found in the context of a record to it
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestId := ExtractRequestIdFromContext(ctx); requestId != "" {
+		record.AddAttrs(slog.String("requestId", requestId))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
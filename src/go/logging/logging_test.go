@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/sdedovic/wgsltoy-server/src/go/config"
+	"github.com/sdedovic/wgsltoy-server/src/go/guid"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestRequestId(t *testing.T) {
+	assert.Equal(t, "abc-123", RequestId("abc-123"))
+	assert.True(t, guid.Validate(RequestId("")))
+	assert.True(t, guid.Validate(RequestId("has space")))
+	assert.True(t, guid.Validate(RequestId("line\nbreak")))
+	assert.True(t, guid.Validate(RequestId(strings.Repeat("a", maxRequestIdLength+1))))
+}
+
+func TestNew_RequestId(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := New(config.LogConfig{Level: "info", Format: "json"}, &out)
+	assert.NoError(t, err)
+
+	ctx := InsertRequestIdIntoContext(context.Background(), "abc-123")
+	logger.With("component", "test").InfoContext(ctx, "Handled request")
+	logger.DebugContext(ctx, "Below the level")
+
+	var record map[string]any
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &record))
+	assert.Equal(t, "abc-123", record["requestId"])
+	assert.Equal(t, "test", record["component"])
+
+	_, err = New(config.LogConfig{Level: "loud", Format: "json"}, &out)
+	assert.Error(t, err)
+}
@@ -0,0 +1,41 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"github.com/sdedovic/wgsltoy-server/src/go/guid"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// LogMailer writes emails to the application log instead of delivering them, for local development
+type LogMailer struct{}
+
+func (m *LogMailer) Send(ctx context.Context, message Message) error {
+	slog.InfoContext(ctx, "Mail", "to", message.To, "subject", message.Subject, "body", message.Body)
+	return nil
+}
+
+// FileMailer writes each email to its own .eml file in a directory instead of delivering it, for local development
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir string, from string) (*FileMailer, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create mail directory caused by: %w", err)
+	}
+	return &FileMailer{dir, from}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, message Message) error {
+	path := filepath.Join(m.dir, guid.New()+".eml")
+	err := os.WriteFile(path, format(m.from, message), 0o644)
+	if err != nil {
+		return fmt.Errorf("failed writing mail caused by: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/sdedovic/wgsltoy-server/src/go/config"
+	"mime"
+	"strings"
+	"time"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails to users
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// NewMailer creates the Mailer selected by the configured transport, one of 'log', 'file' or 'smtp'
+func NewMailer(mailConfig config.MailConfig) (Mailer, error) {
+	switch mailConfig.Transport {
+	case "log":
+		return &LogMailer{}, nil
+	case "file":
+		return NewFileMailer(mailConfig.Dir, mailConfig.From)
+	case "smtp":
+		return NewSmtpMailer(mailConfig.SmtpHost, mailConfig.SmtpPort, mailConfig.SmtpUsername, mailConfig.SmtpPassword, mailConfig.From), nil
+	default:
+		return nil, fmt.Errorf("unsupported mail transport: %s", mailConfig.Transport)
+	}
+}
+
+// format renders message as an RFC 5322 email
+func format(from string, message Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", message.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(message.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return buf.Bytes()
+}
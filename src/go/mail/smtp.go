@@ -0,0 +1,37 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+)
+
+// SmtpMailer delivers emails through an SMTP relay, authenticating when a username is configured
+type SmtpMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSmtpMailer(host string, port string, username string, password string, from string) *SmtpMailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SmtpMailer{net.JoinHostPort(host, port), auth, from}
+}
+
+func (m *SmtpMailer) Send(ctx context.Context, message Message) error {
+	sender, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return fmt.Errorf("invalid sender address caused by: %w", err)
+	}
+
+	err = smtp.SendMail(m.addr, m.auth, sender.Address, []string{message.To}, format(m.from, message))
+	if err != nil {
+		return fmt.Errorf("failed sending mail caused by: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+// Package metrics collects application metrics and exposes them in the Prometheus text exposition format, so they can
+// be scraped without running anything besides the server.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds of histogram buckets suited to request latencies, in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// family is a metric with all of its samples
+type family interface {
+	name() string
+	write(w io.Writer)
+}
+
+// Registry holds the metrics exposed together, in the order they were registered
+type Registry struct {
+	mu       sync.Mutex
+	families []family
+}
+
+// Default is the registry the package level constructors register metrics with
+var Default = &Registry{}
+
+func (r *Registry) register(f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, registered := range r.families {
+		if registered.name() == f.name() {
+			panic(fmt.Sprintf("metrics: metric %s is already registered", f.name()))
+		}
+	}
+	r.families = append(r.families, f)
+}
+
+// Write writes every metric in the Prometheus text exposition format
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	families := slices.Clone(r.families)
+	r.mu.Unlock()
+
+	var buf bytes.Buffer
+	for _, f := range families {
+		f.write(&buf)
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// Handler serves the metrics of registry to Prometheus
+func Handler(registry *Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" && r.Method != "HEAD" {
+			w.Header().Set("Allow", "GET, HEAD")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = registry.Write(w)
+	}
+}
+
+// desc describes a metric, its samples are distinguished by the values of its labels
+type desc struct {
+	metricName string
+	help       string
+	metricType string
+	labels     []string
+}
+
+func (d desc) name() string {
+	return d.metricName
+}
+
+func (d desc) writeHeader(w io.Writer) {
+	help := strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(d.help)
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.metricName, help, d.metricName, d.metricType)
+}
+
+// key identifies the sample with labelValues, panicking when their number does not match the labels of the metric
+func (d desc) key(labelValues []string) string {
+	if len(labelValues) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.metricName, len(d.labels), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+// formatLabels renders labels and their values, with extra appended as is, such as the 'le' label of buckets
+func (d desc) formatLabels(labelValues []string, extra string) string {
+	parts := make([]string, 0, len(d.labels)+1)
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	for idx, label := range d.labels {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, label, escape.Replace(labelValues[idx])))
+	}
+	if extra != "" {
+		parts = append(parts, extra)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+//==== Counter ====\\
+
+// CounterVec is a count that only goes up, such as the number of requests handled
+type CounterVec struct {
+	desc
+	mu      sync.Mutex
+	samples map[string]*counterSample
+}
+
+type counterSample struct {
+	labelValues []string
+	value       float64
+}
+
+// NewCounterVec creates a counter with the given labels in the Default registry
+func NewCounterVec(name string, help string, labels ...string) *CounterVec {
+	return Default.NewCounterVec(name, help, labels...)
+}
+
+func (r *Registry) NewCounterVec(name string, help string, labels ...string) *CounterVec {
+	counter := &CounterVec{desc: desc{name, help, "counter", labels}, samples: map[string]*counterSample{}}
+
+	// a counter without labels is reported as zero before it is first counted
+	if len(labels) == 0 {
+		counter.Add(0)
+	}
+	r.register(counter)
+	return counter
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(value float64, labelValues ...string) {
+	key := c.key(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sample, ok := c.samples[key]
+	if !ok {
+		sample = &counterSample{labelValues: slices.Clone(labelValues)}
+		c.samples[key] = sample
+	}
+	sample.value += value
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writeHeader(w)
+	for _, key := range sortedKeys(c.samples) {
+		sample := c.samples[key]
+		fmt.Fprintf(w, "%s%s %s\n", c.metricName, c.formatLabels(sample.labelValues, ""), formatValue(sample.value))
+	}
+}
+
+//==== Histogram ====\\
+
+// HistogramVec counts observations, such as request latencies, in buckets by their size
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	samples map[string]*histogramSample
+}
+
+type histogramSample struct {
+	labelValues []string
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+// NewHistogramVec creates a histogram with the given bucket upper bounds and labels in the Default registry
+func NewHistogramVec(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	return Default.NewHistogramVec(name, help, buckets, labels...)
+}
+
+func (r *Registry) NewHistogramVec(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+
+	histogram := &HistogramVec{desc: desc{name, help, "histogram", labels}, buckets: buckets, samples: map[string]*histogramSample{}}
+	r.register(histogram)
+	return histogram
+}
+
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := h.key(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sample, ok := h.samples[key]
+	if !ok {
+		sample = &histogramSample{labelValues: slices.Clone(labelValues), counts: make([]uint64, len(h.buckets))}
+		h.samples[key] = sample
+	}
+
+	// buckets are counted individually here and made cumulative when written
+	if idx, _ := slices.BinarySearch(h.buckets, value); idx < len(h.buckets) {
+		sample.counts[idx]++
+	}
+	sample.count++
+	sample.sum += value
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.writeHeader(w)
+	for _, key := range sortedKeys(h.samples) {
+		sample := h.samples[key]
+
+		var cumulative uint64
+		for idx, bound := range h.buckets {
+			cumulative += sample.counts[idx]
+			le := fmt.Sprintf(`le="%s"`, formatValue(bound))
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, h.formatLabels(sample.labelValues, le), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, h.formatLabels(sample.labelValues, `le="+Inf"`), sample.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.metricName, h.formatLabels(sample.labelValues, ""), formatValue(sample.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.metricName, h.formatLabels(sample.labelValues, ""), sample.count)
+	}
+}
+
+//==== Func ====\\
+
+// funcMetric reports the value returned by a function when scraped, for values tracked elsewhere such as pool stats
+type funcMetric struct {
+	desc
+	value func() float64
+}
+
+// NewGaugeFunc reports the value of fn, which may go up and down, from the Default registry
+func NewGaugeFunc(name string, help string, fn func() float64) {
+	Default.NewGaugeFunc(name, help, fn)
+}
+
+func (r *Registry) NewGaugeFunc(name string, help string, fn func() float64) {
+	r.register(&funcMetric{desc{name, help, "gauge", nil}, fn})
+}
+
+// NewCounterFunc reports the value of fn, which only goes up, from the Default registry
+func NewCounterFunc(name string, help string, fn func() float64) {
+	Default.NewCounterFunc(name, help, fn)
+}
+
+func (r *Registry) NewCounterFunc(name string, help string, fn func() float64) {
+	r.register(&funcMetric{desc{name, help, "counter", nil}, fn})
+}
+
+func (f *funcMetric) write(w io.Writer) {
+	f.writeHeader(w)
+	fmt.Fprintf(w, "%s %s\n", f.metricName, formatValue(f.value()))
+}
+
+func sortedKeys[V any](samples map[string]V) []string {
+	keys := make([]string, 0, len(samples))
+	for key := range samples {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
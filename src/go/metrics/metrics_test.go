@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCounterVec(t *testing.T) {
+	registry := &Registry{}
+	counter := registry.NewCounterVec("logins_total", "Login attempts, by result", "result")
+	counter.Inc("success")
+	counter.Inc("failure")
+	counter.Add(2, "success")
+
+	var out strings.Builder
+	assert.NoError(t, registry.Write(&out))
+	assert.Equal(t, `# HELP logins_total Login attempts, by result
+# TYPE logins_total counter
+logins_total{result="failure"} 1
+logins_total{result="success"} 3
+`, out.String())
+
+	assert.Panics(t, func() { counter.Inc() }, "label values must match the labels")
+	assert.Panics(t, func() { registry.NewCounterVec("logins_total", "Duplicate") })
+}
+
+func TestHistogramVec(t *testing.T) {
+	registry := &Registry{}
+	histogram := registry.NewHistogramVec("latency_seconds", "Latency", []float64{1, 0.1}, "route")
+	histogram.Observe(0.05, `/shader/{id}`)
+	histogram.Observe(0.1, `/shader/{id}`)
+	histogram.Observe(0.5, `/shader/{id}`)
+	histogram.Observe(3, `/shader/{id}`)
+
+	var out strings.Builder
+	assert.NoError(t, registry.Write(&out))
+	assert.Equal(t, `# HELP latency_seconds Latency
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/shader/{id}",le="0.1"} 2
+latency_seconds_bucket{route="/shader/{id}",le="1"} 3
+latency_seconds_bucket{route="/shader/{id}",le="+Inf"} 4
+latency_seconds_sum{route="/shader/{id}"} 3.65
+latency_seconds_count{route="/shader/{id}"} 4
+`, out.String())
+}
+
+func TestHandler(t *testing.T) {
+	registry := &Registry{}
+	registry.NewCounterVec("shader_creates_total", "Shaders created")
+	registry.NewGaugeFunc("idle_conns", "Idle connections", func() float64 { return 3 })
+	registry.NewCounterVec("escaped_total", "Line one\nline two", "label").Inc(`quote " and \ backslash`)
+
+	w := httptest.NewRecorder()
+	Handler(registry)(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "shader_creates_total 0\n")
+	assert.Contains(t, w.Body.String(), "# TYPE idle_conns gauge\nidle_conns 3\n")
+	assert.Contains(t, w.Body.String(), "# HELP escaped_total Line one\\nline two\n")
+	assert.Contains(t, w.Body.String(), `escaped_total{label="quote \" and \\ backslash"} 1`)
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// AccessToken is a personal access token, used by scripts to call the API on behalf of a user
+type AccessToken struct {
+	Id         string     `json:"id" db:"token_id"`
+	UserId     string     `json:"-" db:"user_id"`
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+	ExpiresAt  time.Time  `json:"expiresAt" db:"expires_at"`
+	LastUsedAt *time.Time `json:"lastUsedAt" db:"last_used_at"`
+	Location   string     `json:"location" db:"-"`
+
+	Name   string   `json:"name" db:"name"`
+	Scopes []string `json:"scopes" db:"scopes"`
+
+	TokenHash string     `json:"-" db:"token_hash"`
+	RevokedAt *time.Time `json:"-" db:"revoked_at"`
+}
+
+type AccessTokenCreate struct {
+	Name      string    `json:"name"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Scopes    []string  `json:"scopes"`
+}
+
+// AccessTokenCreated is returned once when creating an access token, it is the only time the token itself is revealed
+type AccessTokenCreated struct {
+	AccessToken
+	Token string `json:"token"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+const CollaboratorRoleViewer = "viewer"
+const CollaboratorRoleEditor = "editor"
+
+// Collaborator is a user the owner of a shader shared it with. Viewers may see the shader regardless of its visibility,
+// editors may also change it.
+type Collaborator struct {
+	UserId    string    `json:"-" db:"user_id"`
+	Location  string    `json:"location" db:"-"`
+	Username  string    `json:"username" db:"username"`
+	Role      string    `json:"role" db:"role"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+type CollaboratorUpsert struct {
+	Role string `json:"role"`
+}
+
+type ShaderTransfer struct {
+	Username string `json:"username"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+type CollectionCreate struct {
+	Name        string `json:"name"`
+	Visibility  string `json:"visibility"`
+	Description string `json:"description"`
+}
+
+type CollectionPartialUpdate struct {
+	Name        *string `json:"name"`
+	Visibility  *string `json:"visibility"`
+	Description *string `json:"description"`
+}
+
+// Collection is an ordered, curated list of shaders, excluding the shaders themselves.
+type Collection struct {
+	Id        string    `json:"id" db:"collection_id"`
+	Location  string    `json:"location" db:"-"`
+	CreatedBy string    `json:"createdBy" db:"created_by"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+
+	Name        string `json:"name" db:"name"`
+	Visibility  string `json:"visibility" db:"visibility"`
+	Description string `json:"description" db:"description"`
+}
+
+// CollectionView is a collection along with its shaders, as far as the viewer is able to see them.
+type CollectionView struct {
+	Collection
+	Items []ShaderInfo `json:"items"`
+}
+
+type CollectionItemAdd struct {
+	ShaderId string `json:"shaderId"`
+}
+
+// CollectionItemsReorder moves the listed shaders to the front of a collection in the given order, the remaining
+// items keep their relative order after them.
+type CollectionItemsReorder struct {
+	ShaderIds []string `json:"shaderIds"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+type CommentCreate struct {
+	Body     string  `json:"body"`
+	ParentId *string `json:"parentId"`
+}
+
+type CommentUpdate struct {
+	Body string `json:"body"`
+}
+
+// Comment is a single comment on a shader, replying to ParentId when set. The body of deleted comments, and of hidden
+// comments for anyone but their author and the owner of the shader, is omitted while keeping their place in the thread.
+type Comment struct {
+	Id        string    `json:"id" db:"comment_id"`
+	Location  string    `json:"location" db:"-"`
+	ShaderId  string    `json:"shaderId" db:"shader_id"`
+	ParentId  *string   `json:"parentId,omitempty" db:"parent_id"`
+	CreatedBy string    `json:"createdBy" db:"created_by"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+
+	Body    string `json:"body" db:"body"`
+	Hidden  bool   `json:"hidden" db:"-"`
+	Deleted bool   `json:"deleted" db:"-"`
+
+	HiddenAt  *time.Time `json:"-" db:"hidden_at"`
+	DeletedAt *time.Time `json:"-" db:"deleted_at"`
+}
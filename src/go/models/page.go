@@ -0,0 +1,25 @@
+package models
+
+const ShaderSortNewest = "newest"
+const ShaderSortUpdated = "updated"
+const ShaderSortName = "name"
+
+// ShaderSortLiked orders the shaders a user likes by when they liked them, it only applies to that listing
+const ShaderSortLiked = "liked"
+
+// ShaderSortDeleted orders the trash by when the shaders were deleted, it only applies to that listing
+const ShaderSortDeleted = "deleted"
+
+// PageRequest describes which slice of a paginated listing to return. Cursor is the opaque value returned as Next on
+// the previous page, empty for the first page.
+type PageRequest struct {
+	Sort   string
+	Cursor string
+	Limit  int
+}
+
+// Page is a single slice of a paginated listing. Next is empty on the last page.
+type Page[T any] struct {
+	Items []T    `json:"items"`
+	Next  string `json:"next,omitempty"`
+}
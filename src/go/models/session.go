@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Session is a device the user logged in from, holding the refresh token used to obtain new access tokens
+type Session struct {
+	Id         string    `json:"id" db:"session_id"`
+	UserId     string    `json:"-" db:"user_id"`
+	CreatedAt  time.Time `json:"createdAt" db:"created_at"`
+	LastUsedAt time.Time `json:"lastUsedAt" db:"last_used_at"`
+	ExpiresAt  time.Time `json:"expiresAt" db:"expires_at"`
+	UserAgent  string    `json:"userAgent" db:"user_agent"`
+
+	RefreshTokenHash  string     `json:"-" db:"refresh_token_hash"`
+	PreviousTokenHash *string    `json:"-" db:"previous_token_hash"`
+	RevokedAt         *time.Time `json:"-" db:"revoked_at"`
+
+	// Current is set for the session the request was made with
+	Current bool `json:"current" db:"-"`
+}
+
+// UserTokens are handed out on login and refresh
+type UserTokens struct {
+	AccessToken  string `json:"accessToken"`
+	TokenType    string `json:"tokenType"`
+	ExpiresIn    int    `json:"expiresIn"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+type UserTokenRefresh struct {
+	RefreshToken string `json:"refreshToken"`
+}
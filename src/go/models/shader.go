@@ -10,7 +10,9 @@ type ShaderCreate struct {
 	Content     string   `json:"content"`
 }
 
-type ShaderPartialUpdate struct {
+// ShaderUpdate is the complete editable state of a shader, which PUT replaces and PATCH patches. Its fields are pointers
+// so a field left out of the request can be told apart from one that was emptied, every field is required.
+type ShaderUpdate struct {
 	Name        *string   `json:"name"`
 	Visibility  *string   `json:"visibility"`
 	Description *string   `json:"description"`
@@ -30,6 +32,11 @@ type ShaderInfo struct {
 	Visibility  string   `json:"visibility" db:"visibility"`
 	Description string   `json:"description" db:"description"`
 	Tags        []string `json:"tags" db:"tags"`
+
+	LikeCount int  `json:"likeCount" db:"like_count"`
+	LikedByMe bool `json:"likedByMe" db:"-"`
+
+	DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
 }
 
 // Shader represents the information about a shader and the code.
@@ -45,4 +52,58 @@ type Shader struct {
 	Description string   `json:"description" db:"description"`
 	Tags        []string `json:"tags" db:"tags"`
 	Content     string   `json:"content" db:"content"`
+	Revision    int      `json:"revision" db:"revision"`
+	Version     int      `json:"version" db:"version"`
+
+	ForkedFromId *string            `json:"-" db:"forked_from"`
+	ForkedFrom   *ShaderAttribution `json:"forkedFrom,omitempty" db:"-"`
+
+	LikeCount int  `json:"likeCount" db:"like_count"`
+	LikedByMe bool `json:"likedByMe" db:"-"`
+
+	DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+}
+
+// ShaderAttribution describes the shader another shader was forked from. When the parent is deleted or no longer
+// visible to the viewer, only Available is set.
+type ShaderAttribution struct {
+	Available bool   `json:"available"`
+	Id        string `json:"id,omitempty"`
+	Location  string `json:"location,omitempty"`
+	CreatedBy string `json:"createdBy,omitempty"`
+	Name      string `json:"name,omitempty"`
+}
+
+// ShaderRevisionInfo represents a single entry in the content history of a shader, excluding the content itself.
+type ShaderRevisionInfo struct {
+	Revision     int       `json:"revision" db:"revision"`
+	Location     string    `json:"location" db:"-"`
+	CreatedBy    string    `json:"createdBy" db:"created_by"`
+	CreatedAt    time.Time `json:"createdAt" db:"created_at"`
+	RevertedFrom *int      `json:"revertedFrom,omitempty" db:"reverted_from"`
+}
+
+// ShaderRevision represents a single entry in the content history of a shader, including the content.
+type ShaderRevision struct {
+	Revision     int       `json:"revision" db:"revision"`
+	Location     string    `json:"location" db:"-"`
+	CreatedBy    string    `json:"createdBy" db:"created_by"`
+	CreatedAt    time.Time `json:"createdAt" db:"created_at"`
+	RevertedFrom *int      `json:"revertedFrom,omitempty" db:"reverted_from"`
+	Content      string    `json:"content" db:"content"`
+}
+
+// ShaderSearchResult represents a shader matching a search query, along with its relevance and the matched snippets.
+type ShaderSearchResult struct {
+	ShaderInfo
+	Rank       float32                `json:"rank"`
+	Highlights ShaderSearchHighlights `json:"highlights"`
+}
+
+// ShaderSearchHighlights are the parts of a shader that matched a search query. Matches are wrapped in <mark> tags,
+// everything else is HTML escaped. Fields without a match are left empty.
+type ShaderSearchHighlights struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Content     string `json:"content,omitempty"`
 }
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// ShareLink grants anyone holding its token read access to a shader, regardless of its visibility
+type ShareLink struct {
+	Id         string     `json:"id" db:"link_id"`
+	ShaderId   string     `json:"shaderId" db:"shader_id"`
+	CreatedBy  string     `json:"createdBy" db:"created_by"`
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty" db:"expires_at"`
+	LastUsedAt *time.Time `json:"lastUsedAt" db:"last_used_at"`
+	Location   string     `json:"location" db:"-"`
+
+	TokenHash string     `json:"-" db:"token_hash"`
+	RevokedAt *time.Time `json:"-" db:"revoked_at"`
+}
+
+type ShareLinkCreate struct {
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// ShareLinkCreated is returned once when creating a share link, it is the only time the token itself is revealed
+type ShareLinkCreated struct {
+	ShareLink
+	Token string `json:"token"`
+	Url   string `json:"url"`
+}
@@ -13,6 +13,9 @@ type UserLogin struct {
 	Password string `json:"password"`
 }
 
+const EmailVerificationPending = "pending"
+const EmailVerificationCompleted = "completed"
+
 type User struct {
 	Id                string    `json:"id" db:"user_id"`
 	CreatedAt         time.Time `json:"createdAt" db:"created_at"`
@@ -21,10 +24,49 @@ type User struct {
 	Email             string    `json:"email" db:"email"`
 	EmailVerification string    `json:"emailVerificationStatus" db:"email_verification"`
 	Password          string    `json:"-" db:"password"`
+	Bio               string    `json:"bio" db:"bio"`
+	Links             []string  `json:"links" db:"links"`
+
+	VerificationSentAt *time.Time `json:"-" db:"verification_sent_at"`
+}
+
+type UserPasswordForgot struct {
+	Email string `json:"email"`
+}
+
+type UserPasswordReset struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type UserPasswordChange struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+type UserVerifyEmail struct {
+	Token string `json:"token"`
+}
+
+type UserProfileUpdate struct {
+	Bio   *string   `json:"bio"`
+	Links *[]string `json:"links"`
 }
 
 // UserPublicProfile represents the public information about a user, omitting things such as email addresses.
 type UserPublicProfile struct {
-	CreatedAt time.Time `json:"createdAt" db:"created_at"`
-	Username  string    `json:"username" db:"username"`
+	Id        string           `json:"-" db:"user_id"`
+	CreatedAt time.Time        `json:"createdAt" db:"created_at"`
+	Username  string           `json:"username" db:"username"`
+	Bio       string           `json:"bio" db:"bio"`
+	Links     []string         `json:"links" db:"links"`
+	Shaders   UserShaderCounts `json:"shaders" db:"-"`
+}
+
+// UserShaderCounts is the number of shaders a user has, by visibility. Unlisted and private shaders are only counted
+// for the user themselves.
+type UserShaderCounts struct {
+	Public   int  `json:"public" db:"public"`
+	Unlisted *int `json:"unlisted,omitempty" db:"unlisted"`
+	Private  *int `json:"private,omitempty" db:"private"`
 }
@@ -0,0 +1,310 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+)
+
+const MediaTypeMergePatch = "application/merge-patch+json"
+const MediaTypeJsonPatch = "application/json-patch+json"
+
+// Patch describes changes to a JSON document
+type Patch interface {
+	// Apply returns document with the changes applied, leaving document itself untouched
+	Apply(document []byte) ([]byte, error)
+}
+
+// unmarshal decodes JSON keeping numbers as json.Number, so they round trip unchanged
+func unmarshal(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	err := decoder.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after top-level value")
+	}
+	return value, nil
+}
+
+//==== JSON Merge Patch ====\\
+
+// MergePatch is a JSON Merge Patch as described in RFC 7396
+type MergePatch struct {
+	patch any
+}
+
+func NewMergePatch(data []byte) (MergePatch, error) {
+	value, err := unmarshal(data)
+	if err != nil {
+		return MergePatch{}, infra.NewJsonParsingError(err)
+	}
+	return MergePatch{value}, nil
+}
+
+func (p MergePatch) Apply(document []byte) ([]byte, error) {
+	target, err := unmarshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing document caused by: %w", err)
+	}
+	return json.Marshal(mergePatch(target, p.patch))
+}
+
+func mergePatch(target any, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = map[string]any{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = mergePatch(targetObject[key], value)
+		}
+	}
+	return targetObject
+}
+
+//==== JSON Patch ====\\
+
+// Operation is a single step of a JsonPatch
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JsonPatch is a JSON Patch as described in RFC 6902. Its operations are applied in order, and the whole patch fails
+// if any of them does.
+type JsonPatch struct {
+	operations []Operation
+}
+
+func NewJsonPatch(data []byte) (JsonPatch, error) {
+	var operations []Operation
+	err := json.Unmarshal(data, &operations)
+	if err != nil {
+		return JsonPatch{}, infra.NewJsonParsingError(err)
+	}
+
+	for idx, operation := range operations {
+		switch operation.Op {
+		case "add", "replace", "test":
+			if operation.Value == nil {
+				return JsonPatch{}, infra.NewValidationError(fmt.Sprintf("Patch operation %d is missing 'value'!", idx))
+			}
+		case "move", "copy":
+			if _, err := parsePointer(operation.From); err != nil {
+				return JsonPatch{}, infra.NewValidationError(fmt.Sprintf("Patch operation %d has an invalid 'from'!", idx))
+			}
+		case "remove":
+		default:
+			return JsonPatch{}, infra.NewValidationError(fmt.Sprintf("Patch operation %d has unsupported 'op' '%s'!", idx, operation.Op))
+		}
+		if _, err := parsePointer(operation.Path); err != nil {
+			return JsonPatch{}, infra.NewValidationError(fmt.Sprintf("Patch operation %d has an invalid 'path'!", idx))
+		}
+	}
+
+	return JsonPatch{operations}, nil
+}
+
+func (p JsonPatch) Apply(document []byte) ([]byte, error) {
+	target, err := unmarshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing document caused by: %w", err)
+	}
+
+	for idx, operation := range p.operations {
+		target, err = applyOperation(target, operation)
+		if err != nil {
+			return nil, infra.NewValidationError(fmt.Sprintf("Patch operation %d ('%s' at '%s') failed: %s!", idx, operation.Op, operation.Path, err.Error()))
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+func applyOperation(target any, operation Operation) (any, error) {
+	path, _ := parsePointer(operation.Path)
+
+	switch operation.Op {
+	case "add":
+		value, err := unmarshal(operation.Value)
+		if err != nil {
+			return nil, err
+		}
+		return add(target, path, value)
+	case "remove":
+		return remove(target, path)
+	case "replace":
+		value, err := unmarshal(operation.Value)
+		if err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return value, nil
+		}
+		target, err = remove(target, path)
+		if err != nil {
+			return nil, err
+		}
+		return add(target, path, value)
+	case "move":
+		from, _ := parsePointer(operation.From)
+		if isProperPrefix(from, path) {
+			return nil, fmt.Errorf("a value can not be moved into itself")
+		}
+		value, err := get(target, from)
+		if err != nil {
+			return nil, err
+		}
+		target, err = remove(target, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(target, path, value)
+	case "copy":
+		from, _ := parsePointer(operation.From)
+		value, err := get(target, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(target, path, deepCopy(value))
+	case "test":
+		expected, err := unmarshal(operation.Value)
+		if err != nil {
+			return nil, err
+		}
+		actual, err := get(target, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(expected, actual) {
+			return nil, fmt.Errorf("value does not match")
+		}
+		return target, nil
+	default:
+		return nil, fmt.Errorf("unsupported operation")
+	}
+}
+
+func add(target any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return update(target, path, func(container any, token string) (any, error) {
+		switch typed := container.(type) {
+		case map[string]any:
+			typed[token] = value
+			return typed, nil
+		case []any:
+			if token == "-" {
+				return append(typed, value), nil
+			}
+			idx, err := arrayIndex(token, len(typed)+1)
+			if err != nil {
+				return nil, err
+			}
+			typed = append(typed, nil)
+			copy(typed[idx+1:], typed[idx:])
+			typed[idx] = value
+			return typed, nil
+		default:
+			return nil, fmt.Errorf("parent is not an object or array")
+		}
+	})
+}
+
+func remove(target any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("the whole document can not be removed")
+	}
+
+	return update(target, path, func(container any, token string) (any, error) {
+		switch typed := container.(type) {
+		case map[string]any:
+			if _, ok := typed[token]; !ok {
+				return nil, fmt.Errorf("member '%s' does not exist", token)
+			}
+			delete(typed, token)
+			return typed, nil
+		case []any:
+			idx, err := arrayIndex(token, len(typed))
+			if err != nil {
+				return nil, err
+			}
+			return append(typed[:idx], typed[idx+1:]...), nil
+		default:
+			return nil, fmt.Errorf("parent is not an object or array")
+		}
+	})
+}
+
+// update descends along path and replaces the container holding its last token with the result of fn
+func update(target any, path []string, fn func(container any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(target, path[0])
+	}
+
+	switch typed := target.(type) {
+	case map[string]any:
+		child, ok := typed[path[0]]
+		if !ok {
+			return nil, fmt.Errorf("member '%s' does not exist", path[0])
+		}
+		updated, err := update(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		typed[path[0]] = updated
+		return typed, nil
+	case []any:
+		idx, err := arrayIndex(path[0], len(typed))
+		if err != nil {
+			return nil, err
+		}
+		updated, err := update(typed[idx], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		typed[idx] = updated
+		return typed, nil
+	default:
+		return nil, fmt.Errorf("'%s' does not refer to an object or array", path[0])
+	}
+}
+
+func get(target any, path []string) (any, error) {
+	for _, token := range path {
+		switch typed := target.(type) {
+		case map[string]any:
+			child, ok := typed[token]
+			if !ok {
+				return nil, fmt.Errorf("member '%s' does not exist", token)
+			}
+			target = child
+		case []any:
+			idx, err := arrayIndex(token, len(typed))
+			if err != nil {
+				return nil, err
+			}
+			target = typed[idx]
+		default:
+			return nil, fmt.Errorf("'%s' does not refer to an object or array", token)
+		}
+	}
+	return target, nil
+}
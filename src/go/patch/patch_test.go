@@ -0,0 +1,97 @@
+package patch
+
+import (
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const document = `{"name":"shader","description":"","tags":["one","two"],"content":"x"}`
+
+func applyMergePatch(t *testing.T, patch string) string {
+	mergePatch, err := NewMergePatch([]byte(patch))
+	assert.NoError(t, err)
+
+	patched, err := mergePatch.Apply([]byte(document))
+	assert.NoError(t, err)
+	return string(patched)
+}
+
+func applyJsonPatch(t *testing.T, patch string) (string, error) {
+	jsonPatch, err := NewJsonPatch([]byte(patch))
+	if err != nil {
+		return "", err
+	}
+
+	patched, err := jsonPatch.Apply([]byte(document))
+	return string(patched), err
+}
+
+func TestMergePatch(t *testing.T) {
+	assert.JSONEq(t,
+		`{"name":"renamed","description":"","tags":["one","two"],"content":"x"}`,
+		applyMergePatch(t, `{"name":"renamed"}`))
+	assert.JSONEq(t,
+		`{"name":"shader","tags":["three"],"content":"x"}`,
+		applyMergePatch(t, `{"description":null,"tags":["three"]}`))
+	assert.JSONEq(t, `["replaced"]`, applyMergePatch(t, `["replaced"]`))
+}
+
+func TestNewMergePatch_Malformed(t *testing.T) {
+	_, err := NewMergePatch([]byte(`{"name":`))
+	assert.IsType(t, infra.JsonParsingError{}, err)
+}
+
+func TestJsonPatch(t *testing.T) {
+	patched, err := applyJsonPatch(t, `[
+		{"op":"test","path":"/name","value":"shader"},
+		{"op":"replace","path":"/name","value":"renamed"},
+		{"op":"add","path":"/tags/-","value":"three"},
+		{"op":"remove","path":"/tags/0"},
+		{"op":"add","path":"/tags/0","value":"zero"},
+		{"op":"copy","from":"/name","path":"/description"},
+		{"op":"move","from":"/content","path":"/source"}
+	]`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"renamed","description":"renamed","tags":["zero","two","three"],"source":"x"}`, patched)
+}
+
+func TestJsonPatch_Failures(t *testing.T) {
+	for _, patch := range []string{
+		`[{"op":"test","path":"/name","value":"other"}]`,
+		`[{"op":"remove","path":"/missing"}]`,
+		`[{"op":"replace","path":"/tags/5","value":"x"}]`,
+		`[{"op":"add","path":"/tags/01","value":"x"}]`,
+		`[{"op":"move","from":"/tags","path":"/tags/0"}]`,
+		`[{"op":"remove","path":""}]`,
+	} {
+		_, err := applyJsonPatch(t, patch)
+		assert.IsType(t, infra.ValidationError{}, err, patch)
+	}
+}
+
+func TestNewJsonPatch_Invalid(t *testing.T) {
+	_, err := NewJsonPatch([]byte(`[{"op":"frobnicate","path":"/name"}]`))
+	assert.IsType(t, infra.ValidationError{}, err)
+
+	_, err = NewJsonPatch([]byte(`[{"op":"add","path":"/name"}]`))
+	assert.IsType(t, infra.ValidationError{}, err)
+
+	_, err = NewJsonPatch([]byte(`[{"op":"add","path":"name","value":"x"}]`))
+	assert.IsType(t, infra.ValidationError{}, err)
+
+	_, err = NewJsonPatch([]byte(`{"op":"add"}`))
+	assert.IsType(t, infra.JsonParsingError{}, err)
+}
+
+func TestJsonPatch_NullValue(t *testing.T) {
+	patched, err := applyJsonPatch(t, `[{"op":"replace","path":"/description","value":null}]`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"shader","description":null,"tags":["one","two"],"content":"x"}`, patched)
+}
+
+func TestParsePointer(t *testing.T) {
+	tokens, err := parsePointer("/a~1b/c~0d")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a/b", "c~d"}, tokens)
+}
@@ -0,0 +1,112 @@
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// parsePointer splits a JSON Pointer as described in RFC 6901 into its unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("pointer must start with '/'")
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for idx, token := range tokens {
+		tokens[idx] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses token as an index into an array, which must be less than limit
+func arrayIndex(token string, limit int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.TrimLeft(token, "0123456789") != "" {
+		return 0, fmt.Errorf("'%s' is not an array index", token)
+	}
+
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx >= limit {
+		return 0, fmt.Errorf("index %s is out of bounds", token)
+	}
+	return idx, nil
+}
+
+// isProperPrefix reports whether prefix refers to an ancestor of path
+func isProperPrefix(prefix []string, path []string) bool {
+	if len(prefix) >= len(path) {
+		return false
+	}
+	for idx := range prefix {
+		if prefix[idx] != path[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+func deepCopy(value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(typed))
+		for key, child := range typed {
+			copied[key] = deepCopy(child)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(typed))
+		for idx, child := range typed {
+			copied[idx] = deepCopy(child)
+		}
+		return copied
+	default:
+		return value
+	}
+}
+
+// equal compares JSON values, treating numbers as equal when they have the same value however they were written
+func equal(a any, b any) bool {
+	aNumber, aOk := a.(json.Number)
+	bNumber, bOk := b.(json.Number)
+	if aOk && bOk {
+		aFloat, aErr := aNumber.Float64()
+		bFloat, bErr := bNumber.Float64()
+		if aErr == nil && bErr == nil {
+			return aFloat == bFloat
+		}
+		return aNumber == bNumber
+	}
+
+	switch aTyped := a.(type) {
+	case map[string]any:
+		bTyped, ok := b.(map[string]any)
+		if !ok || len(aTyped) != len(bTyped) {
+			return false
+		}
+		for key, aChild := range aTyped {
+			bChild, ok := bTyped[key]
+			if !ok || !equal(aChild, bChild) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		bTyped, ok := b.([]any)
+		if !ok || len(aTyped) != len(bTyped) {
+			return false
+		}
+		for idx := range aTyped {
+			if !equal(aTyped[idx], bTyped[idx]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
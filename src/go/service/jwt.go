@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/sdedovic/wgsltoy-server/src/go/infra"
-	"os"
 	"time"
 )
 
@@ -16,6 +15,21 @@ var method = jwt.SigningMethodHS256
 
 type UserInfo struct {
 	Id string
+
+	// SessionId identifies the session the token the user authenticated with belongs to, if it was a JWT
+	SessionId string
+
+	// AccessTokenId identifies the personal access token the user authenticated with, if any
+	AccessTokenId string
+
+	// Scopes limits the operations available to the user, nil when not restricted
+	Scopes []string
+}
+
+// Authenticator resolves the user presenting a bearer token, which is either a JWT made by MakeToken or a personal
+// access token
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*UserInfo, error)
 }
 
 func ExtractUserInfoFromContext(ctx context.Context) *UserInfo {
@@ -32,14 +46,16 @@ func InsertUserInfoIntoContext(ctx context.Context, userInfo *UserInfo) context.
 	return context.WithValue(ctx, ContextKey, userInfo)
 }
 
-func MakeToken(user UserInfo) (string, error) {
+// MakeToken signs an access token for user which is valid for lifetime, clients renew it using their refresh token
+func MakeToken(secret string, lifetime time.Duration, user UserInfo) (string, error) {
 	token := jwt.NewWithClaims(method, jwt.MapClaims{
 		"sub": user.Id,
-		"exp": time.Now().Add(time.Hour * 72).Unix(),
+		"sid": user.SessionId,
+		"exp": time.Now().Add(lifetime).Unix(),
 		"iat": time.Now().Unix(),
 		"iss": issuer,
 	})
-	tokenString, err := token.SignedString([]byte(os.Getenv("APP_SECRET")))
+	tokenString, err := token.SignedString([]byte(secret))
 	if err != nil {
 		return "", fmt.Errorf("failed signing token caused by: %w", err)
 	}
@@ -47,9 +63,9 @@ func MakeToken(user UserInfo) (string, error) {
 	return tokenString, nil
 }
 
-func ParseToken(tokenString string) (*UserInfo, error) {
+func ParseToken(secret string, tokenString string) (*UserInfo, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("APP_SECRET")), nil
+		return []byte(secret), nil
 	},
 		jwt.WithExpirationRequired(),
 		jwt.WithIssuer(issuer),
@@ -65,5 +81,11 @@ func ParseToken(tokenString string) (*UserInfo, error) {
 		return nil, fmt.Errorf("failed extracting subject from token caused by: %w", err)
 	}
 
-	return &UserInfo{subject}, nil
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, infra.UnauthorizedError
+	}
+	sessionId, _ := claims["sid"].(string)
+
+	return &UserInfo{Id: subject, SessionId: sessionId}, nil
 }
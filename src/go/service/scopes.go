@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"slices"
+)
+
+const ScopeShaderRead = "shader:read"
+const ScopeShaderWrite = "shader:write"
+const ScopeUserRead = "user:read"
+
+// ScopeAccount covers managing the account itself, such as its password, sessions and access tokens. It is never
+// granted to personal access tokens.
+const ScopeAccount = "account"
+
+// AccessTokenScopes are the scopes personal access tokens may be granted
+var AccessTokenScopes = []string{ScopeShaderRead, ScopeShaderWrite, ScopeUserRead}
+
+// HasScope reports whether the user may perform operations requiring scope. Users who logged in with their password
+// hold every scope.
+func (u *UserInfo) HasScope(scope string) bool {
+	return u.Scopes == nil || slices.Contains(u.Scopes, scope)
+}
+
+// RequireScope returns the current user, failing with infra.UnauthorizedError when the request is not authenticated and
+// infra.InsufficientScopeError when it lacks scope
+func RequireScope(ctx context.Context, scope string) (*UserInfo, error) {
+	userInfo := ExtractUserInfoFromContext(ctx)
+	if userInfo == nil {
+		return nil, infra.UnauthorizedError
+	}
+	if !userInfo.HasScope(scope) {
+		return nil, infra.InsufficientScopeError
+	}
+	return userInfo, nil
+}
+
+// ExtractUserInfoWithScope returns the current user when the request holds scope, for operations which are also
+// available anonymously
+func ExtractUserInfoWithScope(ctx context.Context, scope string) *UserInfo {
+	userInfo := ExtractUserInfoFromContext(ctx)
+	if userInfo == nil || !userInfo.HasScope(scope) {
+		return nil
+	}
+	return userInfo
+}
@@ -0,0 +1,137 @@
+package shader
+
+import (
+	"context"
+	"errors"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/sdedovic/wgsltoy-server/src/go/service"
+)
+
+func validateCollaboratorRole(role string) error {
+	if role == "" {
+		return infra.NewValidationError("Field 'role' may not be empty!")
+	}
+	if role != models.CollaboratorRoleViewer && role != models.CollaboratorRoleEditor {
+		return infra.NewValidationError("Field 'role' must be one of 'viewer' or 'editor'!")
+	}
+	return nil
+}
+
+// shaderGetOwned looks up a shader visible to the user, failing with infra.ForbiddenError unless they own it
+func (s *Service) shaderGetOwned(ctx context.Context, shaderId string, userId string) (models.Shader, error) {
+	shader, err := s.repo.ShaderGetVisibleByIdAndLoggedInUser(ctx, shaderId, userId)
+	if err != nil {
+		return models.Shader{}, err
+	}
+	if shader.CreatedBy != userId {
+		return models.Shader{}, infra.ForbiddenError
+	}
+	return shader, nil
+}
+
+// CollaboratorList lists who the shader is shared with, which the owner and the collaborators may see
+func (s *Service) CollaboratorList(ctx context.Context, shaderId string) ([]models.Collaborator, error) {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderRead)
+	if err != nil {
+		return nil, err
+	}
+
+	shader, err := s.repo.ShaderGetVisibleByIdAndLoggedInUser(ctx, shaderId, userInfo.Id)
+	if err != nil {
+		return nil, err
+	}
+	if shader.CreatedBy != userInfo.Id {
+		if _, err := s.repo.ShaderCollaboratorGetRole(ctx, shaderId, userInfo.Id); err != nil {
+			if errors.Is(err, infra.NotFoundError) {
+				return nil, infra.ForbiddenError
+			}
+			return nil, err
+		}
+	}
+
+	return s.repo.ShaderCollaboratorList(ctx, shaderId)
+}
+
+// CollaboratorUpsert shares a shader of the current user with another user, or changes their role
+func (s *Service) CollaboratorUpsert(ctx context.Context, shaderId string, username string, collaborator models.CollaboratorUpsert) error {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return err
+	}
+
+	if err := validateCollaboratorRole(collaborator.Role); err != nil {
+		return err
+	}
+
+	if _, err := s.shaderGetOwned(ctx, shaderId, userInfo.Id); err != nil {
+		return err
+	}
+
+	profile, err := s.repo.UserGetProfileByUsername(ctx, username)
+	if err != nil {
+		return err
+	}
+	if profile.Id == userInfo.Id {
+		return infra.NewValidationError("The owner of a shader can not be a collaborator!")
+	}
+
+	return s.repo.ShaderCollaboratorUpsert(ctx, shaderId, profile.Id, collaborator.Role)
+}
+
+// CollaboratorRemove stops sharing a shader with a user. The owner may remove anyone, collaborators only themselves.
+func (s *Service) CollaboratorRemove(ctx context.Context, shaderId string, username string) error {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return err
+	}
+
+	profile, err := s.repo.UserGetProfileByUsername(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	shader, err := s.repo.ShaderGetVisibleByIdAndLoggedInUser(ctx, shaderId, userInfo.Id)
+	if err != nil {
+		return err
+	}
+	if shader.CreatedBy != userInfo.Id && profile.Id != userInfo.Id {
+		return infra.ForbiddenError
+	}
+
+	return s.repo.ShaderCollaboratorRemove(ctx, shaderId, profile.Id)
+}
+
+// ShaderTransfer hands a shader of the current user over to another user, keeping the current user on as an editor
+func (s *Service) ShaderTransfer(ctx context.Context, shaderId string, transfer models.ShaderTransfer) (models.Shader, error) {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return models.Shader{}, err
+	}
+
+	if transfer.Username == "" {
+		return models.Shader{}, infra.NewValidationError("Field 'username' is required!")
+	}
+
+	if _, err := s.shaderGetOwned(ctx, shaderId, userInfo.Id); err != nil {
+		return models.Shader{}, err
+	}
+
+	profile, err := s.repo.UserGetProfileByUsername(ctx, transfer.Username)
+	if err != nil {
+		if errors.Is(err, infra.NotFoundError) {
+			return models.Shader{}, infra.NewValidationError("Field 'username' does not refer to a user!")
+		}
+		return models.Shader{}, err
+	}
+	if profile.Id == userInfo.Id {
+		return models.Shader{}, infra.NewValidationError("The shader is already owned by this user!")
+	}
+
+	transferredShader, err := s.repo.ShaderTransferOwnership(ctx, shaderId, userInfo.Id, profile.Id)
+	if err != nil {
+		return models.Shader{}, err
+	}
+
+	return s.withViewerDetails(ctx, transferredShader)
+}
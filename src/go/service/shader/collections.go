@@ -0,0 +1,150 @@
+package shader
+
+import (
+	"context"
+	"fmt"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/sdedovic/wgsltoy-server/src/go/service"
+)
+
+func (s *Service) CollectionCreate(ctx context.Context, collection models.CollectionCreate) (string, error) {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return "", err
+	}
+
+	if err := validateShaderName(collection.Name, s.config.Limits.ShaderNameLength); err != nil {
+		return "", err
+	}
+
+	if err := validateShaderVisibility(collection.Visibility); err != nil {
+		return "", err
+	}
+
+	if err := validateShaderDescription(collection.Description, s.config.Limits.ShaderDescriptionLength); err != nil {
+		return "", err
+	}
+
+	if err := s.checkMayPublish(ctx, userInfo.Id, collection.Visibility); err != nil {
+		return "", err
+	}
+
+	storedCollection, err := s.repo.CollectionCreate(ctx, collection.Name, collection.Visibility, collection.Description, userInfo.Id)
+	if err != nil {
+		return "", err
+	}
+
+	return storedCollection.Id, nil
+}
+
+// CollectionGet returns a collection along with the shaders in it the current user is able to see
+func (s *Service) CollectionGet(ctx context.Context, collectionId string) (models.CollectionView, error) {
+	viewer := currentUserId(ctx)
+
+	collection, err := s.repo.CollectionGetVisibleById(ctx, collectionId, viewer)
+	if err != nil {
+		return models.CollectionView{}, err
+	}
+
+	items, err := s.repo.CollectionItemList(ctx, collectionId, viewer)
+	if err != nil {
+		return models.CollectionView{}, err
+	}
+
+	items, err = s.withLikedByMe(ctx, items)
+	if err != nil {
+		return models.CollectionView{}, err
+	}
+
+	return models.CollectionView{Collection: collection, Items: items}, nil
+}
+
+func (s *Service) CollectionListCurrentUser(ctx context.Context) ([]models.Collection, error) {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderRead)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.CollectionListByCreatedBy(ctx, userInfo.Id)
+}
+
+func (s *Service) CollectionUpdate(ctx context.Context, collectionId string, collection models.CollectionPartialUpdate) (models.Collection, error) {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return models.Collection{}, err
+	}
+
+	if collection.Name != nil {
+		if err := validateShaderName(*collection.Name, s.config.Limits.ShaderNameLength); err != nil {
+			return models.Collection{}, err
+		}
+	}
+
+	if collection.Visibility != nil {
+		if err := validateShaderVisibility(*collection.Visibility); err != nil {
+			return models.Collection{}, err
+		}
+		if err := s.checkMayPublish(ctx, userInfo.Id, *collection.Visibility); err != nil {
+			return models.Collection{}, err
+		}
+	}
+
+	if collection.Description != nil {
+		if err := validateShaderDescription(*collection.Description, s.config.Limits.ShaderDescriptionLength); err != nil {
+			return models.Collection{}, err
+		}
+	}
+
+	return s.repo.CollectionPartialUpdate(ctx, collectionId, userInfo.Id, collection.Name, collection.Visibility, collection.Description)
+}
+
+func (s *Service) CollectionDelete(ctx context.Context, collectionId string) error {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.CollectionDelete(ctx, collectionId, userInfo.Id)
+}
+
+// CollectionItemAdd appends a shader to a collection of the current user, provided they can see the shader
+func (s *Service) CollectionItemAdd(ctx context.Context, collectionId string, shaderId string) error {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return err
+	}
+
+	if shaderId == "" {
+		return infra.NewValidationError("Field 'shaderId' is required!")
+	}
+
+	if _, err := s.repo.ShaderGetVisibleByIdAndLoggedInUser(ctx, shaderId, userInfo.Id); err != nil {
+		return err
+	}
+
+	return s.repo.CollectionItemAdd(ctx, collectionId, userInfo.Id, shaderId, s.config.Limits.CollectionItems)
+}
+
+func (s *Service) CollectionItemRemove(ctx context.Context, collectionId string, shaderId string) error {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.CollectionItemRemove(ctx, collectionId, userInfo.Id, shaderId)
+}
+
+// CollectionItemsReorder moves the shaders to the front of a collection of the current user in the given order
+func (s *Service) CollectionItemsReorder(ctx context.Context, collectionId string, shaderIds []string) error {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return err
+	}
+
+	if len(shaderIds) > s.config.Limits.CollectionItems {
+		return infra.NewValidationError(fmt.Sprintf("Field 'shaderIds' may contain at most %d entries!", s.config.Limits.CollectionItems))
+	}
+
+	return s.repo.CollectionItemsReorder(ctx, collectionId, userInfo.Id, shaderIds)
+}
@@ -0,0 +1,179 @@
+package shader
+
+import (
+	"context"
+	"errors"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/sdedovic/wgsltoy-server/src/go/service"
+	"strings"
+	"unicode/utf8"
+)
+
+func validateCommentBody(body string, maxLength int) error {
+	if strings.TrimSpace(body) == "" {
+		return infra.NewValidationError("Field 'body' may not be empty!")
+	}
+	if utf8.RuneCountInString(body) > maxLength {
+		return infra.NewValidationError("Field 'body' is too long!")
+	}
+	if !displayMultilineRegex.MatchString(body) {
+		return infra.NewValidationError("Field 'body' contains invalid characters!")
+	}
+	return nil
+}
+
+// redactComment omits the body of deleted comments, and of hidden comments unless the viewer wrote the comment or
+// owns the shader
+func redactComment(comment models.Comment, viewer string, shaderOwner string) models.Comment {
+	comment.Deleted = comment.DeletedAt != nil
+	comment.Hidden = comment.HiddenAt != nil
+
+	if comment.Deleted || (comment.Hidden && viewer != comment.CreatedBy && viewer != shaderOwner) {
+		comment.Body = ""
+	}
+	return comment
+}
+
+// currentUserId returns the id of the current user when the request may read shaders, empty for anonymous visitors
+func currentUserId(ctx context.Context) string {
+	if userInfo := service.ExtractUserInfoWithScope(ctx, service.ScopeShaderRead); userInfo != nil {
+		return userInfo.Id
+	}
+	return ""
+}
+
+func (s *Service) CommentList(ctx context.Context, shaderId string, page models.PageRequest) (models.Page[models.Comment], error) {
+	shader, err := s.shaderGetVisible(ctx, shaderId)
+	if err != nil {
+		return models.Page[models.Comment]{}, err
+	}
+
+	page, err = validatePageLimit(page)
+	if err != nil {
+		return models.Page[models.Comment]{}, err
+	}
+
+	comments, err := s.repo.CommentListByShaderId(ctx, shaderId, page)
+	if err != nil {
+		return models.Page[models.Comment]{}, err
+	}
+
+	viewer := currentUserId(ctx)
+	for idx, comment := range comments.Items {
+		comments.Items[idx] = redactComment(comment, viewer, shader.CreatedBy)
+	}
+	return comments, nil
+}
+
+func (s *Service) CommentGet(ctx context.Context, shaderId string, commentId string) (models.Comment, error) {
+	shader, err := s.shaderGetVisible(ctx, shaderId)
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	comment, err := s.repo.CommentGetById(ctx, shaderId, commentId)
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	return redactComment(comment, currentUserId(ctx), shader.CreatedBy), nil
+}
+
+// CommentCreate adds a comment to the shader, replying to another comment on it when ParentId is set. Private shaders
+// may only be commented on by their owner.
+func (s *Service) CommentCreate(ctx context.Context, shaderId string, comment models.CommentCreate) (string, error) {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return "", err
+	}
+
+	if err := validateCommentBody(comment.Body, s.config.Limits.CommentLength); err != nil {
+		return "", err
+	}
+
+	shader, err := s.repo.ShaderGetVisibleByIdAndLoggedInUser(ctx, shaderId, userInfo.Id)
+	if err != nil {
+		return "", err
+	}
+	if shader.Visibility == VisibilityPrivate && shader.CreatedBy != userInfo.Id {
+		return "", infra.NotFoundError
+	}
+
+	if comment.ParentId != nil {
+		parent, err := s.repo.CommentGetById(ctx, shaderId, *comment.ParentId)
+		if err != nil {
+			if errors.Is(err, infra.NotFoundError) {
+				return "", infra.NewValidationError("Field 'parentId' does not refer to a comment on this shader!")
+			}
+			return "", err
+		}
+		if parent.DeletedAt != nil {
+			return "", infra.NewValidationError("Field 'parentId' refers to a deleted comment!")
+		}
+	}
+
+	created, err := s.repo.CommentCreate(ctx, shaderId, comment.ParentId, userInfo.Id, comment.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return created.Id, nil
+}
+
+func (s *Service) CommentUpdate(ctx context.Context, shaderId string, commentId string, comment models.CommentUpdate) (models.Comment, error) {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	if err := validateCommentBody(comment.Body, s.config.Limits.CommentLength); err != nil {
+		return models.Comment{}, err
+	}
+
+	shader, err := s.repo.ShaderGetVisibleByIdAndLoggedInUser(ctx, shaderId, userInfo.Id)
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	updated, err := s.repo.CommentUpdate(ctx, shaderId, commentId, userInfo.Id, comment.Body)
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	return redactComment(updated, userInfo.Id, shader.CreatedBy), nil
+}
+
+// CommentDelete deletes a comment of the current user. This is allowed even when the shader is no longer visible to
+// them.
+func (s *Service) CommentDelete(ctx context.Context, shaderId string, commentId string) error {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.CommentSoftDelete(ctx, shaderId, commentId, userInfo.Id)
+}
+
+// CommentSetHidden hides or reveals a comment, which only the owner of the shader may do
+func (s *Service) CommentSetHidden(ctx context.Context, shaderId string, commentId string, hidden bool) (models.Comment, error) {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	shader, err := s.repo.ShaderGetVisibleByIdAndLoggedInUser(ctx, shaderId, userInfo.Id)
+	if err != nil {
+		return models.Comment{}, err
+	}
+	if shader.CreatedBy != userInfo.Id {
+		return models.Comment{}, infra.ForbiddenError
+	}
+
+	comment, err := s.repo.CommentSetHidden(ctx, shaderId, commentId, hidden)
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	return redactComment(comment, userInfo.Id, shader.CreatedBy), nil
+}
@@ -3,11 +3,55 @@ package shader
 import (
 	"context"
 	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/sdedovic/wgsltoy-server/src/go/patch"
 )
 
 type IService interface {
 	ShaderCreate(ctx context.Context, shader models.ShaderCreate) (string, error)
-	ShaderUpdate(ctx context.Context, shaderId string, shader models.ShaderPartialUpdate) (models.Shader, error)
-	ShaderInfoListCurrentUser(ctx context.Context) ([]models.ShaderInfo, error)
+	ShaderUpdate(ctx context.Context, shaderId string, shader models.ShaderUpdate) (models.Shader, error)
+	ShaderPatch(ctx context.Context, shaderId string, shaderPatch patch.Patch) (models.Shader, error)
+	ShaderInfoListCurrentUser(ctx context.Context, page models.PageRequest) (models.Page[models.ShaderInfo], error)
+	ShaderInfoListPublic(ctx context.Context, page models.PageRequest) (models.Page[models.ShaderInfo], error)
+	ShaderInfoListByUsername(ctx context.Context, username string, page models.PageRequest) (models.Page[models.ShaderInfo], error)
+	ShaderSearch(ctx context.Context, query string, tags []string, page models.PageRequest) (models.Page[models.ShaderSearchResult], error)
 	ShaderGet(ctx context.Context, shaderId string) (models.Shader, error)
+	ShaderDelete(ctx context.Context, shaderId string) error
+	ShaderRestore(ctx context.Context, shaderId string) (models.Shader, error)
+	ShaderInfoListTrashCurrentUser(ctx context.Context, page models.PageRequest) (models.Page[models.ShaderInfo], error)
+	ShaderRevisionList(ctx context.Context, shaderId string) ([]models.ShaderRevisionInfo, error)
+	ShaderRevisionGet(ctx context.Context, shaderId string, revision int) (models.ShaderRevision, error)
+	ShaderRevert(ctx context.Context, shaderId string, revision int) (models.Shader, error)
+	ShaderFork(ctx context.Context, shaderId string) (string, error)
+	ShaderInfoListForks(ctx context.Context, shaderId string, page models.PageRequest) (models.Page[models.ShaderInfo], error)
+	ShaderLike(ctx context.Context, shaderId string) error
+	ShaderUnlike(ctx context.Context, shaderId string) error
+	ShaderInfoListLikesCurrentUser(ctx context.Context, page models.PageRequest) (models.Page[models.ShaderInfo], error)
+
+	CollaboratorList(ctx context.Context, shaderId string) ([]models.Collaborator, error)
+	CollaboratorUpsert(ctx context.Context, shaderId string, username string, collaborator models.CollaboratorUpsert) error
+	CollaboratorRemove(ctx context.Context, shaderId string, username string) error
+	ShaderTransfer(ctx context.Context, shaderId string, transfer models.ShaderTransfer) (models.Shader, error)
+
+	ShaderGetByShareToken(ctx context.Context, token string) (models.Shader, error)
+	ShareLinkCreate(ctx context.Context, shaderId string, shareLinkCreate models.ShareLinkCreate) (models.ShareLinkCreated, error)
+	ShareLinkList(ctx context.Context, shaderId string) ([]models.ShareLink, error)
+	ShareLinkRevoke(ctx context.Context, shaderId string, linkId string) error
+
+	CommentList(ctx context.Context, shaderId string, page models.PageRequest) (models.Page[models.Comment], error)
+	CommentGet(ctx context.Context, shaderId string, commentId string) (models.Comment, error)
+	CommentCreate(ctx context.Context, shaderId string, comment models.CommentCreate) (string, error)
+	CommentUpdate(ctx context.Context, shaderId string, commentId string, comment models.CommentUpdate) (models.Comment, error)
+	CommentDelete(ctx context.Context, shaderId string, commentId string) error
+	CommentSetHidden(ctx context.Context, shaderId string, commentId string, hidden bool) (models.Comment, error)
+
+	CollectionCreate(ctx context.Context, collection models.CollectionCreate) (string, error)
+	CollectionGet(ctx context.Context, collectionId string) (models.CollectionView, error)
+	CollectionListCurrentUser(ctx context.Context) ([]models.Collection, error)
+	CollectionUpdate(ctx context.Context, collectionId string, collection models.CollectionPartialUpdate) (models.Collection, error)
+	CollectionDelete(ctx context.Context, collectionId string) error
+	CollectionItemAdd(ctx context.Context, collectionId string, shaderId string) error
+	CollectionItemRemove(ctx context.Context, collectionId string, shaderId string) error
+	CollectionItemsReorder(ctx context.Context, collectionId string, shaderIds []string) error
 }
+
+var _ IService = (*Service)(nil)
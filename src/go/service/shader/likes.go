@@ -0,0 +1,109 @@
+package shader
+
+import (
+	"context"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/sdedovic/wgsltoy-server/src/go/service"
+)
+
+// ShaderLike adds the shader to the favorites of the current user, provided they can see it
+func (s *Service) ShaderLike(ctx context.Context, shaderId string) error {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.repo.ShaderGetVisibleByIdAndLoggedInUser(ctx, shaderId, userInfo.Id); err != nil {
+		return err
+	}
+
+	return s.repo.ShaderLike(ctx, shaderId, userInfo.Id)
+}
+
+// ShaderUnlike removes the shader from the favorites of the current user. Unlike liking, this is allowed for shaders
+// that are no longer visible to them.
+func (s *Service) ShaderUnlike(ctx context.Context, shaderId string) error {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.ShaderUnlike(ctx, shaderId, userInfo.Id)
+}
+
+func (s *Service) ShaderInfoListLikesCurrentUser(ctx context.Context, page models.PageRequest) (models.Page[models.ShaderInfo], error) {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderRead)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	if page.Sort == "" {
+		page.Sort = models.ShaderSortLiked
+	}
+	if page.Sort != models.ShaderSortLiked {
+		return models.Page[models.ShaderInfo]{}, infra.NewValidationError("Parameter 'sort' must be 'liked'!")
+	}
+	page, err = validatePageLimit(page)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	shaders, err := s.repo.ShaderInfoListLikedByUser(ctx, userInfo.Id, page)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	for idx := range shaders.Items {
+		shaders.Items[idx].LikedByMe = true
+	}
+	return shaders, nil
+}
+
+// likedByCurrentUser returns which of the shaders the current user likes, nil for anonymous visitors
+func (s *Service) likedByCurrentUser(ctx context.Context, shaderIds []string) (map[string]bool, error) {
+	userInfo := service.ExtractUserInfoWithScope(ctx, service.ScopeShaderRead)
+	if userInfo == nil || len(shaderIds) == 0 {
+		return nil, nil
+	}
+
+	liked, err := s.repo.ShaderLikedByUser(ctx, userInfo.Id, shaderIds)
+	if err != nil {
+		return nil, err
+	}
+
+	likedSet := make(map[string]bool, len(liked))
+	for _, shaderId := range liked {
+		likedSet[shaderId] = true
+	}
+	return likedSet, nil
+}
+
+// withLikedByMe sets whether the current user likes each of the shaders
+func (s *Service) withLikedByMe(ctx context.Context, shaders []models.ShaderInfo) ([]models.ShaderInfo, error) {
+	shaderIds := make([]string, len(shaders))
+	for idx, shader := range shaders {
+		shaderIds[idx] = shader.Id
+	}
+
+	liked, err := s.likedByCurrentUser(ctx, shaderIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for idx := range shaders {
+		shaders[idx].LikedByMe = liked[shaders[idx].Id]
+	}
+	return shaders, nil
+}
+
+// pageWithLikedByMe is withLikedByMe for a page of a listing
+func (s *Service) pageWithLikedByMe(ctx context.Context, page models.Page[models.ShaderInfo]) (models.Page[models.ShaderInfo], error) {
+	items, err := s.withLikedByMe(ctx, page.Items)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	page.Items = items
+	return page, nil
+}
@@ -0,0 +1,8 @@
+package shader
+
+import "github.com/sdedovic/wgsltoy-server/src/go/metrics"
+
+var shaderCreatesTotal = metrics.NewCounterVec("wgsltoy_shader_creates_total",
+	"Shaders created, by whether they are new or forked.", "origin")
+var shaderUpdatesTotal = metrics.NewCounterVec("wgsltoy_shader_updates_total",
+	"Shader updates, by whether they were edited or reverted to an earlier revision.", "kind")
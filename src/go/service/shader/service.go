@@ -1,20 +1,27 @@
 package shader
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/sdedovic/wgsltoy-server/src/go/config"
 	"github.com/sdedovic/wgsltoy-server/src/go/db"
 	"github.com/sdedovic/wgsltoy-server/src/go/infra"
 	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/sdedovic/wgsltoy-server/src/go/patch"
 	"github.com/sdedovic/wgsltoy-server/src/go/service"
+	"github.com/sdedovic/wgsltoy-server/src/go/wgsl"
 	"regexp"
+	"slices"
 	"strings"
-	"time"
 	"unicode/utf8"
 )
 
 type Service struct {
-	repo db.IRepository `di.inject:"Repository"`
+	repo   db.IRepository `di.inject:"Repository"`
+	config *config.Config `di.inject:"Config"`
 }
 
 var tagRegex = regexp.MustCompile(`^[a-z][a-z0-9]+$`)
@@ -25,11 +32,21 @@ const VisibilityPrivate = "private"
 const VisibilityUnlisted = "unlisted"
 const VisibilityPublic = "public"
 
-func validateShaderName(name string) error {
+// StrictContextKey marks requests which opted in to having shader content parsed as WGSL before it is stored
+const StrictContextKey = "strict"
+
+// IfMatchContextKey carries the shader versions a conditional update requires the shader to be at, nil when the update
+// is unconditional
+const IfMatchContextKey = "ifMatch"
+
+const defaultPageLimit = 50
+const maxPageLimit = 100
+
+func validateShaderName(name string, maxLength int) error {
 	if name == "" {
 		return infra.NewValidationError("Field 'name' may not be empty!")
 	}
-	if utf8.RuneCountInString(name) > 160 {
+	if utf8.RuneCountInString(name) > maxLength {
 		return infra.NewValidationError("Field 'name' is too long!")
 	}
 	if !displayRegex.MatchString(name) {
@@ -48,8 +65,8 @@ func validateShaderVisibility(visibility string) error {
 	return nil
 }
 
-func validateShaderDescription(description string) error {
-	if utf8.RuneCountInString(description) > 480 {
+func validateShaderDescription(description string, maxLength int) error {
+	if utf8.RuneCountInString(description) > maxLength {
 		return infra.NewValidationError("Field 'description' is too long!")
 	}
 	if description != "" && !displayMultilineRegex.MatchString(description) {
@@ -58,8 +75,8 @@ func validateShaderDescription(description string) error {
 	return nil
 }
 
-func validateShaderContent(content string) error {
-	if utf8.RuneCountInString(content) > 5250 {
+func validateShaderContent(content string, maxLength int) error {
+	if utf8.RuneCountInString(content) > maxLength {
 		return infra.NewValidationError("Field 'content' is too long!")
 	}
 	if content != "" && !displayMultilineRegex.MatchString(content) {
@@ -68,6 +85,14 @@ func validateShaderContent(content string) error {
 	return nil
 }
 
+// validateShaderSource rejects content which is not syntactically valid WGSL, when the request opted in to it
+func validateShaderSource(ctx context.Context, content string) error {
+	if !ExtractStrictFromContext(ctx) {
+		return nil
+	}
+	return wgsl.Validate(content)
+}
+
 func validateShaderTags(tags []string) error {
 	for idx, tag := range tags {
 		if tag == "" {
@@ -86,13 +111,94 @@ func validateShaderTags(tags []string) error {
 	return nil
 }
 
+// validatePageRequest checks the requested slice of a shader listing, falling back to defaultSort and the default
+// page size when those are not supplied
+func validatePageRequest(page models.PageRequest, defaultSort string) (models.PageRequest, error) {
+	if page.Sort == "" {
+		page.Sort = defaultSort
+	}
+	if page.Sort != models.ShaderSortNewest && page.Sort != models.ShaderSortUpdated && page.Sort != models.ShaderSortName {
+		return models.PageRequest{}, infra.NewValidationError("Parameter 'sort' must be one of 'newest', 'updated' or 'name'!")
+	}
+
+	return validatePageLimit(page)
+}
+
+// validatePageLimit checks the requested page size, falling back to the default when it is not supplied
+func validatePageLimit(page models.PageRequest) (models.PageRequest, error) {
+	if page.Limit == 0 {
+		page.Limit = defaultPageLimit
+	}
+	if page.Limit < 0 || page.Limit > maxPageLimit {
+		return models.PageRequest{}, infra.NewValidationError(fmt.Sprintf("Parameter 'limit' must be between 1 and %d!", maxPageLimit))
+	}
+
+	return page, nil
+}
+
+func validateSearchQuery(query string, tags []string) error {
+	if query == "" && len(tags) == 0 {
+		return infra.NewValidationError("Either parameter 'q' or 'tag' is required!")
+	}
+	if utf8.RuneCountInString(query) > 200 {
+		return infra.NewValidationError("Parameter 'q' is too long!")
+	}
+	if query != "" && !displayRegex.MatchString(query) {
+		return infra.NewValidationError("Parameter 'q' contains invalid characters!")
+	}
+	if len(tags) > 5 {
+		return infra.NewValidationError("Parameter 'tag' may be supplied at most 5 times!")
+	}
+	for _, tag := range tags {
+		if !tagRegex.MatchString(tag) {
+			return infra.NewValidationError(fmt.Sprintf("Parameter 'tag' value '%s' is not a valid tag!", tag))
+		}
+	}
+	return nil
+}
+
+func ExtractStrictFromContext(ctx context.Context) bool {
+	strict, _ := ctx.Value(StrictContextKey).(bool)
+	return strict
+}
+
+func InsertStrictIntoContext(ctx context.Context, strict bool) context.Context {
+	return context.WithValue(ctx, StrictContextKey, strict)
+}
+
+func ExtractIfMatchFromContext(ctx context.Context) []int {
+	versions, _ := ctx.Value(IfMatchContextKey).([]int)
+	return versions
+}
+
+func InsertIfMatchIntoContext(ctx context.Context, versions []int) context.Context {
+	return context.WithValue(ctx, IfMatchContextKey, versions)
+}
+
+// checkMayPublish rejects making a shader public for users who have not verified their email address, when that is
+// required by the configuration
+func (s *Service) checkMayPublish(ctx context.Context, userId string, visibility string) error {
+	if visibility != VisibilityPublic || !s.config.Shader.RequireVerifiedEmailToPublish {
+		return nil
+	}
+
+	user, err := s.repo.UserGetById(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerification != models.EmailVerificationCompleted {
+		return infra.EmailNotVerifiedError
+	}
+	return nil
+}
+
 func (s *Service) ShaderCreate(ctx context.Context, shader models.ShaderCreate) (string, error) {
-	userInfo := service.ExtractUserInfoFromContext(ctx)
-	if userInfo == nil {
-		return "", infra.UnauthorizedError
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return "", err
 	}
 
-	if err := validateShaderName(shader.Name); err != nil {
+	if err := validateShaderName(shader.Name, s.config.Limits.ShaderNameLength); err != nil {
 		return "", err
 	}
 
@@ -100,11 +206,15 @@ func (s *Service) ShaderCreate(ctx context.Context, shader models.ShaderCreate)
 		return "", err
 	}
 
-	if err := validateShaderDescription(shader.Description); err != nil {
+	if err := validateShaderDescription(shader.Description, s.config.Limits.ShaderDescriptionLength); err != nil {
+		return "", err
+	}
+
+	if err := validateShaderContent(shader.Content, s.config.Limits.ShaderContentLength); err != nil {
 		return "", err
 	}
 
-	if err := validateShaderContent(shader.Content); err != nil {
+	if err := validateShaderSource(ctx, shader.Content); err != nil {
 		return "", err
 	}
 
@@ -112,79 +222,482 @@ func (s *Service) ShaderCreate(ctx context.Context, shader models.ShaderCreate)
 		return "", err
 	}
 
-	storedShader, err := s.repo.ShaderCreate(shader.Name, shader.Visibility, shader.Description, shader.Tags, shader.Content, userInfo.Id)
+	if err := s.checkMayPublish(ctx, userInfo.Id, shader.Visibility); err != nil {
+		return "", err
+	}
+
+	storedShader, err := s.repo.ShaderCreate(ctx, shader.Name, shader.Visibility, shader.Description, shader.Tags, shader.Content, userInfo.Id)
 	if err != nil {
 		return "", err
 	}
+	shaderCreatesTotal.Inc("new")
 
 	return storedShader.Id, nil
 }
 
-func (s *Service) ShaderUpdate(ctx context.Context, shaderId string, shader models.ShaderPartialUpdate) (models.Shader, error) {
-	userInfo := service.ExtractUserInfoFromContext(ctx)
-	if userInfo == nil {
-		return models.Shader{}, infra.UnauthorizedError
+// ShaderUpdate replaces the editable state of a shader as a whole
+func (s *Service) ShaderUpdate(ctx context.Context, shaderId string, shader models.ShaderUpdate) (models.Shader, error) {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return models.Shader{}, err
 	}
 
-	var query strings.Builder
-	args := make([]any, 0, 8)
+	return s.shaderReplace(ctx, userInfo.Id, shaderId, func(models.Shader) (models.ShaderUpdate, error) {
+		return shader, nil
+	})
+}
 
-	query.WriteString("UPDATE shaders SET updated_at = $1 ")
-	args = append(args, time.Now())
+// ShaderPatch applies shaderPatch to the editable state of a shader, as it would be replaced by ShaderUpdate
+func (s *Service) ShaderPatch(ctx context.Context, shaderId string, shaderPatch patch.Patch) (models.Shader, error) {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return models.Shader{}, err
+	}
+
+	return s.shaderReplace(ctx, userInfo.Id, shaderId, func(current models.Shader) (models.ShaderUpdate, error) {
+		tags := current.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		document, err := json.Marshal(models.ShaderUpdate{
+			Name:        &current.Name,
+			Visibility:  &current.Visibility,
+			Description: &current.Description,
+			Tags:        &tags,
+			Content:     &current.Content,
+		})
+		if err != nil {
+			return models.ShaderUpdate{}, fmt.Errorf("failed serializing shader caused by: %w", err)
+		}
+
+		patched, err := shaderPatch.Apply(document)
+		if err != nil {
+			return models.ShaderUpdate{}, err
+		}
 
-	if shader.Name != nil {
-		if err := validateShaderName(*shader.Name); err != nil {
+		var shader models.ShaderUpdate
+		decoder := json.NewDecoder(bytes.NewReader(patched))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&shader)
+		if err != nil {
+			return models.ShaderUpdate{}, infra.NewJsonParsingError(err)
+		}
+
+		// removing the optional fields clears them, such as setting them to null with a merge patch
+		if shader.Description == nil {
+			shader.Description = new(string)
+		}
+		if shader.Tags == nil {
+			shader.Tags = &[]string{}
+		}
+		return shader, nil
+	})
+}
+
+// maxReplaceAttempts bounds how often an unconditional update is retried while the shader keeps changing concurrently
+const maxReplaceAttempts = 3
+
+// shaderReplace stores the state replace derives from the current state of a shader. Only a conditional update fails
+// with infra.PreconditionFailedError when the shader changed since the client read it, an unconditional one is derived
+// again from the changed shader and fails with infra.ConflictError only when it keeps changing.
+func (s *Service) shaderReplace(ctx context.Context, userId string, shaderId string, replace func(current models.Shader) (models.ShaderUpdate, error)) (models.Shader, error) {
+	conditional := ExtractIfMatchFromContext(ctx) != nil
+
+	for attempt := 1; ; attempt++ {
+		current, err := s.repo.ShaderGetVisibleByIdAndLoggedInUser(ctx, shaderId, userId)
+		if err != nil {
 			return models.Shader{}, err
 		}
-	}
 
-	if shader.Visibility != nil {
-		if err := validateShaderVisibility(*shader.Visibility); err != nil {
+		shader, err := replace(current)
+		if err != nil {
 			return models.Shader{}, err
 		}
+
+		updated, err := s.shaderStore(ctx, userId, current, shader)
+		if conditional || !errors.Is(err, infra.PreconditionFailedError) {
+			return updated, err
+		}
+		if attempt == maxReplaceAttempts {
+			return models.Shader{}, infra.ConflictError
+		}
 	}
+}
 
-	if shader.Description != nil {
-		if err := validateShaderDescription(*shader.Description); err != nil {
-			return models.Shader{}, err
+// shaderStore validates and stores update as the new state of current. The update only applies while the shader is
+// still at the version current was read at, so concurrent changes are never silently overwritten.
+func (s *Service) shaderStore(ctx context.Context, userId string, current models.Shader, update models.ShaderUpdate) (models.Shader, error) {
+	// a replacement leaving out a field would silently empty it, such as the content for clients of partial updates
+	required := []struct {
+		field   string
+		missing bool
+	}{
+		{"name", update.Name == nil},
+		{"visibility", update.Visibility == nil},
+		{"description", update.Description == nil},
+		{"tags", update.Tags == nil},
+		{"content", update.Content == nil},
+	}
+	for _, r := range required {
+		if r.missing {
+			return models.Shader{}, infra.NewValidationError(fmt.Sprintf("Field '%s' is required!", r.field))
 		}
 	}
 
-	if shader.Content != nil {
-		if err := validateShaderContent(*shader.Content); err != nil {
+	// viewers may see the shader but not change it
+	if current.CreatedBy != userId {
+		role, err := s.repo.ShaderCollaboratorGetRole(ctx, current.Id, userId)
+		if err != nil && !errors.Is(err, infra.NotFoundError) {
 			return models.Shader{}, err
 		}
+		if role != models.CollaboratorRoleEditor {
+			return models.Shader{}, infra.ForbiddenError
+		}
+	}
+
+	// the replaced state is validated the same way as the state of a new shader
+	shader := models.ShaderCreate{
+		Name:        *update.Name,
+		Visibility:  *update.Visibility,
+		Description: *update.Description,
+		Tags:        *update.Tags,
+		Content:     *update.Content,
+	}
+
+	if err := validateShaderName(shader.Name, s.config.Limits.ShaderNameLength); err != nil {
+		return models.Shader{}, err
+	}
+
+	if err := validateShaderVisibility(shader.Visibility); err != nil {
+		return models.Shader{}, err
+	}
+
+	if err := validateShaderDescription(shader.Description, s.config.Limits.ShaderDescriptionLength); err != nil {
+		return models.Shader{}, err
+	}
+
+	if err := validateShaderContent(shader.Content, s.config.Limits.ShaderContentLength); err != nil {
+		return models.Shader{}, err
 	}
 
-	if shader.Tags != nil {
-		if err := validateShaderTags(*shader.Tags); err != nil {
+	if err := validateShaderSource(ctx, shader.Content); err != nil {
+		return models.Shader{}, err
+	}
+
+	if err := validateShaderTags(shader.Tags); err != nil {
+		return models.Shader{}, err
+	}
+
+	if versions := ExtractIfMatchFromContext(ctx); versions != nil && !slices.Contains(versions, current.Version) {
+		return models.Shader{}, infra.PreconditionFailedError
+	}
+
+	var visibility *string
+	if shader.Visibility != current.Visibility {
+		if err := s.checkMayPublish(ctx, userId, shader.Visibility); err != nil {
 			return models.Shader{}, err
 		}
+		// editors may change everything but who gets to see the shader
+		if current.CreatedBy != userId {
+			return models.Shader{}, infra.ForbiddenError
+		}
+		visibility = &shader.Visibility
+	}
+
+	// only changed content is recorded as a new revision
+	var content *string
+	if shader.Content != current.Content {
+		content = &shader.Content
 	}
 
-	updatedShader, err := s.repo.ShaderPartialUpdate(shaderId, userInfo.Id, shader.Name, shader.Visibility, shader.Description, shader.Tags, shader.Content)
+	tags := shader.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	updatedShader, err := s.repo.ShaderPartialUpdate(ctx, current.Id, userId, []int{current.Version}, &shader.Name, visibility, &shader.Description, &tags, content)
 	if err != nil {
 		return models.Shader{}, err
 	}
+	shaderUpdatesTotal.Inc("edit")
 
-	return updatedShader, nil
+	return s.withViewerDetails(ctx, updatedShader)
 }
 
-func (s *Service) ShaderInfoListCurrentUser(ctx context.Context) ([]models.ShaderInfo, error) {
-	userInfo := service.ExtractUserInfoFromContext(ctx)
-	if userInfo == nil {
-		return nil, infra.UnauthorizedError
+func (s *Service) ShaderInfoListCurrentUser(ctx context.Context, page models.PageRequest) (models.Page[models.ShaderInfo], error) {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderRead)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
 	}
 
-	return s.repo.ShaderInfoListByCreatedBy(userInfo.Id)
+	page, err = validatePageRequest(page, models.ShaderSortUpdated)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	shaders, err := s.repo.ShaderInfoListByCreatedBy(ctx, userInfo.Id, page)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	return s.pageWithLikedByMe(ctx, shaders)
+}
+
+func (s *Service) ShaderSearch(ctx context.Context, query string, tags []string, page models.PageRequest) (models.Page[models.ShaderSearchResult], error) {
+	query = strings.TrimSpace(query)
+	if err := validateSearchQuery(query, tags); err != nil {
+		return models.Page[models.ShaderSearchResult]{}, err
+	}
+
+	page, err := validatePageLimit(page)
+	if err != nil {
+		return models.Page[models.ShaderSearchResult]{}, err
+	}
+
+	var currentUser string
+	if userInfo := service.ExtractUserInfoWithScope(ctx, service.ScopeShaderRead); userInfo != nil {
+		currentUser = userInfo.Id
+	}
+
+	results, err := s.repo.ShaderInfoSearch(ctx, query, tags, currentUser, page)
+	if err != nil {
+		return models.Page[models.ShaderSearchResult]{}, err
+	}
+
+	shaderIds := make([]string, len(results.Items))
+	for idx, result := range results.Items {
+		shaderIds[idx] = result.Id
+	}
+	liked, err := s.likedByCurrentUser(ctx, shaderIds)
+	if err != nil {
+		return models.Page[models.ShaderSearchResult]{}, err
+	}
+	for idx := range results.Items {
+		results.Items[idx].LikedByMe = liked[results.Items[idx].Id]
+	}
+
+	return results, nil
+}
+
+func (s *Service) ShaderInfoListPublic(ctx context.Context, page models.PageRequest) (models.Page[models.ShaderInfo], error) {
+	page, err := validatePageRequest(page, models.ShaderSortNewest)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	shaders, err := s.repo.ShaderInfoListPublic(ctx, page)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	return s.pageWithLikedByMe(ctx, shaders)
+}
+
+// ShaderInfoListByUsername lists the public shaders of the user, or all of their shaders when the caller is that user
+func (s *Service) ShaderInfoListByUsername(ctx context.Context, username string, page models.PageRequest) (models.Page[models.ShaderInfo], error) {
+	page, err := validatePageRequest(page, models.ShaderSortNewest)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	profile, err := s.repo.UserGetProfileByUsername(ctx, username)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	var shaders models.Page[models.ShaderInfo]
+	if userInfo := service.ExtractUserInfoWithScope(ctx, service.ScopeShaderRead); userInfo != nil && userInfo.Id == profile.Id {
+		shaders, err = s.repo.ShaderInfoListByCreatedBy(ctx, profile.Id, page)
+	} else {
+		shaders, err = s.repo.ShaderInfoListPublicByCreatedBy(ctx, profile.Id, page)
+	}
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	return s.pageWithLikedByMe(ctx, shaders)
 }
 
 func (s *Service) ShaderGet(ctx context.Context, shaderId string) (models.Shader, error) {
-	userInfo := service.ExtractUserInfoFromContext(ctx)
+	shader, err := s.shaderGetVisible(ctx, shaderId)
+	if errors.Is(err, infra.NotFoundError) {
+		shader, err = s.shaderGetShared(ctx, shaderId)
+	}
+	if err != nil {
+		return models.Shader{}, err
+	}
+
+	return s.withViewerDetails(ctx, shader)
+}
+
+// shaderGetVisible looks up a shader if it is visible to the current user, without resolving attribution
+func (s *Service) shaderGetVisible(ctx context.Context, shaderId string) (models.Shader, error) {
+	userInfo := service.ExtractUserInfoWithScope(ctx, service.ScopeShaderRead)
 
 	if userInfo == nil {
-		return s.repo.ShaderGetPubliclyVisibleById(shaderId)
+		return s.repo.ShaderGetPubliclyVisibleById(ctx, shaderId)
 	} else {
-		return s.repo.ShaderGetVisibleByIdAndLoggedInUser(shaderId, userInfo.Id)
+		return s.repo.ShaderGetVisibleByIdAndLoggedInUser(ctx, shaderId, userInfo.Id)
+	}
+}
+
+// withViewerDetails resolves attribution and whether the current user likes the shader
+func (s *Service) withViewerDetails(ctx context.Context, shader models.Shader) (models.Shader, error) {
+	liked, err := s.likedByCurrentUser(ctx, []string{shader.Id})
+	if err != nil {
+		return models.Shader{}, err
+	}
+	shader.LikedByMe = liked[shader.Id]
+
+	return s.withAttribution(ctx, shader)
+}
+
+// withAttribution resolves the shader a fork was created from, as far as it is still visible to the current user
+func (s *Service) withAttribution(ctx context.Context, shader models.Shader) (models.Shader, error) {
+	if shader.ForkedFromId == nil {
+		return shader, nil
+	}
+
+	parent, err := s.shaderGetVisible(ctx, *shader.ForkedFromId)
+	if err != nil {
+		if errors.Is(err, infra.NotFoundError) {
+			shader.ForkedFrom = &models.ShaderAttribution{Available: false}
+			return shader, nil
+		}
+		return models.Shader{}, err
+	}
+
+	shader.ForkedFrom = &models.ShaderAttribution{
+		Available: true,
+		Id:        parent.Id,
+		Location:  fmt.Sprintf("/shader/%s", parent.Id),
+		CreatedBy: parent.CreatedBy,
+		Name:      parent.Name,
+	}
+	return shader, nil
+}
+
+func (s *Service) ShaderDelete(ctx context.Context, shaderId string) error {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.ShaderSoftDelete(ctx, shaderId, userInfo.Id)
+}
+
+func (s *Service) ShaderRestore(ctx context.Context, shaderId string) (models.Shader, error) {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return models.Shader{}, err
 	}
+
+	restoredShader, err := s.repo.ShaderRestore(ctx, shaderId, userInfo.Id)
+	if err != nil {
+		return models.Shader{}, err
+	}
+
+	return s.withViewerDetails(ctx, restoredShader)
+}
+
+func (s *Service) ShaderInfoListTrashCurrentUser(ctx context.Context, page models.PageRequest) (models.Page[models.ShaderInfo], error) {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderRead)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	if page.Sort == "" {
+		page.Sort = models.ShaderSortDeleted
+	}
+	if page.Sort != models.ShaderSortDeleted {
+		return models.Page[models.ShaderInfo]{}, infra.NewValidationError("Parameter 'sort' must be 'deleted'!")
+	}
+	page, err = validatePageLimit(page)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	shaders, err := s.repo.ShaderInfoListDeletedByCreatedBy(ctx, userInfo.Id, page)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	return s.pageWithLikedByMe(ctx, shaders)
+}
+
+func (s *Service) ShaderRevisionList(ctx context.Context, shaderId string) ([]models.ShaderRevisionInfo, error) {
+	// the history is visible to anyone who can see the shader itself
+	if _, err := s.shaderGetVisible(ctx, shaderId); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ShaderRevisionInfoList(ctx, shaderId)
+}
+
+func (s *Service) ShaderRevisionGet(ctx context.Context, shaderId string, revision int) (models.ShaderRevision, error) {
+	if _, err := s.shaderGetVisible(ctx, shaderId); err != nil {
+		return models.ShaderRevision{}, err
+	}
+
+	return s.repo.ShaderRevisionGet(ctx, shaderId, revision)
+}
+
+func (s *Service) ShaderRevert(ctx context.Context, shaderId string, revision int) (models.Shader, error) {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return models.Shader{}, err
+	}
+
+	revertedShader, err := s.repo.ShaderRevert(ctx, shaderId, userInfo.Id, revision)
+	if err != nil {
+		return models.Shader{}, err
+	}
+	shaderUpdatesTotal.Inc("revert")
+
+	return s.withViewerDetails(ctx, revertedShader)
+}
+
+// ShaderFork copies a shader the current user may open into a new private shader of theirs. Anyone a share link was
+// handed to may fork the shader, as they could copy its content just the same.
+func (s *Service) ShaderFork(ctx context.Context, shaderId string) (string, error) {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return "", err
+	}
+
+	parent, err := s.ShaderGet(ctx, shaderId)
+	if err != nil {
+		return "", err
+	}
+
+	fork, err := s.repo.ShaderCreateFork(ctx, parent, userInfo.Id)
+	if err != nil {
+		return "", err
+	}
+	shaderCreatesTotal.Inc("fork")
+
+	return fork.Id, nil
+}
+
+func (s *Service) ShaderInfoListForks(ctx context.Context, shaderId string, page models.PageRequest) (models.Page[models.ShaderInfo], error) {
+	page, err := validatePageRequest(page, models.ShaderSortNewest)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	if _, err := s.shaderGetVisible(ctx, shaderId); err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	var currentUser string
+	if userInfo := service.ExtractUserInfoWithScope(ctx, service.ScopeShaderRead); userInfo != nil {
+		currentUser = userInfo.Id
+	}
+
+	shaders, err := s.repo.ShaderInfoListForks(ctx, shaderId, currentUser, page)
+	if err != nil {
+		return models.Page[models.ShaderInfo]{}, err
+	}
+
+	return s.pageWithLikedByMe(ctx, shaders)
 }
@@ -0,0 +1,771 @@
+package shader
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/sdedovic/wgsltoy-server/src/go/config"
+	"github.com/sdedovic/wgsltoy-server/src/go/db"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/sdedovic/wgsltoy-server/src/go/patch"
+	"github.com/sdedovic/wgsltoy-server/src/go/service"
+	"github.com/sdedovic/wgsltoy-server/src/go/wgsl"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type repoMock struct {
+	db.IRepository
+
+	shaderSoftDelete             func(shaderId string, createdBy string) error
+	shaderGetPubliclyVisibleById func(shaderId string) (models.Shader, error)
+	shaderRevisionInfoList       func(shaderId string) ([]models.ShaderRevisionInfo, error)
+	shaderCreate                 func(name string, visibility string, description string, tags []string, content string, createdBy string) (models.Shader, error)
+	userGetProfileByUsername     func(username string) (models.UserPublicProfile, error)
+	shaderInfoListByCreatedBy    func(createdBy string, page models.PageRequest) (models.Page[models.ShaderInfo], error)
+	shaderInfoListPublicByUser   func(createdBy string, page models.PageRequest) (models.Page[models.ShaderInfo], error)
+	shaderInfoListPublic         func(page models.PageRequest) (models.Page[models.ShaderInfo], error)
+	shaderGetVisibleById         func(shaderId string, currentUser string) (models.Shader, error)
+	shaderLike                   func(shaderId string, userId string) error
+	shaderLikedByUser            func(userId string, shaderIds []string) ([]string, error)
+	commentCreate                func(shaderId string, parentId *string, createdBy string, body string) (models.Comment, error)
+	commentGetById               func(shaderId string, commentId string) (models.Comment, error)
+	commentSetHidden             func(shaderId string, commentId string, hidden bool) (models.Comment, error)
+	collectionItemAdd            func(collectionId string, createdBy string, shaderId string, maxItems int) error
+	shaderCollaboratorRemove     func(shaderId string, userId string) error
+	shareLinkUse                 func(tokenHash string) (models.ShareLink, error)
+	shaderGetById                func(shaderId string) (models.Shader, error)
+	shaderPartialUpdate          func(shaderId string, updatedBy string, expectedVersions []int, name *string, visibility *string, description *string, tags *[]string, content *string) (models.Shader, error)
+	shaderCreateFork             func(parent models.Shader, createdBy string) (models.Shader, error)
+	shaderCollaboratorGetRole    func(shaderId string, userId string) (string, error)
+}
+
+func stringPointer(value string) *string {
+	return &value
+}
+
+// completeUpdate is a replacement of every field of a shader, as PUT requires
+func completeUpdate(name string, visibility string) models.ShaderUpdate {
+	return models.ShaderUpdate{
+		Name:        &name,
+		Visibility:  &visibility,
+		Description: stringPointer(""),
+		Tags:        &[]string{},
+		Content:     stringPointer(""),
+	}
+}
+
+func (m repoMock) ShaderSoftDelete(_ context.Context, shaderId string, createdBy string) error {
+	return m.shaderSoftDelete(shaderId, createdBy)
+}
+
+func (m repoMock) ShaderGetPubliclyVisibleById(_ context.Context, shaderId string) (models.Shader, error) {
+	return m.shaderGetPubliclyVisibleById(shaderId)
+}
+
+func (m repoMock) ShaderRevisionInfoList(_ context.Context, shaderId string) ([]models.ShaderRevisionInfo, error) {
+	return m.shaderRevisionInfoList(shaderId)
+}
+
+func (m repoMock) ShaderCreate(_ context.Context, name string, visibility string, description string, tags []string, content string, createdBy string) (models.Shader, error) {
+	return m.shaderCreate(name, visibility, description, tags, content, createdBy)
+}
+
+func (m repoMock) UserGetProfileByUsername(_ context.Context, username string) (models.UserPublicProfile, error) {
+	return m.userGetProfileByUsername(username)
+}
+
+func (m repoMock) ShaderInfoListByCreatedBy(_ context.Context, createdBy string, page models.PageRequest) (models.Page[models.ShaderInfo], error) {
+	return m.shaderInfoListByCreatedBy(createdBy, page)
+}
+
+func (m repoMock) ShaderInfoListPublicByCreatedBy(_ context.Context, createdBy string, page models.PageRequest) (models.Page[models.ShaderInfo], error) {
+	return m.shaderInfoListPublicByUser(createdBy, page)
+}
+
+func (m repoMock) ShaderInfoListPublic(_ context.Context, page models.PageRequest) (models.Page[models.ShaderInfo], error) {
+	return m.shaderInfoListPublic(page)
+}
+
+func (m repoMock) ShaderGetVisibleByIdAndLoggedInUser(_ context.Context, shaderId string, currentUser string) (models.Shader, error) {
+	return m.shaderGetVisibleById(shaderId, currentUser)
+}
+
+func (m repoMock) ShaderLike(_ context.Context, shaderId string, userId string) error {
+	return m.shaderLike(shaderId, userId)
+}
+
+func (m repoMock) ShaderLikedByUser(_ context.Context, userId string, shaderIds []string) ([]string, error) {
+	return m.shaderLikedByUser(userId, shaderIds)
+}
+
+func (m repoMock) CommentCreate(_ context.Context, shaderId string, parentId *string, createdBy string, body string) (models.Comment, error) {
+	return m.commentCreate(shaderId, parentId, createdBy, body)
+}
+
+func (m repoMock) CommentGetById(_ context.Context, shaderId string, commentId string) (models.Comment, error) {
+	return m.commentGetById(shaderId, commentId)
+}
+
+func (m repoMock) CommentSetHidden(_ context.Context, shaderId string, commentId string, hidden bool) (models.Comment, error) {
+	return m.commentSetHidden(shaderId, commentId, hidden)
+}
+
+func (m repoMock) CollectionItemAdd(_ context.Context, collectionId string, createdBy string, shaderId string, maxItems int) error {
+	return m.collectionItemAdd(collectionId, createdBy, shaderId, maxItems)
+}
+
+func (m repoMock) ShaderCollaboratorRemove(_ context.Context, shaderId string, userId string) error {
+	return m.shaderCollaboratorRemove(shaderId, userId)
+}
+
+func (m repoMock) ShareLinkUse(_ context.Context, tokenHash string) (models.ShareLink, error) {
+	return m.shareLinkUse(tokenHash)
+}
+
+func (m repoMock) ShaderGetById(_ context.Context, shaderId string) (models.Shader, error) {
+	return m.shaderGetById(shaderId)
+}
+
+func (m repoMock) ShaderPartialUpdate(_ context.Context, shaderId string, updatedBy string, expectedVersions []int, name *string, visibility *string, description *string, tags *[]string, content *string) (models.Shader, error) {
+	return m.shaderPartialUpdate(shaderId, updatedBy, expectedVersions, name, visibility, description, tags, content)
+}
+
+func (m repoMock) ShaderCreateFork(_ context.Context, parent models.Shader, createdBy string) (models.Shader, error) {
+	return m.shaderCreateFork(parent, createdBy)
+}
+
+func (m repoMock) ShaderCollaboratorGetRole(_ context.Context, shaderId string, userId string) (string, error) {
+	return m.shaderCollaboratorGetRole(shaderId, userId)
+}
+
+func TestShaderDelete_RequiresLogin(t *testing.T) {
+	mock := repoMock{
+		shaderSoftDelete: func(_, _ string) error {
+			t.Fatal("repository must not be called")
+			return nil
+		},
+	}
+	s := &Service{repo: mock, config: config.Default()}
+
+	err := s.ShaderDelete(context.Background(), "shader")
+	assert.ErrorIs(t, err, infra.UnauthorizedError)
+}
+
+func TestShaderDelete_ScopedToCurrentUser(t *testing.T) {
+	var deletedBy string
+	mock := repoMock{
+		shaderSoftDelete: func(_ string, createdBy string) error {
+			deletedBy = createdBy
+			return nil
+		},
+	}
+	s := &Service{repo: mock, config: config.Default()}
+
+	ctx := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "owner"})
+	err := s.ShaderDelete(ctx, "shader")
+	assert.NoError(t, err)
+	assert.Equal(t, "owner", deletedBy)
+}
+
+func TestShaderRevisionList_HiddenShader(t *testing.T) {
+	mock := repoMock{
+		shaderGetPubliclyVisibleById: func(_ string) (models.Shader, error) {
+			return models.Shader{}, infra.NotFoundError
+		},
+		shaderRevisionInfoList: func(_ string) ([]models.ShaderRevisionInfo, error) {
+			t.Fatal("revisions of a hidden shader must not be queried")
+			return nil, nil
+		},
+	}
+	s := &Service{repo: mock, config: config.Default()}
+
+	_, err := s.ShaderRevisionList(context.Background(), "shader")
+	assert.ErrorIs(t, err, infra.NotFoundError)
+}
+
+func TestShaderGet_ForkAttribution(t *testing.T) {
+	shaders := map[string]models.Shader{
+		"parent":         {Id: "parent", Name: "Original", CreatedBy: "author"},
+		"fork":           {Id: "fork", ForkedFromId: stringPointer("parent")},
+		"orphaned-fork":  {Id: "orphaned-fork", ForkedFromId: stringPointer("deleted")},
+		"unrelated-work": {Id: "unrelated-work"},
+	}
+	mock := repoMock{
+		shaderGetPubliclyVisibleById: func(shaderId string) (models.Shader, error) {
+			shader, ok := shaders[shaderId]
+			if !ok {
+				return models.Shader{}, infra.NotFoundError
+			}
+			return shader, nil
+		},
+	}
+	s := &Service{repo: mock, config: config.Default()}
+
+	fork, err := s.ShaderGet(context.Background(), "fork")
+	assert.NoError(t, err)
+	assert.Equal(t, &models.ShaderAttribution{
+		Available: true,
+		Id:        "parent",
+		Location:  "/shader/parent",
+		CreatedBy: "author",
+		Name:      "Original",
+	}, fork.ForkedFrom)
+
+	orphanedFork, err := s.ShaderGet(context.Background(), "orphaned-fork")
+	assert.NoError(t, err)
+	assert.Equal(t, &models.ShaderAttribution{Available: false}, orphanedFork.ForkedFrom)
+
+	unrelatedWork, err := s.ShaderGet(context.Background(), "unrelated-work")
+	assert.NoError(t, err)
+	assert.Nil(t, unrelatedWork.ForkedFrom)
+}
+
+func TestShaderSearch_FailValidation(t *testing.T) {
+	tests := []struct {
+		name string
+
+		query string
+		tags  []string
+	}{
+		{"query or tag required", "", nil},
+		{"blank query", "   ", nil},
+		{"query invalid characters", "noise\x00", nil},
+		{"tag invalid", "", []string{"Not A Tag"}},
+		{"too many tags", "", []string{"abc", "def", "ghi", "jkl", "mno", "pqr"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{repo: repoMock{}, config: config.Default()}
+
+			_, err := s.ShaderSearch(context.Background(), tt.query, tt.tags, models.PageRequest{})
+			assert.IsType(t, infra.ValidationError{}, err)
+		})
+	}
+}
+
+func TestShaderCreate_StrictValidation(t *testing.T) {
+	mock := repoMock{
+		shaderCreate: func(_, _, _ string, _ []string, _ string, _ string) (models.Shader, error) {
+			return models.Shader{Id: "created"}, nil
+		},
+	}
+	s := &Service{repo: mock, config: config.Default()}
+
+	ctx := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "owner"})
+	create := models.ShaderCreate{Name: "broken", Visibility: VisibilityPrivate, Content: "fn main() {"}
+
+	shaderId, err := s.ShaderCreate(ctx, create)
+	assert.NoError(t, err, "content is not parsed unless requested")
+	assert.Equal(t, "created", shaderId)
+
+	_, err = s.ShaderCreate(InsertStrictIntoContext(ctx, true), create)
+	var wgslError wgsl.Error
+	if assert.ErrorAs(t, err, &wgslError) {
+		assert.Equal(t, "expected '}', found end of file", wgslError.Diagnostics[0].Message)
+	}
+}
+
+func TestShaderDelete_RequiresWriteScope(t *testing.T) {
+	mock := repoMock{
+		shaderSoftDelete: func(_, _ string) error {
+			t.Fatal("repository must not be called")
+			return nil
+		},
+	}
+	s := &Service{repo: mock, config: config.Default()}
+
+	ctx := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "owner", Scopes: []string{service.ScopeShaderRead}})
+	err := s.ShaderDelete(ctx, "shader")
+	assert.ErrorIs(t, err, infra.InsufficientScopeError)
+}
+
+func TestShaderInfoListByUsername_OnlyOwnerSeesAll(t *testing.T) {
+	var listed string
+	mock := repoMock{
+		userGetProfileByUsername: func(username string) (models.UserPublicProfile, error) {
+			if username != "owner" {
+				return models.UserPublicProfile{}, infra.NotFoundError
+			}
+			return models.UserPublicProfile{Id: "owner-id", Username: username}, nil
+		},
+		shaderInfoListByCreatedBy: func(_ string, _ models.PageRequest) (models.Page[models.ShaderInfo], error) {
+			listed = "all"
+			return models.Page[models.ShaderInfo]{}, nil
+		},
+		shaderInfoListPublicByUser: func(_ string, _ models.PageRequest) (models.Page[models.ShaderInfo], error) {
+			listed = "public"
+			return models.Page[models.ShaderInfo]{}, nil
+		},
+	}
+	s := &Service{repo: mock, config: config.Default()}
+
+	_, err := s.ShaderInfoListByUsername(context.Background(), "missing", models.PageRequest{})
+	assert.ErrorIs(t, err, infra.NotFoundError)
+
+	_, err = s.ShaderInfoListByUsername(context.Background(), "owner", models.PageRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "public", listed)
+
+	other := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "other-id"})
+	_, err = s.ShaderInfoListByUsername(other, "owner", models.PageRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "public", listed)
+
+	owner := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "owner-id"})
+	_, err = s.ShaderInfoListByUsername(owner, "owner", models.PageRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "all", listed)
+}
+
+func TestShaderLike_RespectsVisibility(t *testing.T) {
+	var liked []string
+	mock := repoMock{
+		shaderGetVisibleById: func(shaderId string, _ string) (models.Shader, error) {
+			if shaderId == "private" {
+				return models.Shader{}, infra.NotFoundError
+			}
+			return models.Shader{Id: shaderId}, nil
+		},
+		shaderLike: func(shaderId string, _ string) error {
+			liked = append(liked, shaderId)
+			return nil
+		},
+	}
+	s := &Service{repo: mock, config: config.Default()}
+
+	err := s.ShaderLike(context.Background(), "public")
+	assert.ErrorIs(t, err, infra.UnauthorizedError)
+
+	ctx := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "user"})
+	err = s.ShaderLike(ctx, "private")
+	assert.ErrorIs(t, err, infra.NotFoundError)
+
+	err = s.ShaderLike(ctx, "public")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"public"}, liked)
+}
+
+func TestShaderInfoListPublic_LikedByMe(t *testing.T) {
+	mock := repoMock{
+		shaderInfoListPublic: func(_ models.PageRequest) (models.Page[models.ShaderInfo], error) {
+			return models.Page[models.ShaderInfo]{Items: []models.ShaderInfo{{Id: "a"}, {Id: "b"}}}, nil
+		},
+		shaderLikedByUser: func(userId string, shaderIds []string) ([]string, error) {
+			assert.Equal(t, "user", userId)
+			assert.Equal(t, []string{"a", "b"}, shaderIds)
+			return []string{"b"}, nil
+		},
+	}
+	s := &Service{repo: mock, config: config.Default()}
+
+	shaders, err := s.ShaderInfoListPublic(context.Background(), models.PageRequest{})
+	assert.NoError(t, err)
+	assert.False(t, shaders.Items[0].LikedByMe)
+	assert.False(t, shaders.Items[1].LikedByMe)
+
+	ctx := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "user"})
+	shaders, err = s.ShaderInfoListPublic(ctx, models.PageRequest{})
+	assert.NoError(t, err)
+	assert.False(t, shaders.Items[0].LikedByMe)
+	assert.True(t, shaders.Items[1].LikedByMe)
+}
+
+func TestCommentCreate(t *testing.T) {
+	now := time.Now()
+	mock := repoMock{
+		shaderGetVisibleById: func(shaderId string, _ string) (models.Shader, error) {
+			if shaderId == "private" {
+				return models.Shader{Id: shaderId, Visibility: VisibilityPrivate, CreatedBy: "owner"}, nil
+			}
+			return models.Shader{Id: shaderId, Visibility: VisibilityPublic, CreatedBy: "owner"}, nil
+		},
+		commentGetById: func(_ string, commentId string) (models.Comment, error) {
+			switch commentId {
+			case "deleted":
+				return models.Comment{Id: commentId, DeletedAt: &now}, nil
+			case "parent":
+				return models.Comment{Id: commentId}, nil
+			default:
+				return models.Comment{}, infra.NotFoundError
+			}
+		},
+		commentCreate: func(_ string, _ *string, _ string, _ string) (models.Comment, error) {
+			return models.Comment{Id: "comment"}, nil
+		},
+	}
+	s := &Service{repo: mock, config: config.Default()}
+	ctx := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "user"})
+
+	_, err := s.CommentCreate(context.Background(), "public", models.CommentCreate{Body: "nice"})
+	assert.ErrorIs(t, err, infra.UnauthorizedError)
+
+	_, err = s.CommentCreate(ctx, "public", models.CommentCreate{Body: "  "})
+	assert.IsType(t, infra.ValidationError{}, err)
+
+	_, err = s.CommentCreate(ctx, "public", models.CommentCreate{Body: "nice\x00"})
+	assert.IsType(t, infra.ValidationError{}, err)
+
+	_, err = s.CommentCreate(ctx, "private", models.CommentCreate{Body: "nice"})
+	assert.ErrorIs(t, err, infra.NotFoundError)
+
+	_, err = s.CommentCreate(ctx, "public", models.CommentCreate{Body: "nice", ParentId: stringPointer("missing")})
+	assert.IsType(t, infra.ValidationError{}, err)
+
+	_, err = s.CommentCreate(ctx, "public", models.CommentCreate{Body: "nice", ParentId: stringPointer("deleted")})
+	assert.IsType(t, infra.ValidationError{}, err)
+
+	commentId, err := s.CommentCreate(ctx, "public", models.CommentCreate{Body: "nice", ParentId: stringPointer("parent")})
+	assert.NoError(t, err)
+	assert.Equal(t, "comment", commentId)
+}
+
+func TestCommentSetHidden_OnlyShaderOwner(t *testing.T) {
+	mock := repoMock{
+		shaderGetVisibleById: func(shaderId string, _ string) (models.Shader, error) {
+			return models.Shader{Id: shaderId, Visibility: VisibilityPublic, CreatedBy: "owner"}, nil
+		},
+		commentSetHidden: func(shaderId string, commentId string, hidden bool) (models.Comment, error) {
+			now := time.Now()
+			return models.Comment{Id: commentId, ShaderId: shaderId, CreatedBy: "author", Body: "rude", HiddenAt: &now}, nil
+		},
+	}
+	s := &Service{repo: mock, config: config.Default()}
+
+	other := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "author"})
+	_, err := s.CommentSetHidden(other, "shader", "comment", true)
+	assert.ErrorIs(t, err, infra.ForbiddenError)
+
+	owner := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "owner"})
+	comment, err := s.CommentSetHidden(owner, "shader", "comment", true)
+	assert.NoError(t, err)
+	assert.True(t, comment.Hidden)
+	assert.Equal(t, "rude", comment.Body)
+}
+
+func TestRedactComment(t *testing.T) {
+	now := time.Now()
+	hidden := models.Comment{CreatedBy: "author", Body: "rude", HiddenAt: &now}
+	deleted := models.Comment{CreatedBy: "author", Body: "oops", DeletedAt: &now}
+
+	assert.Equal(t, "", redactComment(hidden, "", "owner").Body)
+	assert.Equal(t, "", redactComment(hidden, "other", "owner").Body)
+	assert.Equal(t, "rude", redactComment(hidden, "author", "owner").Body)
+	assert.Equal(t, "rude", redactComment(hidden, "owner", "owner").Body)
+	assert.Equal(t, "", redactComment(deleted, "author", "owner").Body)
+	assert.True(t, redactComment(deleted, "author", "owner").Deleted)
+}
+
+func TestCollectionItemAdd_RespectsVisibility(t *testing.T) {
+	var added []string
+	mock := repoMock{
+		shaderGetVisibleById: func(shaderId string, _ string) (models.Shader, error) {
+			if shaderId == "private" {
+				return models.Shader{}, infra.NotFoundError
+			}
+			return models.Shader{Id: shaderId}, nil
+		},
+		collectionItemAdd: func(_ string, createdBy string, shaderId string, maxItems int) error {
+			assert.Equal(t, "user", createdBy)
+			assert.Equal(t, config.Default().Limits.CollectionItems, maxItems)
+			added = append(added, shaderId)
+			return nil
+		},
+	}
+	s := &Service{repo: mock, config: config.Default()}
+	ctx := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "user"})
+
+	err := s.CollectionItemAdd(ctx, "collection", "")
+	assert.IsType(t, infra.ValidationError{}, err)
+
+	err = s.CollectionItemAdd(ctx, "collection", "private")
+	assert.ErrorIs(t, err, infra.NotFoundError)
+
+	err = s.CollectionItemAdd(ctx, "collection", "public")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"public"}, added)
+}
+
+// collaboratorRoles looks up the role of a collaborator as their user ID, which is named after it
+func collaboratorRoles(_ string, userId string) (string, error) {
+	if userId != models.CollaboratorRoleViewer && userId != models.CollaboratorRoleEditor {
+		return "", infra.NotFoundError
+	}
+	return userId, nil
+}
+
+func TestShaderUpdate_EditorMayNotChangeVisibility(t *testing.T) {
+	mock := repoMock{
+		shaderGetVisibleById: func(shaderId string, _ string) (models.Shader, error) {
+			return models.Shader{Id: shaderId, Visibility: VisibilityPrivate, CreatedBy: "owner"}, nil
+		},
+		shaderCollaboratorGetRole: collaboratorRoles,
+	}
+	s := &Service{repo: mock, config: config.Default()}
+	ctx := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "editor"})
+
+	_, err := s.ShaderUpdate(ctx, "shader", completeUpdate("shader", VisibilityPublic))
+	assert.ErrorIs(t, err, infra.ForbiddenError)
+}
+
+func TestShaderUpdate_ViewerMayNotEdit(t *testing.T) {
+	mock := repoMock{
+		shaderGetVisibleById: func(shaderId string, _ string) (models.Shader, error) {
+			return models.Shader{Id: shaderId, Name: "shader", Visibility: VisibilityPublic, CreatedBy: "owner"}, nil
+		},
+		shaderCollaboratorGetRole: collaboratorRoles,
+	}
+	s := &Service{repo: mock, config: config.Default()}
+
+	viewer := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "viewer"})
+	_, err := s.ShaderUpdate(viewer, "shader", completeUpdate("renamed", VisibilityPublic))
+	assert.ErrorIs(t, err, infra.ForbiddenError)
+
+	// anyone may see a public shader, but only its collaborators may edit it
+	stranger := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "stranger"})
+	_, err = s.ShaderUpdate(stranger, "shader", completeUpdate("renamed", VisibilityPublic))
+	assert.ErrorIs(t, err, infra.ForbiddenError)
+}
+
+func TestShaderUpdate_MissingField(t *testing.T) {
+	mock := repoMock{
+		shaderGetVisibleById: func(shaderId string, _ string) (models.Shader, error) {
+			return models.Shader{Id: shaderId, Name: "shader", Visibility: VisibilityPrivate, Content: "fn main() {}", CreatedBy: "owner"}, nil
+		},
+	}
+	s := &Service{repo: mock, config: config.Default()}
+	ctx := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "owner"})
+
+	// the body of a partial update, sent by clients from before PUT replaced the shader as a whole
+	var update models.ShaderUpdate
+	err := json.Unmarshal([]byte(`{"name":"renamed","visibility":"public"}`), &update)
+	assert.NoError(t, err)
+
+	_, err = s.ShaderUpdate(ctx, "shader", update)
+	assert.Equal(t, infra.NewValidationError("Field 'description' is required!"), err)
+
+	update.Description = stringPointer("")
+	update.Tags = &[]string{}
+	_, err = s.ShaderUpdate(ctx, "shader", update)
+	assert.Equal(t, infra.NewValidationError("Field 'content' is required!"), err)
+}
+
+func TestShaderPatch(t *testing.T) {
+	var updated []any
+	mock := repoMock{
+		shaderGetVisibleById: func(shaderId string, _ string) (models.Shader, error) {
+			return models.Shader{Id: shaderId, Name: "shader", Visibility: VisibilityPrivate, Tags: []string{"one"}, Content: "x", CreatedBy: "owner", Version: 3}, nil
+		},
+		shaderPartialUpdate: func(shaderId string, _ string, expectedVersions []int, name *string, visibility *string, description *string, tags *[]string, content *string) (models.Shader, error) {
+			updated = []any{expectedVersions, *name, visibility, *description, *tags, content}
+			return models.Shader{Id: shaderId, Name: *name, Tags: *tags, Version: 4}, nil
+		},
+		shaderLikedByUser: func(_ string, _ []string) ([]string, error) {
+			return nil, nil
+		},
+		shaderCollaboratorGetRole: collaboratorRoles,
+	}
+	s := &Service{repo: mock, config: config.Default()}
+	ctx := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "editor"})
+
+	mergePatch, err := patch.NewMergePatch([]byte(`{"name":"renamed","description":null}`))
+	assert.NoError(t, err)
+	shader, err := s.ShaderPatch(ctx, "shader", mergePatch)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, shader.Version)
+	assert.Equal(t, []any{[]int{3}, "renamed", (*string)(nil), "", []string{"one"}, (*string)(nil)}, updated)
+
+	jsonPatch, err := patch.NewJsonPatch([]byte(`[{"op":"add","path":"/tags/-","value":"two"}]`))
+	assert.NoError(t, err)
+	_, err = s.ShaderPatch(ctx, "shader", jsonPatch)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"one", "two"}, updated[4])
+
+	mergePatch, err = patch.NewMergePatch([]byte(`{"name":null}`))
+	assert.NoError(t, err)
+	_, err = s.ShaderPatch(ctx, "shader", mergePatch)
+	assert.IsType(t, infra.ValidationError{}, err)
+
+	mergePatch, err = patch.NewMergePatch([]byte(`{"owner":"editor"}`))
+	assert.NoError(t, err)
+	_, err = s.ShaderPatch(ctx, "shader", mergePatch)
+	assert.IsType(t, infra.JsonParsingError{}, err)
+
+	mergePatch, err = patch.NewMergePatch([]byte(`{"visibility":"public"}`))
+	assert.NoError(t, err)
+	_, err = s.ShaderPatch(ctx, "shader", mergePatch)
+	assert.ErrorIs(t, err, infra.ForbiddenError)
+}
+
+func TestShaderUpdate_IfMatch(t *testing.T) {
+	mock := repoMock{
+		shaderGetVisibleById: func(shaderId string, _ string) (models.Shader, error) {
+			return models.Shader{Id: shaderId, Name: "shader", Visibility: VisibilityPrivate, CreatedBy: "owner", Version: 3}, nil
+		},
+		shaderPartialUpdate: func(shaderId string, _ string, _ []int, _ *string, _ *string, _ *string, _ *[]string, _ *string) (models.Shader, error) {
+			return models.Shader{Id: shaderId, Version: 4}, nil
+		},
+		shaderLikedByUser: func(_ string, _ []string) ([]string, error) {
+			return nil, nil
+		},
+	}
+	s := &Service{repo: mock, config: config.Default()}
+	ctx := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "owner"})
+	update := completeUpdate("renamed", VisibilityPrivate)
+
+	_, err := s.ShaderUpdate(InsertIfMatchIntoContext(ctx, []int{2}), "shader", update)
+	assert.ErrorIs(t, err, infra.PreconditionFailedError)
+
+	_, err = s.ShaderUpdate(InsertIfMatchIntoContext(ctx, []int{}), "shader", update)
+	assert.ErrorIs(t, err, infra.PreconditionFailedError)
+
+	_, err = s.ShaderUpdate(InsertIfMatchIntoContext(ctx, []int{2, 3}), "shader", update)
+	assert.NoError(t, err)
+
+	_, err = s.ShaderUpdate(ctx, "shader", update)
+	assert.NoError(t, err)
+}
+
+func TestShaderUpdate_ConcurrentChange(t *testing.T) {
+	version, failures := 3, 0
+	mock := repoMock{
+		shaderGetVisibleById: func(shaderId string, _ string) (models.Shader, error) {
+			return models.Shader{Id: shaderId, Name: "shader", Visibility: VisibilityPrivate, CreatedBy: "owner", Version: version}, nil
+		},
+		shaderPartialUpdate: func(shaderId string, _ string, expectedVersions []int, _ *string, _ *string, _ *string, _ *[]string, _ *string) (models.Shader, error) {
+			// another client changes the shader between every read and write until it runs out of failures
+			if failures > 0 {
+				failures--
+				version++
+				return models.Shader{}, infra.PreconditionFailedError
+			}
+			assert.Equal(t, []int{version}, expectedVersions)
+			return models.Shader{Id: shaderId, Version: version + 1}, nil
+		},
+		shaderLikedByUser: func(_ string, _ []string) ([]string, error) {
+			return nil, nil
+		},
+	}
+	s := &Service{repo: mock, config: config.Default()}
+	ctx := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "owner"})
+	update := completeUpdate("renamed", VisibilityPrivate)
+
+	failures = 1
+	_, err := s.ShaderUpdate(InsertIfMatchIntoContext(ctx, []int{version}), "shader", update)
+	assert.ErrorIs(t, err, infra.PreconditionFailedError)
+
+	failures = 1
+	shader, err := s.ShaderUpdate(ctx, "shader", update)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, shader.Version)
+
+	failures = maxReplaceAttempts
+	_, err = s.ShaderUpdate(ctx, "shader", update)
+	assert.ErrorIs(t, err, infra.ConflictError)
+}
+
+func TestCollaboratorRemove(t *testing.T) {
+	var removed []string
+	mock := repoMock{
+		shaderGetVisibleById: func(shaderId string, _ string) (models.Shader, error) {
+			return models.Shader{Id: shaderId, Visibility: VisibilityPrivate, CreatedBy: "owner-id"}, nil
+		},
+		userGetProfileByUsername: func(username string) (models.UserPublicProfile, error) {
+			return models.UserPublicProfile{Id: username + "-id", Username: username}, nil
+		},
+		shaderCollaboratorRemove: func(_ string, userId string) error {
+			removed = append(removed, userId)
+			return nil
+		},
+	}
+	s := &Service{repo: mock, config: config.Default()}
+
+	viewer := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "viewer-id"})
+	err := s.CollaboratorRemove(viewer, "shader", "editor")
+	assert.ErrorIs(t, err, infra.ForbiddenError)
+
+	err = s.CollaboratorRemove(viewer, "shader", "viewer")
+	assert.NoError(t, err)
+
+	owner := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "owner-id"})
+	err = s.CollaboratorRemove(owner, "shader", "editor")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"viewer-id", "editor-id"}, removed)
+}
+
+func TestShaderGet_ShareToken(t *testing.T) {
+	mock := repoMock{
+		shaderGetPubliclyVisibleById: func(_ string) (models.Shader, error) {
+			return models.Shader{}, infra.NotFoundError
+		},
+		shareLinkUse: func(tokenHash string) (models.ShareLink, error) {
+			if tokenHash != service.HashToken("token") {
+				return models.ShareLink{}, infra.NotFoundError
+			}
+			return models.ShareLink{ShaderId: "shared"}, nil
+		},
+		shaderGetById: func(shaderId string) (models.Shader, error) {
+			return models.Shader{Id: shaderId, Visibility: VisibilityPrivate, CreatedBy: "owner"}, nil
+		},
+	}
+	s := &Service{repo: mock, config: config.Default()}
+
+	_, err := s.ShaderGet(context.Background(), "shared")
+	assert.ErrorIs(t, err, infra.NotFoundError)
+
+	_, err = s.ShaderGet(InsertShareTokenIntoContext(context.Background(), "other"), "shared")
+	assert.ErrorIs(t, err, infra.NotFoundError)
+
+	_, err = s.ShaderGet(InsertShareTokenIntoContext(context.Background(), "token"), "another")
+	assert.ErrorIs(t, err, infra.NotFoundError)
+
+	shader, err := s.ShaderGet(InsertShareTokenIntoContext(context.Background(), "token"), "shared")
+	assert.NoError(t, err)
+	assert.Equal(t, "shared", shader.Id)
+}
+
+func TestShaderFork_ShareToken(t *testing.T) {
+	var forkedFrom string
+	mock := repoMock{
+		shaderGetVisibleById: func(_ string, _ string) (models.Shader, error) {
+			return models.Shader{}, infra.NotFoundError
+		},
+		shareLinkUse: func(tokenHash string) (models.ShareLink, error) {
+			if tokenHash != service.HashToken("token") {
+				return models.ShareLink{}, infra.NotFoundError
+			}
+			return models.ShareLink{ShaderId: "shared"}, nil
+		},
+		shaderGetById: func(shaderId string) (models.Shader, error) {
+			return models.Shader{Id: shaderId, Visibility: VisibilityPrivate, CreatedBy: "owner"}, nil
+		},
+		shaderLikedByUser: func(_ string, _ []string) ([]string, error) {
+			return nil, nil
+		},
+		shaderCreateFork: func(parent models.Shader, createdBy string) (models.Shader, error) {
+			forkedFrom = parent.Id
+			return models.Shader{Id: "fork", CreatedBy: createdBy}, nil
+		},
+	}
+	s := &Service{repo: mock, config: config.Default()}
+	ctx := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "viewer"})
+
+	_, err := s.ShaderFork(ctx, "shared")
+	assert.ErrorIs(t, err, infra.NotFoundError)
+
+	forkId, err := s.ShaderFork(InsertShareTokenIntoContext(ctx, "token"), "shared")
+	assert.NoError(t, err)
+	assert.Equal(t, "fork", forkId)
+	assert.Equal(t, "shared", forkedFrom)
+}
+
+func TestShareLinkCreate_ExpiryInPast(t *testing.T) {
+	s := &Service{repo: repoMock{}, config: config.Default()}
+	ctx := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "owner"})
+
+	expiresAt := time.Now().Add(-time.Minute)
+	_, err := s.ShareLinkCreate(ctx, "shader", models.ShareLinkCreate{ExpiresAt: &expiresAt})
+	assert.IsType(t, infra.ValidationError{}, err)
+}
@@ -0,0 +1,120 @@
+package shader
+
+import (
+	"context"
+	"fmt"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/sdedovic/wgsltoy-server/src/go/service"
+	"time"
+)
+
+// ShareTokenContextKey carries the share link token a request presented, granting read access to the shader it was
+// minted for
+const ShareTokenContextKey = "shareToken"
+
+func ExtractShareTokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(ShareTokenContextKey).(string)
+	return token
+}
+
+func InsertShareTokenIntoContext(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, ShareTokenContextKey, token)
+}
+
+// shaderGetShared looks up a shader through the share token on ctx, infra.NotFoundError unless the token is active and
+// was minted for this shader
+func (s *Service) shaderGetShared(ctx context.Context, shaderId string) (models.Shader, error) {
+	token := ExtractShareTokenFromContext(ctx)
+	if token == "" {
+		return models.Shader{}, infra.NotFoundError
+	}
+
+	shareLink, err := s.repo.ShareLinkUse(ctx, service.HashToken(token))
+	if err != nil {
+		return models.Shader{}, err
+	}
+	if shareLink.ShaderId != shaderId {
+		return models.Shader{}, infra.NotFoundError
+	}
+
+	return s.repo.ShaderGetById(ctx, shaderId)
+}
+
+// ShaderGetByShareToken looks up the shader an active share link token was minted for, regardless of its visibility
+func (s *Service) ShaderGetByShareToken(ctx context.Context, token string) (models.Shader, error) {
+	if token == "" {
+		return models.Shader{}, infra.NotFoundError
+	}
+
+	shareLink, err := s.repo.ShareLinkUse(ctx, service.HashToken(token))
+	if err != nil {
+		return models.Shader{}, err
+	}
+
+	shader, err := s.repo.ShaderGetById(ctx, shareLink.ShaderId)
+	if err != nil {
+		return models.Shader{}, err
+	}
+
+	return s.withViewerDetails(ctx, shader)
+}
+
+// ShareLinkCreate mints a new share link for a shader of the current user. The token is only ever returned here.
+func (s *Service) ShareLinkCreate(ctx context.Context, shaderId string, shareLinkCreate models.ShareLinkCreate) (models.ShareLinkCreated, error) {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return models.ShareLinkCreated{}, err
+	}
+
+	if shareLinkCreate.ExpiresAt != nil && !shareLinkCreate.ExpiresAt.After(time.Now()) {
+		return models.ShareLinkCreated{}, infra.NewValidationError("Field 'expiresAt' must be in the future!")
+	}
+
+	if _, err := s.shaderGetOwned(ctx, shaderId, userInfo.Id); err != nil {
+		return models.ShareLinkCreated{}, err
+	}
+
+	token, tokenHash, err := service.NewToken()
+	if err != nil {
+		return models.ShareLinkCreated{}, err
+	}
+
+	shareLink, err := s.repo.ShareLinkCreate(ctx, shaderId, userInfo.Id, tokenHash, shareLinkCreate.ExpiresAt)
+	if err != nil {
+		return models.ShareLinkCreated{}, err
+	}
+
+	return models.ShareLinkCreated{
+		ShareLink: shareLink,
+		Token:     token,
+		Url:       fmt.Sprintf("/s/%s", token),
+	}, nil
+}
+
+// ShareLinkList lists the share links of a shader of the current user which were not revoked
+func (s *Service) ShareLinkList(ctx context.Context, shaderId string) ([]models.ShareLink, error) {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderRead)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.shaderGetOwned(ctx, shaderId, userInfo.Id); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ShareLinkListActiveByShaderId(ctx, shaderId)
+}
+
+func (s *Service) ShareLinkRevoke(ctx context.Context, shaderId string, linkId string) error {
+	userInfo, err := service.RequireScope(ctx, service.ScopeShaderWrite)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.shaderGetOwned(ctx, shaderId, userInfo.Id); err != nil {
+		return err
+	}
+
+	return s.repo.ShareLinkRevoke(ctx, linkId, shaderId)
+}
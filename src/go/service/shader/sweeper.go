@@ -0,0 +1,29 @@
+package shader
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// SweepTrash permanently purges shaders that have been in the trash for longer than retention, checking once every
+// interval until ctx is done. It is meant to be started in its own goroutine.
+func (s *Service) SweepTrash(ctx context.Context, retention time.Duration, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := s.repo.ShaderPurgeDeletedBefore(ctx, time.Now().Add(-retention))
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed purging trashed shaders", "error", err)
+		} else if purged > 0 {
+			slog.InfoContext(ctx, "Purged trashed shaders", "count", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
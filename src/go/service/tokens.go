@@ -0,0 +1,29 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// NewToken generates a random opaque token, such as a password reset or refresh token, along with the hash it is
+// stored as
+func NewToken() (string, string, error) {
+	token := make([]byte, 32)
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	tokenString := base64.RawURLEncoding.EncodeToString(token)
+	return tokenString, HashToken(tokenString), nil
+}
+
+// HashToken hashes a token made by NewToken for lookup. Tokens are random, so unlike passwords they need neither salt
+// nor a slow hash.
+func HashToken(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(digest[:])
+}
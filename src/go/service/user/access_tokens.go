@@ -0,0 +1,111 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/sdedovic/wgsltoy-server/src/go/service"
+	"regexp"
+	"slices"
+	"time"
+	"unicode/utf8"
+)
+
+// AccessTokenPrefix marks personal access tokens, telling them apart from JWTs and making leaked ones easy to scan for
+const AccessTokenPrefix = "wgt_"
+
+// MaxAccessTokenLifetime is the longest a personal access token may be valid for
+const MaxAccessTokenLifetime = 366 * 24 * time.Hour
+
+var accessTokenNameRegex = regexp.MustCompile(`^[\pL\pM\pN\pP\pS ]+$`)
+
+func validateAccessToken(accessToken models.AccessTokenCreate) error {
+	if accessToken.Name == "" {
+		return infra.NewValidationError("Field 'name' is required!")
+	}
+	if utf8.RuneCountInString(accessToken.Name) > 60 {
+		return infra.NewValidationError("Field 'name' is too long!")
+	}
+	if !accessTokenNameRegex.MatchString(accessToken.Name) {
+		return infra.NewValidationError("Field 'name' contains invalid characters!")
+	}
+
+	if accessToken.ExpiresAt.IsZero() {
+		return infra.NewValidationError("Field 'expiresAt' is required!")
+	}
+	if !accessToken.ExpiresAt.After(time.Now()) {
+		return infra.NewValidationError("Field 'expiresAt' must be in the future!")
+	}
+	if accessToken.ExpiresAt.After(time.Now().Add(MaxAccessTokenLifetime)) {
+		return infra.NewValidationError("Field 'expiresAt' must be at most a year in the future!")
+	}
+
+	if len(accessToken.Scopes) == 0 {
+		return infra.NewValidationError("Field 'scopes' may not be empty!")
+	}
+	for idx, scope := range accessToken.Scopes {
+		if !slices.Contains(service.AccessTokenScopes, scope) {
+			return infra.NewValidationError(fmt.Sprintf("Field 'scopes[%d]' is not a valid scope!", idx))
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) authenticateAccessToken(ctx context.Context, token string) (*service.UserInfo, error) {
+	accessToken, err := s.repo.AccessTokenUse(ctx, service.HashToken(token))
+	if err != nil {
+		if errors.Is(err, infra.NotFoundError) {
+			return nil, infra.UnauthorizedError
+		}
+		return nil, err
+	}
+
+	return &service.UserInfo{Id: accessToken.UserId, AccessTokenId: accessToken.Id, Scopes: accessToken.Scopes}, nil
+}
+
+func (s *Service) AccessTokenCreate(ctx context.Context, accessToken models.AccessTokenCreate) (models.AccessTokenCreated, error) {
+	userInfo, err := service.RequireScope(ctx, service.ScopeAccount)
+	if err != nil {
+		return models.AccessTokenCreated{}, err
+	}
+
+	if err := validateAccessToken(accessToken); err != nil {
+		return models.AccessTokenCreated{}, err
+	}
+	slices.Sort(accessToken.Scopes)
+	scopes := slices.Compact(accessToken.Scopes)
+
+	secret, _, err := service.NewToken()
+	if err != nil {
+		return models.AccessTokenCreated{}, err
+	}
+	token := AccessTokenPrefix + secret
+
+	storedToken, err := s.repo.AccessTokenCreate(ctx, userInfo.Id, accessToken.Name, scopes, service.HashToken(token), accessToken.ExpiresAt)
+	if err != nil {
+		return models.AccessTokenCreated{}, err
+	}
+
+	return models.AccessTokenCreated{AccessToken: storedToken, Token: token}, nil
+}
+
+func (s *Service) AccessTokenList(ctx context.Context) ([]models.AccessToken, error) {
+	userInfo, err := service.RequireScope(ctx, service.ScopeAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.AccessTokenListActiveByUserId(ctx, userInfo.Id)
+}
+
+func (s *Service) AccessTokenRevoke(ctx context.Context, tokenId string) error {
+	userInfo, err := service.RequireScope(ctx, service.ScopeAccount)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.AccessTokenRevoke(ctx, tokenId, userInfo.Id)
+}
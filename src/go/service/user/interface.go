@@ -7,6 +7,25 @@ import (
 
 type IService interface {
 	Register(ctx context.Context, username string, email string, password string) error
-	Login(ctx context.Context, username string, password string) (string, error)
+	Login(ctx context.Context, username string, password string, userAgent string) (models.UserTokens, error)
 	GetCurrent(ctx context.Context) (models.User, error)
+	GetProfile(ctx context.Context, username string) (models.UserPublicProfile, error)
+	UpdateProfile(ctx context.Context, update models.UserProfileUpdate) (models.User, error)
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context) error
+	PasswordForgot(ctx context.Context, email string) error
+	PasswordReset(ctx context.Context, token string, password string) error
+	PasswordChange(ctx context.Context, currentPassword string, newPassword string) error
+
+	Refresh(ctx context.Context, refreshToken string) (models.UserTokens, error)
+	Logout(ctx context.Context) error
+	SessionList(ctx context.Context) ([]models.Session, error)
+	SessionRevoke(ctx context.Context, sessionId string) error
+	SessionRevokeOthers(ctx context.Context) error
+
+	AccessTokenCreate(ctx context.Context, accessToken models.AccessTokenCreate) (models.AccessTokenCreated, error)
+	AccessTokenList(ctx context.Context) ([]models.AccessToken, error)
+	AccessTokenRevoke(ctx context.Context, tokenId string) error
 }
+
+var _ IService = (*Service)(nil)
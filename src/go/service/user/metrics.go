@@ -0,0 +1,6 @@
+package user
+
+import "github.com/sdedovic/wgsltoy-server/src/go/metrics"
+
+var loginsTotal = metrics.NewCounterVec("wgsltoy_logins_total",
+	"Login attempts, by whether they succeeded.", "result")
@@ -8,8 +8,12 @@ import (
 	"fmt"
 	"golang.org/x/crypto/argon2"
 	"strings"
+	"time"
 )
 
+// PasswordResetInterval is how long a user has to wait before another password reset email is sent
+const PasswordResetInterval = time.Minute
+
 const defaultMemory uint32 = 64 * 1024
 const defaultTimeCost uint32 = 3
 const defaultParallelization uint8 = 1
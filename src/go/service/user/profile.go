@@ -0,0 +1,71 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/sdedovic/wgsltoy-server/src/go/service"
+	"net/url"
+	"unicode/utf8"
+)
+
+const MaxLinks = 5
+const MaxLinkLength = 200
+
+func validateLinks(links []string) error {
+	if len(links) > MaxLinks {
+		return infra.NewValidationError(fmt.Sprintf("Field 'links' must contain at most %d entries!", MaxLinks))
+	}
+	for _, link := range links {
+		if len(link) > MaxLinkLength {
+			return infra.NewValidationError(fmt.Sprintf("Field 'links' entries must be at most %d characters!", MaxLinkLength))
+		}
+		parsed, err := url.Parse(link)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return infra.NewValidationError("Field 'links' entries must be http or https URLs!")
+		}
+	}
+	return nil
+}
+
+// GetProfile returns the public profile of the user. Counts of unlisted and private shaders are only included when the
+// caller is that user.
+func (s *Service) GetProfile(ctx context.Context, username string) (models.UserPublicProfile, error) {
+	profile, err := s.repo.UserGetProfileByUsername(ctx, username)
+	if err != nil {
+		return models.UserPublicProfile{}, err
+	}
+
+	counts, err := s.repo.ShaderCountByCreatedBy(ctx, profile.Id)
+	if err != nil {
+		return models.UserPublicProfile{}, err
+	}
+
+	userInfo := service.ExtractUserInfoWithScope(ctx, service.ScopeShaderRead)
+	if userInfo == nil || userInfo.Id != profile.Id {
+		counts.Unlisted = nil
+		counts.Private = nil
+	}
+
+	profile.Shaders = counts
+	return profile, nil
+}
+
+func (s *Service) UpdateProfile(ctx context.Context, update models.UserProfileUpdate) (models.User, error) {
+	userInfo, err := service.RequireScope(ctx, service.ScopeAccount)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if update.Bio != nil && utf8.RuneCountInString(*update.Bio) > s.config.Limits.BioLength {
+		return models.User{}, infra.NewValidationError(fmt.Sprintf("Field 'bio' must be at most %d characters!", s.config.Limits.BioLength))
+	}
+	if update.Links != nil {
+		if err := validateLinks(*update.Links); err != nil {
+			return models.User{}, err
+		}
+	}
+
+	return s.repo.UserUpdateProfile(ctx, userInfo.Id, update.Bio, update.Links)
+}
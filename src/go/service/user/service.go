@@ -2,20 +2,26 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/sdedovic/wgsltoy-server/src/go/config"
 	"github.com/sdedovic/wgsltoy-server/src/go/db"
 	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/mail"
 	"github.com/sdedovic/wgsltoy-server/src/go/models"
 	"github.com/sdedovic/wgsltoy-server/src/go/service"
-	"log"
-	"net/mail"
+	"log/slog"
+	netmail "net/mail"
 	"regexp"
 	"strings"
+	"time"
 	"unicode/utf8"
 )
 
 type Service struct {
-	repo db.IRepository `di.inject:"Repository"`
+	repo   db.IRepository `di.inject:"Repository"`
+	mailer mail.Mailer    `di.inject:"Mailer"`
+	config *config.Config `di.inject:"Config"`
 }
 
 // UsernameRegex validates input is 5 to 15 chars, first one is letter, rest are alphanumeric, -, _, .
@@ -29,6 +35,18 @@ var usernameBlacklist = []string{
 	"guest", "information", "mailer", "mailing", "manager", "marketing", "newsletter", "operator", "password", "postmaster",
 	"project", "projects", "random", "register", "registration", "settings", "subscribe", "support", "supportsystem", "username",
 	"website", "websites", "webmaster", "webmail", "yourname", "yourusername", "yoursite", "yourdomain",
+	// reserved by routes under /user/
+	"login", "logout", "token", "verify-email",
+}
+
+func validatePassword(field string, password string) error {
+	if len(password) == 0 {
+		return infra.NewValidationError(fmt.Sprintf("Field '%s' is required!", field))
+	}
+	if utf8.RuneCountInString(password) < 10 {
+		return infra.NewValidationError("Supplied password is too short!")
+	}
+	return nil
 }
 
 func (s *Service) Register(ctx context.Context, username string, email string, password string) error {
@@ -40,7 +58,7 @@ func (s *Service) Register(ctx context.Context, username string, email string, p
 	}
 	for _, element := range usernameBlacklist {
 		if strings.EqualFold(username, element) {
-			log.Println("WARN", "Banned username attempted:", element)
+			slog.WarnContext(ctx, "Banned username attempted", "username", element)
 			return infra.NewValidationError("Supplied username is not permitted!")
 		}
 	}
@@ -48,15 +66,12 @@ func (s *Service) Register(ctx context.Context, username string, email string, p
 	if len(email) == 0 {
 		return infra.NewValidationError("Field 'email' is required!")
 	}
-	if _, err := mail.ParseAddress(email); err != nil {
+	if _, err := netmail.ParseAddress(email); err != nil {
 		return infra.NewValidationError("Field 'email' is not valid!")
 	}
 
-	if len(password) == 0 {
-		return infra.NewValidationError("Field 'password' is required!")
-	}
-	if utf8.RuneCountInString(password) < 10 {
-		return infra.NewValidationError("Supplied password is too short!")
+	if err := validatePassword("password", password); err != nil {
+		return err
 	}
 
 	hashedPassword, err := HashPassword(password)
@@ -64,54 +79,225 @@ func (s *Service) Register(ctx context.Context, username string, email string, p
 		return fmt.Errorf("failed password hashing caused by: %w", err)
 	}
 
-	_, err = s.repo.UserCreate(username, email, hashedPassword)
+	user, err := s.repo.UserCreate(ctx, username, email, hashedPassword)
 	if err != nil {
 		return err
 	}
 
+	// the account is usable regardless, the user may request another email should this one fail
+	if err := s.sendVerification(ctx, user.Id, time.Now()); err != nil {
+		slog.ErrorContext(ctx, "Failed sending verification email", "userId", user.Id, "error", err)
+	}
+
 	return nil
 }
 
-func (s *Service) Login(ctx context.Context, username string, password string) (string, error) {
+func (s *Service) Login(ctx context.Context, username string, password string, userAgent string) (models.UserTokens, error) {
+	tokens, err := s.login(ctx, username, password, userAgent)
+	if err != nil {
+		loginsTotal.Inc("failure")
+	} else {
+		loginsTotal.Inc("success")
+	}
+	return tokens, err
+}
+
+func (s *Service) login(ctx context.Context, username string, password string, userAgent string) (models.UserTokens, error) {
 	if len(username) == 0 {
-		return "", infra.NewValidationError("Field 'username' is required!")
+		return models.UserTokens{}, infra.NewValidationError("Field 'username' is required!")
 	}
 
 	if len(password) == 0 {
-		return "", infra.NewValidationError("Field 'password' is required!")
+		return models.UserTokens{}, infra.NewValidationError("Field 'password' is required!")
 	}
 
-	user, err := s.repo.UserGetByUsername(username)
+	user, err := s.repo.UserGetByUsername(ctx, username)
 	if err != nil {
-		return "", err
+		return models.UserTokens{}, err
 	}
 
 	isMatch, err := VerifyPassword(password, user.Password)
 	if err != nil {
-		return "", fmt.Errorf("failed verifying user password: %w", err)
+		return models.UserTokens{}, fmt.Errorf("failed verifying user password: %w", err)
 	}
 	if !isMatch {
-		return "", infra.BadLoginError
-	}
-
-	token, err := service.MakeToken(service.UserInfo{user.Id})
-	if err != nil {
-		return "", err
+		return models.UserTokens{}, infra.BadLoginError
 	}
 
-	return token, nil
+	return s.createSession(ctx, user.Id, userAgent)
 }
 
 func (s *Service) GetCurrent(ctx context.Context) (models.User, error) {
-	userInfo := service.ExtractUserInfoFromContext(ctx)
-	if userInfo == nil {
-		return models.User{}, infra.UnauthorizedError
+	userInfo, err := service.RequireScope(ctx, service.ScopeUserRead)
+	if err != nil {
+		return models.User{}, err
 	}
 
-	user, err := s.repo.UserGetById(userInfo.Id)
+	user, err := s.repo.UserGetById(ctx, userInfo.Id)
 	if err != nil {
 		return models.User{}, err
 	}
 
 	return user, nil
 }
+
+func (s *Service) VerifyEmail(ctx context.Context, token string) error {
+	if len(token) == 0 {
+		return infra.NewValidationError("Field 'token' is required!")
+	}
+
+	userId, email, err := ParseVerificationToken(s.config.Auth.AppSecret, token)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.UserCompleteEmailVerification(ctx, userId, email)
+	if errors.Is(err, infra.NotFoundError) {
+		// the user no longer exists or changed their email address since the token was issued
+		return infra.NewValidationError("Supplied token is invalid or expired!")
+	}
+	return err
+}
+
+func (s *Service) ResendVerification(ctx context.Context) error {
+	userInfo, err := service.RequireScope(ctx, service.ScopeAccount)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.repo.UserGetById(ctx, userInfo.Id)
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerification == models.EmailVerificationCompleted {
+		return infra.NewValidationError("Email is already verified!")
+	}
+	if user.VerificationSentAt != nil {
+		if wait := VerificationResendInterval - time.Since(*user.VerificationSentAt); wait > 0 {
+			return infra.NewTooManyRequestsError(wait)
+		}
+	}
+
+	err = s.sendVerification(ctx, user.Id, time.Now().Add(-VerificationResendInterval))
+	if errors.Is(err, infra.NotFoundError) {
+		// raced with another request, which sent the email
+		return infra.NewTooManyRequestsError(VerificationResendInterval)
+	}
+	return err
+}
+
+// sendVerification emails the user a link to verify their email address, unless one was already sent since sentBefore
+func (s *Service) sendVerification(ctx context.Context, userId string, sentBefore time.Time) error {
+	user, err := s.repo.UserClaimVerificationEmail(ctx, userId, sentBefore)
+	if err != nil {
+		return err
+	}
+
+	token, err := MakeVerificationToken(s.config.Auth.AppSecret, s.config.Auth.VerificationTokenLifetime, user.Id, user.Email)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nplease verify your email address by visiting the link below, it expires in %d hours.\n\n%s/verify-email?token=%s\n",
+			user.Username, int(s.config.Auth.VerificationTokenLifetime.Hours()), s.config.Server.AppUrl, token),
+	})
+}
+
+func (s *Service) PasswordForgot(ctx context.Context, email string) error {
+	if len(email) == 0 {
+		return infra.NewValidationError("Field 'email' is required!")
+	}
+
+	user, err := s.repo.UserGetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, infra.NotFoundError) {
+			// do not reveal which email addresses are registered
+			return nil
+		}
+		return err
+	}
+
+	token, tokenHash, err := service.NewToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	created, err := s.repo.PasswordResetCreate(ctx, user.Id, tokenHash, now.Add(s.config.Auth.PasswordResetTokenLifetime), now.Add(-PasswordResetInterval))
+	if err != nil {
+		return err
+	}
+	if !created {
+		slog.WarnContext(ctx, "Password reset requested repeatedly", "userId", user.Id)
+		return nil
+	}
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nsomeone asked to reset your password. To choose a new one, visit the link below, it expires in %d minutes. If this was not you, ignore this email.\n\n%s/reset-password?token=%s\n",
+			user.Username, int(s.config.Auth.PasswordResetTokenLifetime.Minutes()), s.config.Server.AppUrl, token),
+	})
+}
+
+// PasswordReset sets a new password using the token of a password reset email, logging out every session and revoking
+// every personal access token of the user
+func (s *Service) PasswordReset(ctx context.Context, token string, password string) error {
+	if len(token) == 0 {
+		return infra.NewValidationError("Field 'token' is required!")
+	}
+	if err := validatePassword("password", password); err != nil {
+		return err
+	}
+
+	hashedPassword, err := HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed password hashing caused by: %w", err)
+	}
+
+	err = s.repo.PasswordResetConsume(ctx, service.HashToken(token), hashedPassword)
+	if errors.Is(err, infra.NotFoundError) {
+		return infra.NewValidationError("Supplied token is invalid or expired!")
+	}
+	return err
+}
+
+// PasswordChange replaces the password of the current user, logging out every other session. Personal access tokens
+// stay valid, they are revoked one by one with AccessTokenRevoke.
+func (s *Service) PasswordChange(ctx context.Context, currentPassword string, newPassword string) error {
+	userInfo, err := service.RequireScope(ctx, service.ScopeAccount)
+	if err != nil {
+		return err
+	}
+
+	if len(currentPassword) == 0 {
+		return infra.NewValidationError("Field 'currentPassword' is required!")
+	}
+	if err := validatePassword("newPassword", newPassword); err != nil {
+		return err
+	}
+
+	user, err := s.repo.UserGetById(ctx, userInfo.Id)
+	if err != nil {
+		return err
+	}
+
+	isMatch, err := VerifyPassword(currentPassword, user.Password)
+	if err != nil {
+		return fmt.Errorf("failed verifying user password: %w", err)
+	}
+	if !isMatch {
+		return infra.NewValidationError("Field 'currentPassword' is incorrect!")
+	}
+
+	hashedPassword, err := HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed password hashing caused by: %w", err)
+	}
+
+	return s.repo.UserUpdatePassword(ctx, user.Id, hashedPassword, userInfo.SessionId)
+}
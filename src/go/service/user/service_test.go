@@ -2,33 +2,105 @@ package user
 
 import (
 	"context"
+	"github.com/sdedovic/wgsltoy-server/src/go/config"
 	"github.com/sdedovic/wgsltoy-server/src/go/db"
 	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/mail"
 	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/sdedovic/wgsltoy-server/src/go/service"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
+	"time"
 )
 
+// testConfig is the default configuration with the secret the tests sign tokens with
+func testConfig() *config.Config {
+	cfg := config.Default()
+	cfg.Auth.AppSecret = "test"
+	return cfg
+}
+
 type repoMock struct {
 	db.IRepository
 
 	userCreate        func(username string, email string, hashedPassword string) (models.User, error)
 	userGetByUsername func(username string) (models.User, error)
 	userGetById       func(userId string) (models.User, error)
+
+	userClaimVerificationEmail    func(userId string, sentBefore time.Time) (models.User, error)
+	userCompleteEmailVerification func(userId string, email string) error
+	userGetByEmail                func(email string) (models.User, error)
+	userUpdatePassword            func(userId string, hashedPassword string, keepSessionId string) error
+	sessionGetActiveById          func(sessionId string) (models.Session, error)
+	accessTokenCreate             func(userId string, name string, scopes []string, tokenHash string, expiresAt time.Time) (models.AccessToken, error)
+	accessTokenUse                func(tokenHash string) (models.AccessToken, error)
+	userGetProfileByUsername      func(username string) (models.UserPublicProfile, error)
+	userUpdateProfile             func(userId string, bio *string, links *[]string) (models.User, error)
+	shaderCountByCreatedBy        func(createdBy string) (models.UserShaderCounts, error)
+}
+
+type mailerMock struct {
+	sent []mail.Message
+}
+
+func (m *mailerMock) Send(_ context.Context, message mail.Message) error {
+	m.sent = append(m.sent, message)
+	return nil
 }
 
-func (m repoMock) UserCreate(username string, email string, hashedPassword string) (models.User, error) {
+func (m repoMock) UserCreate(_ context.Context, username string, email string, hashedPassword string) (models.User, error) {
 	return m.userCreate(username, email, hashedPassword)
 }
 
-func (m repoMock) UserGetByUsername(username string) (models.User, error) {
+func (m repoMock) UserGetByUsername(_ context.Context, username string) (models.User, error) {
 	return m.userGetByUsername(username)
 }
 
-func (m repoMock) UserGetById(userId string) (models.User, error) {
+func (m repoMock) UserGetById(_ context.Context, userId string) (models.User, error) {
 	return m.userGetById(userId)
 }
 
+func (m repoMock) UserClaimVerificationEmail(_ context.Context, userId string, sentBefore time.Time) (models.User, error) {
+	return m.userClaimVerificationEmail(userId, sentBefore)
+}
+
+func (m repoMock) UserCompleteEmailVerification(_ context.Context, userId string, email string) error {
+	return m.userCompleteEmailVerification(userId, email)
+}
+
+func (m repoMock) UserGetByEmail(_ context.Context, email string) (models.User, error) {
+	return m.userGetByEmail(email)
+}
+
+func (m repoMock) UserUpdatePassword(_ context.Context, userId string, hashedPassword string, keepSessionId string) error {
+	return m.userUpdatePassword(userId, hashedPassword, keepSessionId)
+}
+
+func (m repoMock) SessionGetActiveById(_ context.Context, sessionId string) (models.Session, error) {
+	return m.sessionGetActiveById(sessionId)
+}
+
+func (m repoMock) AccessTokenCreate(_ context.Context, userId string, name string, scopes []string, tokenHash string, expiresAt time.Time) (models.AccessToken, error) {
+	return m.accessTokenCreate(userId, name, scopes, tokenHash, expiresAt)
+}
+
+func (m repoMock) AccessTokenUse(_ context.Context, tokenHash string) (models.AccessToken, error) {
+	return m.accessTokenUse(tokenHash)
+}
+
+func (m repoMock) UserGetProfileByUsername(_ context.Context, username string) (models.UserPublicProfile, error) {
+	return m.userGetProfileByUsername(username)
+}
+
+func (m repoMock) UserUpdateProfile(_ context.Context, userId string, bio *string, links *[]string) (models.User, error) {
+	return m.userUpdateProfile(userId, bio, links)
+}
+
+func (m repoMock) ShaderCountByCreatedBy(_ context.Context, createdBy string) (models.UserShaderCounts, error) {
+	return m.shaderCountByCreatedBy(createdBy)
+}
+
 func TestRegister_FailValidation(t *testing.T) {
 	tests := []struct {
 		name string
@@ -102,10 +174,233 @@ func TestRegister_FailValidation(t *testing.T) {
 					return models.User{}, nil
 				},
 			}
-			s := &Service{mock}
+			s := &Service{repo: mock, config: testConfig()}
 
 			err := s.Register(context.Background(), tt.username, tt.email, tt.password)
 			assert.IsType(t, infra.ValidationError{}, err)
 		})
 	}
 }
+
+func TestRegister_SendsVerification(t *testing.T) {
+	user := models.User{Id: "user", Username: "TestUser1", Email: "testuser@wgsltoy.com"}
+	mock := repoMock{
+		userCreate: func(_, _, _ string) (models.User, error) {
+			return user, nil
+		},
+		userClaimVerificationEmail: func(_ string, _ time.Time) (models.User, error) {
+			return user, nil
+		},
+	}
+	mailer := &mailerMock{}
+	s := &Service{repo: mock, mailer: mailer, config: testConfig()}
+
+	err := s.Register(context.Background(), "TestUser1", "testuser@wgsltoy.com", "valid-password123")
+	assert.NoError(t, err)
+	if assert.Len(t, mailer.sent, 1) {
+		assert.Equal(t, "testuser@wgsltoy.com", mailer.sent[0].To)
+		assert.Contains(t, mailer.sent[0].Body, "https://wgsltoy.com/verify-email?token=")
+	}
+}
+
+func TestResendVerification_Throttled(t *testing.T) {
+	sentAt := time.Now().Add(-time.Minute)
+	mock := repoMock{
+		userGetById: func(userId string) (models.User, error) {
+			return models.User{Id: userId, EmailVerification: models.EmailVerificationPending, VerificationSentAt: &sentAt}, nil
+		},
+		userClaimVerificationEmail: func(_ string, _ time.Time) (models.User, error) {
+			t.Fatal("repository must not be called")
+			return models.User{}, nil
+		},
+	}
+	mailer := &mailerMock{}
+	s := &Service{repo: mock, mailer: mailer, config: testConfig()}
+
+	ctx := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "user"})
+	err := s.ResendVerification(ctx)
+
+	var tooManyRequestsError infra.TooManyRequestsError
+	if assert.ErrorAs(t, err, &tooManyRequestsError) {
+		assert.InDelta(t, (VerificationResendInterval - time.Minute).Seconds(), tooManyRequestsError.RetryAfter.Seconds(), 5)
+	}
+	assert.Empty(t, mailer.sent)
+}
+
+func TestAuthenticate(t *testing.T) {
+	mock := repoMock{
+		sessionGetActiveById: func(sessionId string) (models.Session, error) {
+			if sessionId == "active" {
+				return models.Session{Id: sessionId, UserId: "user"}, nil
+			}
+			return models.Session{}, infra.NotFoundError
+		},
+		accessTokenUse: func(tokenHash string) (models.AccessToken, error) {
+			if tokenHash == service.HashToken("wgt_active") {
+				return models.AccessToken{Id: "token", UserId: "user", Scopes: []string{service.ScopeShaderRead}}, nil
+			}
+			return models.AccessToken{}, infra.NotFoundError
+		},
+	}
+	s := &Service{repo: mock, config: testConfig()}
+
+	makeToken := func(userInfo service.UserInfo) string {
+		token, err := service.MakeToken("test", time.Minute, userInfo)
+		assert.NoError(t, err)
+		return token
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		expected *service.UserInfo
+	}{
+		{"active session", makeToken(service.UserInfo{Id: "user", SessionId: "active"}), &service.UserInfo{Id: "user", SessionId: "active"}},
+		{"revoked session", makeToken(service.UserInfo{Id: "user", SessionId: "revoked"}), nil},
+		{"session of another user", makeToken(service.UserInfo{Id: "other", SessionId: "active"}), nil},
+		{"no session", makeToken(service.UserInfo{Id: "user"}), nil},
+		{"access token", "wgt_active", &service.UserInfo{Id: "user", AccessTokenId: "token", Scopes: []string{service.ScopeShaderRead}}},
+		{"revoked access token", "wgt_revoked", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userInfo, err := s.Authenticate(context.Background(), tt.token)
+			if tt.expected == nil {
+				assert.Equal(t, infra.UnauthorizedError, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expected, userInfo)
+		})
+	}
+}
+
+func TestAccessTokenCreate(t *testing.T) {
+	var storedHash string
+	mock := repoMock{
+		accessTokenCreate: func(userId string, name string, scopes []string, tokenHash string, expiresAt time.Time) (models.AccessToken, error) {
+			storedHash = tokenHash
+			return models.AccessToken{Id: "token", UserId: userId, Name: name, Scopes: scopes, ExpiresAt: expiresAt}, nil
+		},
+	}
+	s := &Service{repo: mock, config: testConfig()}
+
+	create := models.AccessTokenCreate{
+		Name:      "build script",
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+		Scopes:    []string{service.ScopeShaderWrite, service.ScopeShaderRead, service.ScopeShaderWrite},
+	}
+
+	session := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "user", SessionId: "session"})
+	created, err := s.AccessTokenCreate(session, create)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(created.Token, AccessTokenPrefix))
+	assert.Equal(t, service.HashToken(created.Token), storedHash)
+	assert.Equal(t, []string{service.ScopeShaderRead, service.ScopeShaderWrite}, created.Scopes)
+
+	// access tokens cannot mint further access tokens
+	scoped := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "user", Scopes: service.AccessTokenScopes})
+	_, err = s.AccessTokenCreate(scoped, create)
+	assert.ErrorIs(t, err, infra.InsufficientScopeError)
+
+	create.Scopes = []string{service.ScopeAccount}
+	_, err = s.AccessTokenCreate(session, create)
+	assert.IsType(t, infra.ValidationError{}, err)
+}
+
+func TestPasswordForgot_UnknownEmail(t *testing.T) {
+	mock := repoMock{
+		userGetByEmail: func(_ string) (models.User, error) {
+			return models.User{}, infra.NotFoundError
+		},
+	}
+	mailer := &mailerMock{}
+	s := &Service{repo: mock, mailer: mailer, config: testConfig()}
+
+	err := s.PasswordForgot(context.Background(), "nobody@wgsltoy.com")
+	assert.NoError(t, err)
+	assert.Empty(t, mailer.sent)
+}
+
+func TestPasswordChange_RequiresCurrentPassword(t *testing.T) {
+	hashedPassword, err := HashPassword("current-password")
+	assert.NoError(t, err)
+
+	var keptSession *string
+	mock := repoMock{
+		userGetById: func(userId string) (models.User, error) {
+			return models.User{Id: userId, Password: hashedPassword}, nil
+		},
+		userUpdatePassword: func(_ string, _ string, keepSessionId string) error {
+			keptSession = &keepSessionId
+			return nil
+		},
+	}
+	s := &Service{repo: mock, config: testConfig()}
+	ctx := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "user", SessionId: "current"})
+
+	err = s.PasswordChange(ctx, "wrong-password", "new-password123")
+	assert.IsType(t, infra.ValidationError{}, err)
+	assert.Nil(t, keptSession)
+
+	err = s.PasswordChange(ctx, "current-password", "new-password123")
+	assert.NoError(t, err)
+	if assert.NotNil(t, keptSession) {
+		assert.Equal(t, "current", *keptSession)
+	}
+}
+
+func TestGetProfile_HidesPrivateCountsFromOthers(t *testing.T) {
+	one, two := 1, 2
+	mock := repoMock{
+		userGetProfileByUsername: func(username string) (models.UserPublicProfile, error) {
+			return models.UserPublicProfile{Id: "owner", Username: username}, nil
+		},
+		shaderCountByCreatedBy: func(_ string) (models.UserShaderCounts, error) {
+			return models.UserShaderCounts{Public: 3, Unlisted: &one, Private: &two}, nil
+		},
+	}
+	s := &Service{repo: mock, config: testConfig()}
+
+	profile, err := s.GetProfile(context.Background(), "owner")
+	assert.NoError(t, err)
+	assert.Equal(t, models.UserShaderCounts{Public: 3}, profile.Shaders)
+
+	other := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "other"})
+	profile, err = s.GetProfile(other, "owner")
+	assert.NoError(t, err)
+	assert.Equal(t, models.UserShaderCounts{Public: 3}, profile.Shaders)
+
+	owner := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "owner"})
+	profile, err = s.GetProfile(owner, "owner")
+	assert.NoError(t, err)
+	assert.Equal(t, models.UserShaderCounts{Public: 3, Unlisted: &one, Private: &two}, profile.Shaders)
+}
+
+func TestUpdateProfile_FailValidation(t *testing.T) {
+	mock := repoMock{
+		userUpdateProfile: func(_ string, _ *string, _ *[]string) (models.User, error) {
+			t.Fatal("repository must not be called")
+			return models.User{}, nil
+		},
+	}
+	s := &Service{repo: mock, config: testConfig()}
+	ctx := service.InsertUserInfoIntoContext(context.Background(), &service.UserInfo{Id: "user"})
+
+	tests := []struct {
+		name   string
+		update models.UserProfileUpdate
+	}{
+		{"bio too long", models.UserProfileUpdate{Bio: func() *string { bio := strings.Repeat("a", config.Default().Limits.BioLength+1); return &bio }()}},
+		{"too many links", models.UserProfileUpdate{Links: &[]string{"https://a.com", "https://b.com", "https://c.com", "https://d.com", "https://e.com", "https://f.com"}}},
+		{"relative link", models.UserProfileUpdate{Links: &[]string{"/shader/abc"}}},
+		{"javascript link", models.UserProfileUpdate{Links: &[]string{"javascript:alert(1)"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.UpdateProfile(ctx, tt.update)
+			assert.IsType(t, infra.ValidationError{}, err)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/sdedovic/wgsltoy-server/src/go/service"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxUserAgentLength bounds the user agent stored to tell sessions apart
+const maxUserAgentLength = 256
+
+// createSession starts a new session for the user and hands out its first tokens
+func (s *Service) createSession(ctx context.Context, userId string, userAgent string) (models.UserTokens, error) {
+	refreshToken, refreshTokenHash, err := service.NewToken()
+	if err != nil {
+		return models.UserTokens{}, err
+	}
+
+	if utf8.RuneCountInString(userAgent) > maxUserAgentLength {
+		userAgent = string([]rune(userAgent)[:maxUserAgentLength])
+	}
+
+	session, err := s.repo.SessionCreate(ctx, userId, refreshTokenHash, userAgent, time.Now().Add(s.config.Auth.RefreshTokenLifetime))
+	if err != nil {
+		return models.UserTokens{}, err
+	}
+
+	return s.makeTokens(session, refreshToken)
+}
+
+func (s *Service) makeTokens(session models.Session, refreshToken string) (models.UserTokens, error) {
+	accessToken, err := service.MakeToken(s.config.Auth.AppSecret, s.config.Auth.AccessTokenLifetime, service.UserInfo{Id: session.UserId, SessionId: session.Id})
+	if err != nil {
+		return models.UserTokens{}, err
+	}
+
+	return models.UserTokens{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.config.Auth.AccessTokenLifetime.Seconds()),
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// Authenticate resolves the user of a personal access token, or of a JWT whose session was neither revoked nor has
+// expired. JWTs not tied to a session are rejected.
+func (s *Service) Authenticate(ctx context.Context, token string) (*service.UserInfo, error) {
+	if strings.HasPrefix(token, AccessTokenPrefix) {
+		return s.authenticateAccessToken(ctx, token)
+	}
+
+	userInfo, err := service.ParseToken(s.config.Auth.AppSecret, token)
+	if err != nil {
+		return nil, err
+	}
+	if userInfo.SessionId == "" {
+		return nil, infra.UnauthorizedError
+	}
+
+	session, err := s.repo.SessionGetActiveById(ctx, userInfo.SessionId)
+	if err != nil {
+		if errors.Is(err, infra.NotFoundError) {
+			return nil, infra.UnauthorizedError
+		}
+		return nil, err
+	}
+
+	if session.UserId != userInfo.Id {
+		return nil, infra.UnauthorizedError
+	}
+	return userInfo, nil
+}
+
+// Refresh exchanges a refresh token for new tokens, the refresh token may only be used once
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (models.UserTokens, error) {
+	if len(refreshToken) == 0 {
+		return models.UserTokens{}, infra.NewValidationError("Field 'refreshToken' is required!")
+	}
+
+	newRefreshToken, newRefreshTokenHash, err := service.NewToken()
+	if err != nil {
+		return models.UserTokens{}, err
+	}
+
+	session, err := s.repo.SessionRotate(ctx, service.HashToken(refreshToken), newRefreshTokenHash, time.Now().Add(s.config.Auth.RefreshTokenLifetime))
+	if err != nil {
+		if errors.Is(err, infra.NotFoundError) {
+			return models.UserTokens{}, infra.UnauthorizedError
+		}
+		return models.UserTokens{}, err
+	}
+
+	return s.makeTokens(session, newRefreshToken)
+}
+
+// Logout revokes the session of the current user
+func (s *Service) Logout(ctx context.Context) error {
+	userInfo, err := service.RequireScope(ctx, service.ScopeAccount)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.SessionRevoke(ctx, userInfo.SessionId, userInfo.Id)
+}
+
+func (s *Service) SessionList(ctx context.Context) ([]models.Session, error) {
+	userInfo, err := service.RequireScope(ctx, service.ScopeAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.repo.SessionListActiveByUserId(ctx, userInfo.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	for idx, session := range sessions {
+		sessions[idx].Current = session.Id == userInfo.SessionId
+	}
+	return sessions, nil
+}
+
+func (s *Service) SessionRevoke(ctx context.Context, sessionId string) error {
+	userInfo, err := service.RequireScope(ctx, service.ScopeAccount)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.SessionRevoke(ctx, sessionId, userInfo.Id)
+}
+
+// SessionRevokeOthers logs the current user out everywhere except the session the request was made with
+func (s *Service) SessionRevokeOthers(ctx context.Context) error {
+	userInfo, err := service.RequireScope(ctx, service.ScopeAccount)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.SessionRevokeAllByUserId(ctx, userInfo.Id, userInfo.SessionId)
+}
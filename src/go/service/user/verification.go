@@ -0,0 +1,73 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"time"
+)
+
+const verificationIssuer = "wgsltoy.com"
+const verificationAudience = "email-verification"
+
+// VerificationResendInterval is how long a user has to wait before another verification email is sent
+const VerificationResendInterval = 5 * time.Minute
+
+var verificationMethod = jwt.SigningMethodHS256
+
+// verificationKey is derived from the app secret so verification tokens are never accepted for authentication
+func verificationKey(secret string) []byte {
+	return []byte(verificationAudience + ":" + secret)
+}
+
+// MakeVerificationToken signs a token proving ownership of email by the user, which is valid for lifetime
+func MakeVerificationToken(secret string, lifetime time.Duration, userId string, email string) (string, error) {
+	token := jwt.NewWithClaims(verificationMethod, jwt.MapClaims{
+		"sub":   userId,
+		"email": email,
+		"aud":   verificationAudience,
+		"exp":   time.Now().Add(lifetime).Unix(),
+		"iat":   time.Now().Unix(),
+		"iss":   verificationIssuer,
+	})
+	tokenString, err := token.SignedString(verificationKey(secret))
+	if err != nil {
+		return "", fmt.Errorf("failed signing verification token caused by: %w", err)
+	}
+
+	return tokenString, nil
+}
+
+// ParseVerificationToken returns the user and email address a token made by MakeVerificationToken was issued for
+func ParseVerificationToken(secret string, tokenString string) (string, string, error) {
+	invalid := infra.NewValidationError("Supplied token is invalid or expired!")
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return verificationKey(secret), nil
+	},
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuer(verificationIssuer),
+		jwt.WithAudience(verificationAudience),
+		jwt.WithIssuedAt(),
+		jwt.WithValidMethods([]string{verificationMethod.Name}))
+	if err != nil || token == nil || !token.Valid {
+		return "", "", invalid
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", "", errors.New("unexpected verification token claims")
+	}
+
+	userId, err := claims.GetSubject()
+	if err != nil || userId == "" {
+		return "", "", invalid
+	}
+	email, ok := claims["email"].(string)
+	if !ok || email == "" {
+		return "", "", invalid
+	}
+
+	return userId, email, nil
+}
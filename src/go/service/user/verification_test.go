@@ -0,0 +1,28 @@
+package user
+
+import (
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/service"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestVerificationToken(t *testing.T) {
+	token, err := MakeVerificationToken("test", time.Hour, "user", "user@wgsltoy.com")
+	assert.NoError(t, err)
+
+	userId, email, err := ParseVerificationToken("test", token)
+	assert.NoError(t, err)
+	assert.Equal(t, "user", userId)
+	assert.Equal(t, "user@wgsltoy.com", email)
+
+	// verification tokens must not authenticate and vice versa
+	_, err = service.ParseToken("test", token)
+	assert.ErrorIs(t, err, infra.UnauthorizedError)
+
+	loginToken, err := service.MakeToken("test", time.Hour, service.UserInfo{Id: "user"})
+	assert.NoError(t, err)
+	_, _, err = ParseVerificationToken("test", loginToken)
+	assert.IsType(t, infra.ValidationError{}, err)
+}
@@ -3,19 +3,124 @@ package web
 import (
 	"context"
 	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/logging"
 	"github.com/sdedovic/wgsltoy-server/src/go/service"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
-// StartContext extracts the root context from the incoming HTTP request to be passed down to subsequent processing
-func StartContext(r *http.Request) context.Context {
-	return context.Background()
+// requestTimeout bounds the processing of requests whose route does not set a deadline of its own
+var requestTimeout = 30 * time.Second
+
+// SetRequestTimeout changes the deadline applied by Handler to requests, unless overridden with WithTimeout
+func SetRequestTimeout(timeout time.Duration) {
+	requestTimeout = timeout
 }
 
-func Handler(handler func(context.Context, http.ResponseWriter, *http.Request) error) http.HandlerFunc {
+// WithTimeout sets the deadline of the requests handled by handler, overriding the default applied by Handler
+func WithTimeout(timeout time.Duration, handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := StartContext(r)
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		handler(w, r.WithContext(ctx))
+	}
+}
+
+// StartContext derives the root context from the incoming HTTP request to be passed down to subsequent processing. It
+// is cancelled when the client goes away or the deadline of the request passes.
+func StartContext(r *http.Request) (context.Context, context.CancelFunc) {
+	if _, ok := r.Context().Deadline(); ok {
+		return context.WithCancel(r.Context())
+	}
+	return context.WithTimeout(r.Context(), requestTimeout)
+}
+
+// authenticator resolves the user of every request carrying a bearer token. Until it is set such requests are rejected.
+var authenticator service.Authenticator
+
+// SetAuthenticator installs the check applied to authenticated requests by Handler
+func SetAuthenticator(a service.Authenticator) {
+	authenticator = a
+}
+
+// responseRecorder remembers the status and size of a response, and the class of error it reports if any, for the
+// access log and metrics
+type responseRecorder struct {
+	http.ResponseWriter
+	status     int
+	bytes      int64
+	errorClass string
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *responseRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// recordRequest writes the access log line of a request and updates the request metrics. Routes are looked up in
+// http.DefaultServeMux where they are registered.
+func recordRequest(ctx context.Context, r *http.Request, w *responseRecorder, start time.Time) {
+	_, route := http.DefaultServeMux.Handler(r)
+	latency := time.Since(start)
+
+	var userId string
+	if userInfo := service.ExtractUserInfoFromContext(ctx); userInfo != nil {
+		userId = userInfo.Id
+	}
+
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	slog.LogAttrs(ctx, slog.LevelInfo, "Request handled",
+		slog.String("method", r.Method),
+		slog.String("route", route),
+		slog.Int("status", status),
+		slog.Duration("latency", latency),
+		slog.String("userId", userId),
+		slog.Int64("bytes", w.bytes),
+		slog.String("errorClass", w.errorClass),
+	)
+
+	statusLabel := strconv.Itoa(status)
+	requestsTotal.Inc(r.Method, route, statusLabel, w.errorClass)
+	requestDuration.Observe(latency.Seconds(), r.Method, route, statusLabel, w.errorClass)
+}
+
+func Handler(handler func(context.Context, http.ResponseWriter, *http.Request) error) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		w := &responseRecorder{ResponseWriter: rw}
+
+		ctx, cancel := StartContext(r)
+		defer cancel()
+
+		// Tag the request with the ID supplied by the client or a proxy, or a new one, so its log lines can be found
+		requestId := logging.RequestId(r.Header.Get("X-Request-ID"))
+		w.Header().Set("X-Request-ID", requestId)
+		ctx = logging.InsertRequestIdIntoContext(ctx, requestId)
+		defer func() {
+			recordRequest(ctx, r, w, start)
+		}()
 
 		// If Authorization header is present, extract user information and reject with 401 Unauthorized on failure.
 		//  User info is then added to ctx for handlers to determine authorization.
@@ -23,13 +128,17 @@ func Handler(handler func(context.Context, http.ResponseWriter, *http.Request) e
 		if authorizationHeader != "" {
 			parts := strings.Split(authorizationHeader, " ")
 			if len(parts) != 2 || parts[0] != "Bearer" {
-				WriteErrorResponse(w, infra.UnauthorizedError)
+				WriteErrorResponse(ctx, w, infra.UnauthorizedError)
 				return
 			}
 
-			user, err := service.ParseToken(parts[1])
+			if authenticator == nil {
+				WriteErrorResponse(ctx, w, infra.UnauthorizedError)
+				return
+			}
+			user, err := authenticator.Authenticate(ctx, parts[1])
 			if err != nil {
-				WriteErrorResponse(w, err)
+				WriteErrorResponse(ctx, w, err)
 				return
 			}
 
@@ -40,7 +149,7 @@ func Handler(handler func(context.Context, http.ResponseWriter, *http.Request) e
 
 		w.Header().Set("X-Content-Type-Options", "nosniff")
 		if err != nil {
-			WriteErrorResponse(w, err)
+			WriteErrorResponse(ctx, w, err)
 		}
 	}
 }
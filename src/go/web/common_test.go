@@ -0,0 +1,103 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sdedovic/wgsltoy-server/src/go/config"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/logging"
+	"github.com/sdedovic/wgsltoy-server/src/go/metrics"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_Deadline(t *testing.T) {
+	var deadline time.Time
+	handler := Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		deadline, _ = ctx.Deadline()
+		return nil
+	})
+
+	handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	assert.WithinDuration(t, time.Now().Add(requestTimeout), deadline, time.Second)
+
+	WithTimeout(time.Second, handler)(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	assert.WithinDuration(t, time.Now().Add(time.Second), deadline, time.Second)
+}
+
+func TestHandler_Cancelled(t *testing.T) {
+	handler := Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		<-ctx.Done()
+		return fmt.Errorf("failed querying shaders caused by: %w", ctx.Err())
+	})
+
+	w := httptest.NewRecorder()
+	WithTimeout(time.Millisecond, handler)(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestHandler_RequestId(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := logging.New(config.LogConfig{Level: "info", Format: "json"}, &out)
+	assert.NoError(t, err)
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(logger)
+
+	handler := Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return errors.New("boom")
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Request-ID", "abc-123")
+	w := httptest.NewRecorder()
+	handler(w, r)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "abc-123", w.Header().Get("X-Request-ID"))
+	assert.JSONEq(t, `{"errorClass":"UNKNOWN","causedBy":"An unexpected error occurred!","requestId":"abc-123"}`, w.Body.String())
+
+	// the error and then the access log line, both tagged with the request ID
+	decoder := json.NewDecoder(&out)
+	var errorLine, accessLine map[string]any
+	assert.NoError(t, decoder.Decode(&errorLine))
+	assert.NoError(t, decoder.Decode(&accessLine))
+	assert.Equal(t, "abc-123", errorLine["requestId"])
+	assert.Equal(t, "abc-123", accessLine["requestId"])
+	assert.Equal(t, "GET", accessLine["method"])
+	assert.EqualValues(t, http.StatusInternalServerError, accessLine["status"])
+	assert.EqualValues(t, w.Body.Len(), accessLine["bytes"])
+	assert.Equal(t, "UNKNOWN", accessLine["errorClass"])
+
+	var exposition strings.Builder
+	assert.NoError(t, metrics.Default.Write(&exposition))
+	assert.Contains(t, exposition.String(), `http_requests_total{method="GET",route="",status="500",error_class="UNKNOWN"}`)
+}
+
+// brokenWriter fails every write, as when the client disconnected before the response was sent
+type brokenWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w brokenWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestWriteErrorResponse_WriteFails(t *testing.T) {
+	w := brokenWriter{httptest.NewRecorder()}
+	WriteErrorResponse(context.Background(), w, infra.NotFoundError)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
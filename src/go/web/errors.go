@@ -1,13 +1,17 @@
 package web
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/sdedovic/wgsltoy-server/src/go/infra"
-	"log"
+	"github.com/sdedovic/wgsltoy-server/src/go/logging"
+	"github.com/sdedovic/wgsltoy-server/src/go/wgsl"
+	"log/slog"
+	"math"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
 )
 
@@ -23,47 +27,119 @@ func NewUnsupportedOperationError(allow ...string) error {
 	return UnsupportedOperationError{allow}
 }
 
+// UnsupportedMediaTypeError occurs when a PATCH request body is in a format other than the accepted ones
+type UnsupportedMediaTypeError struct {
+	accept []string
+}
+
+func (e UnsupportedMediaTypeError) Error() string {
+	return fmt.Sprintf("Supported media types are: [%s].", strings.Join(e.accept, ", "))
+}
+
+func NewUnsupportedMediaTypeError(accept ...string) error {
+	return UnsupportedMediaTypeError{accept}
+}
+
+// RequestTooLargeError occurs when a request body exceeds the size an endpoint accepts
+type RequestTooLargeError struct {
+	limit int64
+}
+
+func (e RequestTooLargeError) Error() string {
+	return fmt.Sprintf("Request body must not exceed %d bytes.", e.limit)
+}
+
+func NewRequestTooLargeError(limit int64) error {
+	return RequestTooLargeError{limit}
+}
+
 type ErrorDto struct {
 	Class   string `json:"errorClass"`
 	Message string `json:"causedBy"`
 }
 
-func WriteErrorResponse(w http.ResponseWriter, in error) {
+func (dto ErrorDto) errorClass() string {
+	return dto.Class
+}
+
+// WgslErrorDto extends ErrorDto with the problems found in rejected WGSL source
+type WgslErrorDto struct {
+	ErrorDto
+	Diagnostics []wgsl.Diagnostic `json:"diagnostics"`
+}
+
+// UnknownErrorDto extends ErrorDto with the ID of the request, so unexpected errors can be traced to its log lines
+type UnknownErrorDto struct {
+	ErrorDto
+	RequestId string `json:"requestId"`
+}
+
+func WriteErrorResponse(ctx context.Context, w http.ResponseWriter, in error) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 
-	var err error
+	var status int
+	var response interface{ errorClass() string }
 
 	var validationError infra.ValidationError
 	var unsupportedOperationError UnsupportedOperationError
+	var unsupportedMediaTypeError UnsupportedMediaTypeError
+	var requestTooLargeError RequestTooLargeError
 	var jsonParsingError infra.JsonParsingError
+	var wgslError wgsl.Error
+	var tooManyRequestsError infra.TooManyRequestsError
 	switch {
 	case errors.As(in, &validationError):
-		w.WriteHeader(http.StatusBadRequest)
-		err = json.NewEncoder(w).Encode(ErrorDto{"VALIDATION_FAILURE", in.Error()})
+		status, response = http.StatusBadRequest, ErrorDto{"VALIDATION_FAILURE", in.Error()}
+	case errors.As(in, &wgslError):
+		status, response = http.StatusBadRequest, WgslErrorDto{ErrorDto{"WGSL_SYNTAX_ERROR", "Field 'content' is not valid WGSL!"}, wgslError.Diagnostics}
 	case errors.Is(in, infra.BadLoginError):
-		w.WriteHeader(http.StatusBadRequest)
-		err = json.NewEncoder(w).Encode(ErrorDto{"BAD_LOGIN", "Either 'username' or 'password' are incorrect."})
+		status, response = http.StatusBadRequest, ErrorDto{"BAD_LOGIN", "Either 'username' or 'password' are incorrect."}
 	case errors.Is(in, infra.UnauthorizedError):
-		w.WriteHeader(http.StatusUnauthorized)
-		err = json.NewEncoder(w).Encode(ErrorDto{"UNAUTHORIZED", "This resource requires authorization."})
+		status, response = http.StatusUnauthorized, ErrorDto{"UNAUTHORIZED", "This resource requires authorization."}
+	case errors.Is(in, infra.InsufficientScopeError):
+		status, response = http.StatusForbidden, ErrorDto{"INSUFFICIENT_SCOPE", "The supplied token does not permit this operation."}
+	case errors.Is(in, infra.ForbiddenError):
+		status, response = http.StatusForbidden, ErrorDto{"FORBIDDEN", "You are not permitted to perform this operation."}
+	case errors.Is(in, infra.EmailNotVerifiedError):
+		status, response = http.StatusForbidden, ErrorDto{"EMAIL_NOT_VERIFIED", "This operation requires a verified email address."}
+	case errors.Is(in, infra.PreconditionFailedError):
+		status, response = http.StatusPreconditionFailed, ErrorDto{"PRECONDITION_FAILED", "The resource was modified since it was last read."}
+	case errors.Is(in, infra.ConflictError):
+		status, response = http.StatusConflict, ErrorDto{"CONFLICT", "The resource was modified concurrently, try again."}
+	case errors.As(in, &tooManyRequestsError):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(tooManyRequestsError.RetryAfter.Seconds()))))
+		status, response = http.StatusTooManyRequests, ErrorDto{"TOO_MANY_REQUESTS", in.Error()}
 	case errors.Is(in, infra.NotFoundError):
-		w.WriteHeader(http.StatusNotFound)
-		err = json.NewEncoder(w).Encode(ErrorDto{"NOT_FOUND", "The requested resource was not found."})
+		status, response = http.StatusNotFound, ErrorDto{"NOT_FOUND", "The requested resource was not found."}
 	case errors.As(in, &jsonParsingError):
-		w.WriteHeader(http.StatusBadRequest)
-		err = json.NewEncoder(w).Encode(ErrorDto{"JSON_PARSING_FAILURE", "Failed to parse JSON payload."})
+		status, response = http.StatusBadRequest, ErrorDto{"JSON_PARSING_FAILURE", "Failed to parse JSON payload."}
 	case errors.As(in, &unsupportedOperationError):
 		w.Header().Set("Allow", strings.ToUpper(strings.Join(unsupportedOperationError.allow, ", ")))
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		err = json.NewEncoder(w).Encode(ErrorDto{"UNSUPPORTED_OPERATION", in.Error()})
+		status, response = http.StatusMethodNotAllowed, ErrorDto{"UNSUPPORTED_OPERATION", in.Error()}
+	case errors.As(in, &unsupportedMediaTypeError):
+		w.Header().Set("Accept-Patch", strings.Join(unsupportedMediaTypeError.accept, ", "))
+		status, response = http.StatusUnsupportedMediaType, ErrorDto{"UNSUPPORTED_MEDIA_TYPE", in.Error()}
+	case errors.As(in, &requestTooLargeError):
+		status, response = http.StatusRequestEntityTooLarge, ErrorDto{"REQUEST_TOO_LARGE", in.Error()}
+	case errors.Is(in, context.DeadlineExceeded):
+		slog.WarnContext(ctx, "Request timed out", "error", in)
+		status, response = http.StatusGatewayTimeout, ErrorDto{"TIMEOUT", "The request took too long to process."}
+	case errors.Is(in, context.Canceled):
+		status, response = http.StatusServiceUnavailable, ErrorDto{"CANCELLED", "The request was cancelled before it completed."}
 	default:
-		log.Println("ERROR", in.Error())
-		w.WriteHeader(http.StatusInternalServerError)
-		err = json.NewEncoder(w).Encode(ErrorDto{"UNKNOWN", "An unexpected error occurred!"})
+		slog.ErrorContext(ctx, "Unexpected error", "error", in)
+		status, response = http.StatusInternalServerError, UnknownErrorDto{ErrorDto{"UNKNOWN", "An unexpected error occurred!"}, logging.ExtractRequestIdFromContext(ctx)}
+	}
+
+	// let the access log and metrics of the request tell errors apart
+	if recorder, ok := w.(*responseRecorder); ok {
+		recorder.errorClass = response.errorClass()
 	}
 
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(response)
 	if err != nil {
-		log.Println("ERROR", err.Error())
-		os.Exit(1)
+		// the client is most likely gone, which is no reason to take down every other request with it
+		slog.ErrorContext(ctx, "Failed writing error response", "error", err)
 	}
 }
@@ -0,0 +1,9 @@
+package web
+
+import "github.com/sdedovic/wgsltoy-server/src/go/metrics"
+
+// requestsTotal and requestDuration are labelled with the ErrorDto class of failed requests, empty otherwise
+var requestsTotal = metrics.NewCounterVec("http_requests_total",
+	"Requests handled, by route and outcome.", "method", "route", "status", "error_class")
+var requestDuration = metrics.NewHistogramVec("http_request_duration_seconds",
+	"Time taken to handle requests, by route and outcome.", metrics.DefaultBuckets, "method", "route", "status", "error_class")
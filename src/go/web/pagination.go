@@ -0,0 +1,29 @@
+package web
+
+import (
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"net/http"
+	"strconv"
+)
+
+// ParsePageRequest extracts the 'sort', 'cursor' and 'limit' query parameters of a paginated listing. Absent values are
+// left empty for the service to apply its defaults.
+func ParsePageRequest(r *http.Request) (models.PageRequest, error) {
+	query := r.URL.Query()
+
+	page := models.PageRequest{
+		Sort:   query.Get("sort"),
+		Cursor: query.Get("cursor"),
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 {
+			return models.PageRequest{}, infra.NewValidationError("Parameter 'limit' must be a positive number!")
+		}
+		page.Limit = value
+	}
+
+	return page, nil
+}
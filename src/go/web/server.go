@@ -0,0 +1,52 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sdedovic/wgsltoy-server/src/go/config"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// NewServer creates the HTTP server for handler, bounding how long clients may take to send requests and read responses
+func NewServer(serverConfig config.ServerConfig, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              serverConfig.ListenAddress,
+		Handler:           handler,
+		ReadTimeout:       serverConfig.ReadTimeout,
+		ReadHeaderTimeout: serverConfig.ReadHeaderTimeout,
+		WriteTimeout:      serverConfig.WriteTimeout,
+		IdleTimeout:       serverConfig.IdleTimeout,
+		MaxHeaderBytes:    serverConfig.MaxHeaderBytes,
+	}
+}
+
+// Serve runs server until it fails or ctx is done. It then stops accepting connections and waits up to shutdownTimeout
+// for in-flight requests to finish.
+func Serve(ctx context.Context, server *http.Server, shutdownTimeout time.Duration) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down, waiting for in-flight requests", "timeout", shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := server.Shutdown(shutdownCtx)
+	if err != nil {
+		return fmt.Errorf("failed shutting down gracefully caused by: %w", err)
+	}
+	if err = <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
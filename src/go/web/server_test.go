@@ -0,0 +1,31 @@
+package web
+
+import (
+	"context"
+	"github.com/sdedovic/wgsltoy-server/src/go/config"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServe_Shutdown(t *testing.T) {
+	serverConfig := config.Default().Server
+	serverConfig.ListenAddress = "127.0.0.1:0"
+	server := NewServer(serverConfig, http.NotFoundHandler())
+	assert.Equal(t, serverConfig.ReadHeaderTimeout, server.ReadHeaderTimeout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(ctx, server, time.Second)
+	}()
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+}
@@ -0,0 +1,110 @@
+package shader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/sdedovic/wgsltoy-server/src/go/web"
+	"net/http"
+)
+
+func (c *Controller) ShaderCollaborators() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "GET" {
+			return web.NewUnsupportedOperationError("GET")
+		}
+
+		shaderId := r.PathValue("id")
+		if shaderId == "" {
+			return infra.NotFoundError
+		}
+
+		collaborators, err := c.service.CollaboratorList(ctx, shaderId)
+		if err != nil {
+			return err
+		}
+
+		for idx, collaborator := range collaborators {
+			collaborators[idx].Location = fmt.Sprintf("/shader/%s/collaborators/%s", shaderId, collaborator.Username)
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(w).Encode(collaborators)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+		return nil
+	})
+}
+
+func (c *Controller) ShaderCollaboratorByUsername() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		shaderId := r.PathValue("id")
+		username := r.PathValue("username")
+		if shaderId == "" || username == "" {
+			return infra.NotFoundError
+		}
+
+		switch r.Method {
+		case "PUT":
+			var collaboratorUpsert models.CollaboratorUpsert
+			err := json.NewDecoder(r.Body).Decode(&collaboratorUpsert)
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+
+			err = c.service.CollaboratorUpsert(ctx, shaderId, username, collaboratorUpsert)
+			if err != nil {
+				return err
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		case "DELETE":
+			err := c.service.CollaboratorRemove(ctx, shaderId, username)
+			if err != nil {
+				return err
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		default:
+			return web.NewUnsupportedOperationError("PUT", "DELETE")
+		}
+	})
+}
+
+func (c *Controller) ShaderTransfer() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "POST" {
+			return web.NewUnsupportedOperationError("POST")
+		}
+
+		shaderId := r.PathValue("id")
+		if shaderId == "" {
+			return infra.NotFoundError
+		}
+
+		var shaderTransfer models.ShaderTransfer
+		err := json.NewDecoder(r.Body).Decode(&shaderTransfer)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+
+		shader, err := c.service.ShaderTransfer(ctx, shaderId, shaderTransfer)
+		if err != nil {
+			return err
+		}
+
+		shader.Location = fmt.Sprintf("/shader/%s", shader.Id)
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(w).Encode(shader)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,183 @@
+package shader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/sdedovic/wgsltoy-server/src/go/web"
+	"net/http"
+)
+
+func (c *Controller) Collections() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "POST" {
+			return web.NewUnsupportedOperationError("POST")
+		}
+
+		var collectionCreate models.CollectionCreate
+		err := json.NewDecoder(r.Body).Decode(&collectionCreate)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+
+		collectionId, err := c.service.CollectionCreate(ctx, collectionCreate)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Location", fmt.Sprintf("/collection/%s", collectionId))
+		w.WriteHeader(http.StatusCreated)
+
+		return nil
+	})
+}
+
+func (c *Controller) CollectionListOwn() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "GET" {
+			return web.NewUnsupportedOperationError("GET")
+		}
+
+		collections, err := c.service.CollectionListCurrentUser(ctx)
+		if err != nil {
+			return err
+		}
+
+		for idx, collection := range collections {
+			collections[idx].Location = fmt.Sprintf("/collection/%s", collection.Id)
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(w).Encode(collections)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+		return nil
+	})
+}
+
+func (c *Controller) CollectionById() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		collectionId := r.PathValue("id")
+		if collectionId == "" {
+			return infra.NotFoundError
+		}
+
+		switch r.Method {
+		case "GET":
+			collection, err := c.service.CollectionGet(ctx, collectionId)
+			if err != nil {
+				return err
+			}
+
+			collection.Location = fmt.Sprintf("/collection/%s", collection.Id)
+			for idx, s := range collection.Items {
+				collection.Items[idx].Location = fmt.Sprintf("/shader/%s", s.Id)
+			}
+
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			err = json.NewEncoder(w).Encode(collection)
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+			return nil
+		case "PUT":
+			var collectionUpdate models.CollectionPartialUpdate
+			err := json.NewDecoder(r.Body).Decode(&collectionUpdate)
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+
+			collection, err := c.service.CollectionUpdate(ctx, collectionId, collectionUpdate)
+			if err != nil {
+				return err
+			}
+
+			collection.Location = fmt.Sprintf("/collection/%s", collection.Id)
+
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			err = json.NewEncoder(w).Encode(collection)
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+			return nil
+		case "DELETE":
+			err := c.service.CollectionDelete(ctx, collectionId)
+			if err != nil {
+				return err
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		default:
+			return web.NewUnsupportedOperationError("GET", "PUT", "DELETE")
+		}
+	})
+}
+
+// CollectionItems adds a shader to a collection with POST and reorders its shaders with PUT
+func (c *Controller) CollectionItems() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		collectionId := r.PathValue("id")
+		if collectionId == "" {
+			return infra.NotFoundError
+		}
+
+		switch r.Method {
+		case "POST":
+			var itemAdd models.CollectionItemAdd
+			err := json.NewDecoder(r.Body).Decode(&itemAdd)
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+
+			err = c.service.CollectionItemAdd(ctx, collectionId, itemAdd.ShaderId)
+			if err != nil {
+				return err
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		case "PUT":
+			var itemsReorder models.CollectionItemsReorder
+			err := json.NewDecoder(r.Body).Decode(&itemsReorder)
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+
+			err = c.service.CollectionItemsReorder(ctx, collectionId, itemsReorder.ShaderIds)
+			if err != nil {
+				return err
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		default:
+			return web.NewUnsupportedOperationError("POST", "PUT")
+		}
+	})
+}
+
+func (c *Controller) CollectionItemById() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "DELETE" {
+			return web.NewUnsupportedOperationError("DELETE")
+		}
+
+		collectionId := r.PathValue("id")
+		shaderId := r.PathValue("shaderId")
+		if collectionId == "" || shaderId == "" {
+			return infra.NotFoundError
+		}
+
+		err := c.service.CollectionItemRemove(ctx, collectionId, shaderId)
+		if err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+}
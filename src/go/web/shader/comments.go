@@ -0,0 +1,158 @@
+package shader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/sdedovic/wgsltoy-server/src/go/web"
+	"net/http"
+)
+
+func commentLocation(comment models.Comment) string {
+	return fmt.Sprintf("/shader/%s/comments/%s", comment.ShaderId, comment.Id)
+}
+
+func (c *Controller) ShaderComments() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		shaderId := r.PathValue("id")
+		if shaderId == "" {
+			return infra.NotFoundError
+		}
+
+		switch r.Method {
+		case "GET":
+			page, err := web.ParsePageRequest(r)
+			if err != nil {
+				return err
+			}
+
+			comments, err := c.service.CommentList(ctx, shaderId, page)
+			if err != nil {
+				return err
+			}
+
+			for idx, comment := range comments.Items {
+				comments.Items[idx].Location = commentLocation(comment)
+			}
+
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			err = json.NewEncoder(w).Encode(comments)
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+			return nil
+		case "POST":
+			var commentCreate models.CommentCreate
+			err := json.NewDecoder(r.Body).Decode(&commentCreate)
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+
+			commentId, err := c.service.CommentCreate(ctx, shaderId, commentCreate)
+			if err != nil {
+				return err
+			}
+
+			w.Header().Set("Location", fmt.Sprintf("/shader/%s/comments/%s", shaderId, commentId))
+			w.WriteHeader(http.StatusCreated)
+
+			return nil
+		default:
+			return web.NewUnsupportedOperationError("GET", "POST")
+		}
+	})
+}
+
+func (c *Controller) ShaderCommentById() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		shaderId := r.PathValue("id")
+		commentId := r.PathValue("commentId")
+		if shaderId == "" || commentId == "" {
+			return infra.NotFoundError
+		}
+
+		switch r.Method {
+		case "GET":
+			comment, err := c.service.CommentGet(ctx, shaderId, commentId)
+			if err != nil {
+				return err
+			}
+
+			comment.Location = commentLocation(comment)
+
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			err = json.NewEncoder(w).Encode(comment)
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+			return nil
+		case "PUT":
+			var commentUpdate models.CommentUpdate
+			err := json.NewDecoder(r.Body).Decode(&commentUpdate)
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+
+			comment, err := c.service.CommentUpdate(ctx, shaderId, commentId, commentUpdate)
+			if err != nil {
+				return err
+			}
+
+			comment.Location = commentLocation(comment)
+
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			err = json.NewEncoder(w).Encode(comment)
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+			return nil
+		case "DELETE":
+			err := c.service.CommentDelete(ctx, shaderId, commentId)
+			if err != nil {
+				return err
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		default:
+			return web.NewUnsupportedOperationError("GET", "PUT", "DELETE")
+		}
+	})
+}
+
+// ShaderCommentHidden lets the owner of a shader hide a comment with PUT and reveal it again with DELETE
+func (c *Controller) ShaderCommentHidden() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		shaderId := r.PathValue("id")
+		commentId := r.PathValue("commentId")
+		if shaderId == "" || commentId == "" {
+			return infra.NotFoundError
+		}
+
+		var hidden bool
+		switch r.Method {
+		case "PUT":
+			hidden = true
+		case "DELETE":
+			hidden = false
+		default:
+			return web.NewUnsupportedOperationError("PUT", "DELETE")
+		}
+
+		comment, err := c.service.CommentSetHidden(ctx, shaderId, commentId, hidden)
+		if err != nil {
+			return err
+		}
+
+		comment.Location = commentLocation(comment)
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(w).Encode(comment)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+		return nil
+	})
+}
@@ -6,39 +6,113 @@ import (
 	"fmt"
 	"github.com/sdedovic/wgsltoy-server/src/go/infra"
 	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/sdedovic/wgsltoy-server/src/go/patch"
 	"github.com/sdedovic/wgsltoy-server/src/go/service/shader"
 	"github.com/sdedovic/wgsltoy-server/src/go/web"
+	"io"
+	"mime"
 	"net/http"
+	"strconv"
 )
 
 type Controller struct {
 	service shader.IService `di.inject:"ShaderService"`
 }
 
-func (c *Controller) ShaderCreate() http.HandlerFunc {
+// withStrict records on ctx whether the request opted in to strict WGSL validation via the 'strict' parameter
+func withStrict(ctx context.Context, r *http.Request) (context.Context, error) {
+	value := r.URL.Query().Get("strict")
+	if value == "" {
+		return ctx, nil
+	}
+
+	strict, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, infra.NewValidationError("Parameter 'strict' must be a boolean!")
+	}
+	return shader.InsertStrictIntoContext(ctx, strict), nil
+}
+
+func (c *Controller) Shaders() http.HandlerFunc {
 	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-		if r.Method != "POST" {
-			return web.NewUnsupportedOperationError("POST")
-		}
+		switch r.Method {
+		case "GET":
+			page, err := web.ParsePageRequest(r)
+			if err != nil {
+				return err
+			}
 
-		var shaderCreate models.ShaderCreate
-		err := json.NewDecoder(r.Body).Decode(&shaderCreate)
-		if err != nil {
-			return infra.NewJsonParsingError(err)
-		}
+			shaders, err := c.service.ShaderInfoListPublic(ctx, page)
+			if err != nil {
+				return err
+			}
 
-		shaderId, err := c.service.ShaderCreate(ctx, shaderCreate)
-		if err != nil {
-			return err
-		}
+			for idx, s := range shaders.Items {
+				location := fmt.Sprintf("/shader/%s", s.Id)
+				shaders.Items[idx].Location = location
+			}
 
-		w.Header().Set("Location", fmt.Sprintf("/shader/%s", shaderId))
-		w.WriteHeader(http.StatusCreated)
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			err = json.NewEncoder(w).Encode(shaders)
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+			return nil
+		case "POST":
+			ctx, err := withStrict(ctx, r)
+			if err != nil {
+				return err
+			}
 
-		return nil
+			var shaderCreate models.ShaderCreate
+			if isSourceRequest(r) {
+				shaderCreate, err = shaderCreateFromSource(r)
+				if err != nil {
+					return err
+				}
+			} else {
+				err = json.NewDecoder(r.Body).Decode(&shaderCreate)
+				if err != nil {
+					return infra.NewJsonParsingError(err)
+				}
+			}
+
+			shaderId, err := c.service.ShaderCreate(ctx, shaderCreate)
+			if err != nil {
+				return err
+			}
+
+			w.Header().Set("Location", fmt.Sprintf("/shader/%s", shaderId))
+			w.WriteHeader(http.StatusCreated)
+
+			return nil
+		default:
+			return web.NewUnsupportedOperationError("GET", "POST")
+		}
 	})
 }
 
+// readPatch reads the body of a PATCH request as a JSON Merge Patch or a JSON Patch, depending on its Content-Type
+func readPatch(r *http.Request) (patch.Patch, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType = ""
+	}
+	if mediaType != patch.MediaTypeMergePatch && mediaType != patch.MediaTypeJsonPatch {
+		return nil, web.NewUnsupportedMediaTypeError(patch.MediaTypeMergePatch, patch.MediaTypeJsonPatch)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading request body caused by: %w", err)
+	}
+
+	if mediaType == patch.MediaTypeMergePatch {
+		return patch.NewMergePatch(body)
+	}
+	return patch.NewJsonPatch(body)
+}
+
 func (c *Controller) ShaderById() http.HandlerFunc {
 	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 		shaderId := r.PathValue("id")
@@ -48,6 +122,10 @@ func (c *Controller) ShaderById() http.HandlerFunc {
 
 		switch r.Method {
 		case "GET":
+			if token := r.URL.Query().Get("share"); token != "" {
+				ctx = shader.InsertShareTokenIntoContext(ctx, token)
+			}
+
 			shader, err := c.service.ShaderGet(ctx, shaderId)
 			if err != nil {
 				return err
@@ -55,6 +133,27 @@ func (c *Controller) ShaderById() http.HandlerFunc {
 
 			shader.Location = fmt.Sprintf("/shader/%s", shader.Id)
 
+			mediaType := negotiateShaderMediaType(r.Header.Get("Accept"))
+			etag := sourceETag(shader.Version)
+			if mediaType == mediaTypeJson {
+				etag, err = shaderETag(shader)
+				if err != nil {
+					return err
+				}
+			}
+
+			// whether the viewer liked the shader is part of its representation
+			w.Header().Set("Vary", "Accept, Authorization")
+			w.Header().Set("ETag", etag)
+			if matchesIfNoneMatch(r.Header.Get("If-None-Match"), etag) {
+				w.WriteHeader(http.StatusNotModified)
+				return nil
+			}
+
+			if mediaType != mediaTypeJson {
+				return writeSource(w, shader, mediaType)
+			}
+
 			w.Header().Set("Content-Type", "application/json; charset=utf-8")
 			err = json.NewEncoder(w).Encode(shader)
 			if err != nil {
@@ -62,43 +161,140 @@ func (c *Controller) ShaderById() http.HandlerFunc {
 			}
 			return nil
 		case "PUT":
-			var shaderUpdate models.ShaderPartialUpdate
-			err := json.NewDecoder(r.Body).Decode(&shaderUpdate)
+			ctx, err := withStrict(ctx, r)
 			if err != nil {
-				return infra.NewJsonParsingError(err)
+				return err
 			}
+			ctx = shader.InsertIfMatchIntoContext(ctx, parseIfMatch(r.Header.Get("If-Match")))
 
-			shader, err := c.service.ShaderUpdate(ctx, shaderId, shaderUpdate)
+			var shader models.Shader
+			if isSourceRequest(r) {
+				// the raw WGSL representation of a shader is just its content, so only that is replaced
+				shaderPatch, err := shaderPatchFromSource(r)
+				if err != nil {
+					return err
+				}
+
+				shader, err = c.service.ShaderPatch(ctx, shaderId, shaderPatch)
+				if err != nil {
+					return err
+				}
+			} else {
+				var shaderUpdate models.ShaderUpdate
+				err = json.NewDecoder(r.Body).Decode(&shaderUpdate)
+				if err != nil {
+					return infra.NewJsonParsingError(err)
+				}
+
+				shader, err = c.service.ShaderUpdate(ctx, shaderId, shaderUpdate)
+				if err != nil {
+					return err
+				}
+			}
+
+			shader.Location = fmt.Sprintf("/shader/%s", shader.Id)
+			etag, err := shaderETag(shader)
 			if err != nil {
 				return err
 			}
 
+			w.Header().Set("ETag", etag)
 			w.WriteHeader(http.StatusOK)
 			err = json.NewEncoder(w).Encode(shader)
 			if err != nil {
 				return infra.NewJsonParsingError(err)
 			}
 			return nil
+		case "PATCH":
+			ctx, err := withStrict(ctx, r)
+			if err != nil {
+				return err
+			}
+			ctx = shader.InsertIfMatchIntoContext(ctx, parseIfMatch(r.Header.Get("If-Match")))
+
+			shaderPatch, err := readPatch(r)
+			if err != nil {
+				return err
+			}
+
+			shader, err := c.service.ShaderPatch(ctx, shaderId, shaderPatch)
+			if err != nil {
+				return err
+			}
+
+			shader.Location = fmt.Sprintf("/shader/%s", shader.Id)
+			etag, err := shaderETag(shader)
+			if err != nil {
+				return err
+			}
+
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			err = json.NewEncoder(w).Encode(shader)
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+			return nil
+		case "DELETE":
+			err := c.service.ShaderDelete(ctx, shaderId)
+			if err != nil {
+				return err
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+			return nil
 		default:
-			return web.NewUnsupportedOperationError("GET", "PUT")
+			return web.NewUnsupportedOperationError("GET", "PUT", "PATCH", "DELETE")
 		}
 	})
 }
 
+func (c *Controller) ShaderRestore() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "POST" {
+			return web.NewUnsupportedOperationError("POST")
+		}
+
+		shaderId := r.PathValue("id")
+		if shaderId == "" {
+			return infra.NotFoundError
+		}
+
+		shader, err := c.service.ShaderRestore(ctx, shaderId)
+		if err != nil {
+			return err
+		}
+
+		shader.Location = fmt.Sprintf("/shader/%s", shader.Id)
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(w).Encode(shader)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+		return nil
+	})
+}
+
 func (c *Controller) ShaderInfoListOwn() http.HandlerFunc {
 	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 		if r.Method != "GET" {
 			return web.NewUnsupportedOperationError("GET")
 		}
 
-		shaders, err := c.service.ShaderInfoListCurrentUser(ctx)
+		page, err := web.ParsePageRequest(r)
+		if err != nil {
+			return err
+		}
+
+		shaders, err := c.service.ShaderInfoListCurrentUser(ctx, page)
 		if err != nil {
 			return err
 		}
 
-		for idx, s := range shaders {
+		for idx, s := range shaders.Items {
 			location := fmt.Sprintf("/shader/%s", s.Id)
-			shaders[idx].Location = location
+			shaders.Items[idx].Location = location
 		}
 
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
@@ -109,3 +305,309 @@ func (c *Controller) ShaderInfoListOwn() http.HandlerFunc {
 		return nil
 	})
 }
+
+func (c *Controller) ShaderInfoListByUser() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "GET" {
+			return web.NewUnsupportedOperationError("GET")
+		}
+
+		username := r.PathValue("username")
+		if username == "" {
+			return infra.NotFoundError
+		}
+
+		page, err := web.ParsePageRequest(r)
+		if err != nil {
+			return err
+		}
+
+		shaders, err := c.service.ShaderInfoListByUsername(ctx, username, page)
+		if err != nil {
+			return err
+		}
+
+		for idx, s := range shaders.Items {
+			location := fmt.Sprintf("/shader/%s", s.Id)
+			shaders.Items[idx].Location = location
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(w).Encode(shaders)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+		return nil
+	})
+}
+
+func (c *Controller) ShaderInfoListLikes() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "GET" {
+			return web.NewUnsupportedOperationError("GET")
+		}
+
+		page, err := web.ParsePageRequest(r)
+		if err != nil {
+			return err
+		}
+
+		shaders, err := c.service.ShaderInfoListLikesCurrentUser(ctx, page)
+		if err != nil {
+			return err
+		}
+
+		for idx, s := range shaders.Items {
+			location := fmt.Sprintf("/shader/%s", s.Id)
+			shaders.Items[idx].Location = location
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(w).Encode(shaders)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+		return nil
+	})
+}
+
+func (c *Controller) ShaderInfoListTrash() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "GET" {
+			return web.NewUnsupportedOperationError("GET")
+		}
+
+		page, err := web.ParsePageRequest(r)
+		if err != nil {
+			return err
+		}
+
+		shaders, err := c.service.ShaderInfoListTrashCurrentUser(ctx, page)
+		if err != nil {
+			return err
+		}
+
+		for idx, s := range shaders.Items {
+			location := fmt.Sprintf("/shader/%s", s.Id)
+			shaders.Items[idx].Location = location
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(w).Encode(shaders)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+		return nil
+	})
+}
+
+func (c *Controller) ShaderRevisionList() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "GET" {
+			return web.NewUnsupportedOperationError("GET")
+		}
+
+		shaderId := r.PathValue("id")
+		if shaderId == "" {
+			return infra.NotFoundError
+		}
+
+		revisions, err := c.service.ShaderRevisionList(ctx, shaderId)
+		if err != nil {
+			return err
+		}
+
+		for idx, revision := range revisions {
+			revisions[idx].Location = fmt.Sprintf("/shader/%s/revision/%d", shaderId, revision.Revision)
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(w).Encode(revisions)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+		return nil
+	})
+}
+
+func (c *Controller) ShaderRevisionById() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "GET" {
+			return web.NewUnsupportedOperationError("GET")
+		}
+
+		shaderId := r.PathValue("id")
+		if shaderId == "" {
+			return infra.NotFoundError
+		}
+
+		revisionNumber, err := strconv.Atoi(r.PathValue("revision"))
+		if err != nil {
+			return infra.NotFoundError
+		}
+
+		revision, err := c.service.ShaderRevisionGet(ctx, shaderId, revisionNumber)
+		if err != nil {
+			return err
+		}
+
+		revision.Location = fmt.Sprintf("/shader/%s/revision/%d", shaderId, revision.Revision)
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(w).Encode(revision)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+		return nil
+	})
+}
+
+func (c *Controller) ShaderRevert() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "POST" {
+			return web.NewUnsupportedOperationError("POST")
+		}
+
+		shaderId := r.PathValue("id")
+		if shaderId == "" {
+			return infra.NotFoundError
+		}
+
+		revisionNumber, err := strconv.Atoi(r.PathValue("revision"))
+		if err != nil {
+			return infra.NotFoundError
+		}
+
+		shader, err := c.service.ShaderRevert(ctx, shaderId, revisionNumber)
+		if err != nil {
+			return err
+		}
+
+		shader.Location = fmt.Sprintf("/shader/%s", shader.Id)
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(w).Encode(shader)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+		return nil
+	})
+}
+
+func (c *Controller) ShaderFork() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "POST" {
+			return web.NewUnsupportedOperationError("POST")
+		}
+
+		shaderId := r.PathValue("id")
+		if shaderId == "" {
+			return infra.NotFoundError
+		}
+
+		if token := r.URL.Query().Get("share"); token != "" {
+			ctx = shader.InsertShareTokenIntoContext(ctx, token)
+		}
+
+		forkId, err := c.service.ShaderFork(ctx, shaderId)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Location", fmt.Sprintf("/shader/%s", forkId))
+		w.WriteHeader(http.StatusCreated)
+
+		return nil
+	})
+}
+
+func (c *Controller) ShaderLike() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		shaderId := r.PathValue("id")
+		if shaderId == "" {
+			return infra.NotFoundError
+		}
+
+		var err error
+		switch r.Method {
+		case "PUT":
+			err = c.service.ShaderLike(ctx, shaderId)
+		case "DELETE":
+			err = c.service.ShaderUnlike(ctx, shaderId)
+		default:
+			return web.NewUnsupportedOperationError("PUT", "DELETE")
+		}
+		if err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+}
+
+func (c *Controller) ShaderInfoListForks() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "GET" {
+			return web.NewUnsupportedOperationError("GET")
+		}
+
+		shaderId := r.PathValue("id")
+		if shaderId == "" {
+			return infra.NotFoundError
+		}
+
+		page, err := web.ParsePageRequest(r)
+		if err != nil {
+			return err
+		}
+
+		shaders, err := c.service.ShaderInfoListForks(ctx, shaderId, page)
+		if err != nil {
+			return err
+		}
+
+		for idx, s := range shaders.Items {
+			location := fmt.Sprintf("/shader/%s", s.Id)
+			shaders.Items[idx].Location = location
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(w).Encode(shaders)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+		return nil
+	})
+}
+
+func (c *Controller) ShaderSearch() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "GET" {
+			return web.NewUnsupportedOperationError("GET")
+		}
+
+		page, err := web.ParsePageRequest(r)
+		if err != nil {
+			return err
+		}
+
+		query := r.URL.Query()
+		results, err := c.service.ShaderSearch(ctx, query.Get("q"), query["tag"], page)
+		if err != nil {
+			return err
+		}
+
+		for idx, s := range results.Items {
+			location := fmt.Sprintf("/shader/%s", s.Id)
+			results.Items[idx].Location = location
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(w).Encode(results)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,76 @@
+package shader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"strconv"
+	"strings"
+)
+
+// shaderETag is the strong entity tag of the JSON representation of shader. The like count, the attribution and whether
+// the viewer liked the shader change without a new version, so a digest of the representation follows the version.
+func shaderETag(shader models.Shader) (string, error) {
+	body, err := json.Marshal(shader)
+	if err != nil {
+		return "", infra.NewJsonParsingError(err)
+	}
+
+	digest := sha256.Sum256(body)
+	return fmt.Sprintf(`"%d-%s"`, shader.Version, hex.EncodeToString(digest[:8])), nil
+}
+
+// sourceETag is the strong entity tag of the raw WGSL representation of a shader at version. It holds only the name and
+// content of the shader, which never change without a new version.
+func sourceETag(version int) string {
+	return fmt.Sprintf(`"%d%s"`, version, sourceETagSuffix)
+}
+
+const sourceETagSuffix = "-source"
+
+// parseIfMatch returns the shader versions an If-Match header permits, nil when it places no condition on the update.
+// Only the version of each entity tag is compared, as the parts of a shader that change without one cannot be updated.
+// Weak and malformed entity tags never match, so they yield an empty rather than a nil slice.
+func parseIfMatch(header string) []int {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil
+	}
+
+	versions := make([]int, 0)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) || len(tag) < 2 {
+			continue
+		}
+
+		version, _, _ := strings.Cut(tag[1:len(tag)-1], "-")
+		parsed, err := strconv.Atoi(version)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, parsed)
+	}
+	return versions
+}
+
+// matchesIfNoneMatch reports whether etag is listed in an If-None-Match header, using the weak comparison
+func matchesIfNoneMatch(header string, etag string) bool {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
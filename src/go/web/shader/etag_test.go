@@ -0,0 +1,51 @@
+package shader
+
+import (
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestShaderETag(t *testing.T) {
+	shader := models.Shader{Id: "shader", Version: 3, LikeCount: 1}
+	etag, err := shaderETag(shader)
+	assert.NoError(t, err)
+	assert.Regexp(t, `^"3-[0-9a-f]{16}"$`, etag)
+
+	same, err := shaderETag(shader)
+	assert.NoError(t, err)
+	assert.Equal(t, etag, same)
+
+	// liking a shader does not bump its version, but still changes its representation
+	shader.LikeCount = 2
+	liked, err := shaderETag(shader)
+	assert.NoError(t, err)
+	assert.NotEqual(t, etag, liked)
+
+	shader.LikedByMe = true
+	likedByMe, err := shaderETag(shader)
+	assert.NoError(t, err)
+	assert.NotEqual(t, liked, likedByMe)
+
+	assert.Equal(t, []int{3}, parseIfMatch(etag))
+}
+
+func TestParseIfMatch(t *testing.T) {
+	assert.Nil(t, parseIfMatch(""))
+	assert.Nil(t, parseIfMatch("*"))
+	assert.Equal(t, []int{3}, parseIfMatch(`"3"`))
+	assert.Equal(t, []int{3, 4}, parseIfMatch(`"3", "4-0123456789abcdef"`))
+	assert.Equal(t, []int{3}, parseIfMatch(sourceETag(3)))
+	assert.Equal(t, []int{}, parseIfMatch(`W/"3"`))
+	assert.Equal(t, []int{}, parseIfMatch(`"three"`))
+}
+
+func TestMatchesIfNoneMatch(t *testing.T) {
+	etag := sourceETag(3)
+
+	assert.False(t, matchesIfNoneMatch("", etag))
+	assert.True(t, matchesIfNoneMatch("*", etag))
+	assert.True(t, matchesIfNoneMatch(`"3-source"`, etag))
+	assert.True(t, matchesIfNoneMatch(`"2-source", W/"3-source"`, etag))
+	assert.False(t, matchesIfNoneMatch(`"3"`, etag))
+}
@@ -0,0 +1,117 @@
+package shader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/sdedovic/wgsltoy-server/src/go/web"
+	"net/http"
+)
+
+func (c *Controller) ShaderShareLinks() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		shaderId := r.PathValue("id")
+		if shaderId == "" {
+			return infra.NotFoundError
+		}
+
+		switch r.Method {
+		case "GET":
+			shareLinks, err := c.service.ShareLinkList(ctx, shaderId)
+			if err != nil {
+				return err
+			}
+
+			for idx, shareLink := range shareLinks {
+				shareLinks[idx].Location = fmt.Sprintf("/shader/%s/share-links/%s", shaderId, shareLink.Id)
+			}
+
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			err = json.NewEncoder(w).Encode(shareLinks)
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+			return nil
+		case "POST":
+			var shareLinkCreate models.ShareLinkCreate
+			err := json.NewDecoder(r.Body).Decode(&shareLinkCreate)
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+
+			shareLink, err := c.service.ShareLinkCreate(ctx, shaderId, shareLinkCreate)
+			if err != nil {
+				return err
+			}
+
+			shareLink.Location = fmt.Sprintf("/shader/%s/share-links/%s", shaderId, shareLink.Id)
+
+			w.Header().Set("Location", shareLink.Location)
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusCreated)
+			err = json.NewEncoder(w).Encode(shareLink)
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+			return nil
+		default:
+			return web.NewUnsupportedOperationError("GET", "POST")
+		}
+	})
+}
+
+func (c *Controller) ShaderShareLinkById() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "DELETE" {
+			return web.NewUnsupportedOperationError("DELETE")
+		}
+
+		shaderId := r.PathValue("id")
+		linkId := r.PathValue("linkId")
+		if shaderId == "" || linkId == "" {
+			return infra.NotFoundError
+		}
+
+		err := c.service.ShareLinkRevoke(ctx, shaderId, linkId)
+		if err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+}
+
+// ShaderByShareToken returns the shader a share link was minted for. The token is a credential, so the response is
+// neither cached nor is the URL leaked to other sites via the referrer.
+func (c *Controller) ShaderByShareToken() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "GET" {
+			return web.NewUnsupportedOperationError("GET")
+		}
+
+		token := r.PathValue("token")
+		if token == "" {
+			return infra.NotFoundError
+		}
+
+		shader, err := c.service.ShaderGetByShareToken(ctx, token)
+		if err != nil {
+			return err
+		}
+
+		shader.Location = fmt.Sprintf("/shader/%s", shader.Id)
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "private, no-store")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		err = json.NewEncoder(w).Encode(shader)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,155 @@
+package shader
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/sdedovic/wgsltoy-server/src/go/patch"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const mediaTypeJson = "application/json"
+const mediaTypeWgsl = "text/wgsl"
+const mediaTypePlain = "text/plain"
+
+// negotiateShaderMediaType picks the representation of a shader best matching an Accept header, preferring JSON when
+// the client has no preference
+func negotiateShaderMediaType(accept string) string {
+	if strings.TrimSpace(accept) == "" {
+		return mediaTypeJson
+	}
+
+	best, bestQuality := mediaTypeJson, 0.0
+	for _, offered := range []string{mediaTypeJson, mediaTypeWgsl, mediaTypePlain} {
+		quality := acceptQuality(accept, offered)
+		if quality > bestQuality {
+			best, bestQuality = offered, quality
+		}
+	}
+	return best
+}
+
+// acceptQuality is the quality an Accept header assigns to mediaType, taken from the most specific range matching it
+func acceptQuality(accept string, mediaType string) float64 {
+	mainType, _, _ := strings.Cut(mediaType, "/")
+
+	quality, specificity := 0.0, -1
+	for _, mediaRange := range strings.Split(accept, ",") {
+		rangeType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+
+		var rangeSpecificity int
+		switch rangeType {
+		case mediaType:
+			rangeSpecificity = 2
+		case mainType + "/*":
+			rangeSpecificity = 1
+		case "*/*":
+			rangeSpecificity = 0
+		default:
+			continue
+		}
+		if rangeSpecificity <= specificity {
+			continue
+		}
+
+		rangeQuality := 1.0
+		if q, ok := params["q"]; ok {
+			rangeQuality, err = strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+		}
+		quality, specificity = rangeQuality, rangeSpecificity
+	}
+	return quality
+}
+
+// isSourceRequest reports whether the request body is raw WGSL rather than JSON
+func isSourceRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && (mediaType == mediaTypeWgsl || mediaType == mediaTypePlain)
+}
+
+func readSource(r *http.Request) (string, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed reading request body caused by: %w", err)
+	}
+	if !utf8.Valid(body) {
+		return "", infra.NewValidationError("Request body is not valid UTF-8!")
+	}
+	return string(body), nil
+}
+
+// shaderCreateFromSource reads a shader sent as raw WGSL, with the rest of its fields supplied as query parameters
+func shaderCreateFromSource(r *http.Request) (models.ShaderCreate, error) {
+	content, err := readSource(r)
+	if err != nil {
+		return models.ShaderCreate{}, err
+	}
+
+	query := r.URL.Query()
+	return models.ShaderCreate{
+		Name:        query.Get("name"),
+		Visibility:  query.Get("visibility"),
+		Description: query.Get("description"),
+		Tags:        query["tag"],
+		Content:     content,
+	}, nil
+}
+
+// shaderPatchFromSource reads new content sent as raw WGSL as a patch of the shader. Fields supplied as query parameters
+// are changed as well, the others are left as they are.
+func shaderPatchFromSource(r *http.Request) (patch.Patch, error) {
+	content, err := readSource(r)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]any{"content": content}
+	query := r.URL.Query()
+	for _, field := range []string{"name", "visibility", "description"} {
+		if query.Has(field) {
+			changes[field] = query.Get(field)
+		}
+	}
+	if query.Has("tag") {
+		changes["tags"] = query["tag"]
+	}
+
+	document, err := json.Marshal(changes)
+	if err != nil {
+		return nil, fmt.Errorf("failed serializing patch caused by: %w", err)
+	}
+	return patch.NewMergePatch(document)
+}
+
+// writeSource writes just the content of a shader, as a download named after it
+func writeSource(w http.ResponseWriter, shader models.Shader, mediaType string) error {
+	filename := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(shader.Name))
+	if filename == "" {
+		filename = shader.Id
+	}
+
+	w.Header().Set("Content-Type", mime.FormatMediaType(mediaType, map[string]string{"charset": "utf-8"}))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename + ".wgsl"}))
+	_, err := io.WriteString(w, shader.Content)
+	if err != nil {
+		return fmt.Errorf("failed writing response caused by: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package shader
+
+import (
+	"github.com/sdedovic/wgsltoy-server/src/go/models"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateShaderMediaType(t *testing.T) {
+	assert.Equal(t, mediaTypeJson, negotiateShaderMediaType(""))
+	assert.Equal(t, mediaTypeJson, negotiateShaderMediaType("*/*"))
+	assert.Equal(t, mediaTypeJson, negotiateShaderMediaType("image/png"))
+	assert.Equal(t, mediaTypeWgsl, negotiateShaderMediaType("text/wgsl"))
+	assert.Equal(t, mediaTypePlain, negotiateShaderMediaType("text/plain"))
+	assert.Equal(t, mediaTypeWgsl, negotiateShaderMediaType("text/*"))
+	assert.Equal(t, mediaTypeJson, negotiateShaderMediaType("text/wgsl;q=0.5, application/json"))
+	assert.Equal(t, mediaTypeWgsl, negotiateShaderMediaType("text/wgsl, */*;q=0.1"))
+	assert.Equal(t, mediaTypePlain, negotiateShaderMediaType("text/*, text/wgsl;q=0"))
+}
+
+func TestShaderPatchFromSource(t *testing.T) {
+	r := httptest.NewRequest("PUT", "/shader/id?name=renamed&tag=one&tag=two", strings.NewReader("fn main() {}"))
+
+	shaderPatch, err := shaderPatchFromSource(r)
+	assert.NoError(t, err)
+
+	patched, err := shaderPatch.Apply([]byte(`{"name":"shader","description":"kept","tags":[],"content":""}`))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"renamed","description":"kept","tags":["one","two"],"content":"fn main() {}"}`, string(patched))
+}
+
+func TestWriteSource(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	err := writeSource(w, models.Shader{Id: "id", Name: "plasma/waves", Content: "fn main() {}"}, mediaTypeWgsl)
+	assert.NoError(t, err)
+	assert.Equal(t, "text/wgsl; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=plasma_waves.wgsl`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "fn main() {}", w.Body.String())
+}
@@ -3,6 +3,7 @@ package user
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/sdedovic/wgsltoy-server/src/go/infra"
 	"github.com/sdedovic/wgsltoy-server/src/go/models"
 	"github.com/sdedovic/wgsltoy-server/src/go/service/user"
@@ -49,16 +50,17 @@ func (c *Controller) UserLogin() http.HandlerFunc {
 			return infra.NewJsonParsingError(err)
 		}
 
-		jwt, err := c.service.Login(ctx, userLogin.Username, userLogin.Password)
+		tokens, err := c.service.Login(ctx, userLogin.Username, userLogin.Password, r.UserAgent())
 		if err != nil {
 			return err
 		}
 
-		_, err = w.Write([]byte(jwt))
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		err = json.NewEncoder(w).Encode(tokens)
 		if err != nil {
-			return err
+			return infra.NewJsonParsingError(err)
 		}
-
 		return nil
 	})
 }
@@ -72,6 +74,24 @@ func (c *Controller) UserMe() http.HandlerFunc {
 				return err
 			}
 
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			err = json.NewEncoder(w).Encode(currentUser)
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+			return nil
+		case "PUT":
+			var profileUpdate models.UserProfileUpdate
+			err := json.NewDecoder(r.Body).Decode(&profileUpdate)
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+
+			currentUser, err := c.service.UpdateProfile(ctx, profileUpdate)
+			if err != nil {
+				return err
+			}
+
 			w.Header().Set("Content-Type", "application/json; charset=utf-8")
 			err = json.NewEncoder(w).Encode(currentUser)
 			if err != nil {
@@ -79,7 +99,298 @@ func (c *Controller) UserMe() http.HandlerFunc {
 			}
 			return nil
 		default:
+			return web.NewUnsupportedOperationError("GET", "PUT")
+		}
+	})
+}
+
+func (c *Controller) UserProfile() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "GET" {
 			return web.NewUnsupportedOperationError("GET")
 		}
+
+		username := r.PathValue("username")
+		if username == "" {
+			return infra.NotFoundError
+		}
+
+		profile, err := c.service.GetProfile(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(w).Encode(profile)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+		return nil
+	})
+}
+
+func (c *Controller) UserVerifyEmail() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "POST" {
+			return web.NewUnsupportedOperationError("POST")
+		}
+
+		var userVerifyEmail models.UserVerifyEmail
+		err := json.NewDecoder(r.Body).Decode(&userVerifyEmail)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+
+		err = c.service.VerifyEmail(ctx, userVerifyEmail.Token)
+		if err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+}
+
+func (c *Controller) UserVerifyEmailResend() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "POST" {
+			return web.NewUnsupportedOperationError("POST")
+		}
+
+		err := c.service.ResendVerification(ctx)
+		if err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+}
+
+func (c *Controller) UserPasswordForgot() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "POST" {
+			return web.NewUnsupportedOperationError("POST")
+		}
+
+		var userPasswordForgot models.UserPasswordForgot
+		err := json.NewDecoder(r.Body).Decode(&userPasswordForgot)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+
+		err = c.service.PasswordForgot(ctx, userPasswordForgot.Email)
+		if err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+}
+
+func (c *Controller) UserPasswordReset() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "POST" {
+			return web.NewUnsupportedOperationError("POST")
+		}
+
+		var userPasswordReset models.UserPasswordReset
+		err := json.NewDecoder(r.Body).Decode(&userPasswordReset)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+
+		err = c.service.PasswordReset(ctx, userPasswordReset.Token, userPasswordReset.Password)
+		if err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+}
+
+func (c *Controller) UserMePassword() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "POST" {
+			return web.NewUnsupportedOperationError("POST")
+		}
+
+		var userPasswordChange models.UserPasswordChange
+		err := json.NewDecoder(r.Body).Decode(&userPasswordChange)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+
+		err = c.service.PasswordChange(ctx, userPasswordChange.CurrentPassword, userPasswordChange.NewPassword)
+		if err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+}
+
+func (c *Controller) UserTokenRefresh() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "POST" {
+			return web.NewUnsupportedOperationError("POST")
+		}
+
+		var userTokenRefresh models.UserTokenRefresh
+		err := json.NewDecoder(r.Body).Decode(&userTokenRefresh)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+
+		tokens, err := c.service.Refresh(ctx, userTokenRefresh.RefreshToken)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		err = json.NewEncoder(w).Encode(tokens)
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+		return nil
+	})
+}
+
+func (c *Controller) UserLogout() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "POST" {
+			return web.NewUnsupportedOperationError("POST")
+		}
+
+		err := c.service.Logout(ctx)
+		if err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+}
+
+func (c *Controller) UserMeSessions() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		switch r.Method {
+		case "GET":
+			sessions, err := c.service.SessionList(ctx)
+			if err != nil {
+				return err
+			}
+
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			err = json.NewEncoder(w).Encode(sessions)
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+			return nil
+		case "DELETE":
+			err := c.service.SessionRevokeOthers(ctx)
+			if err != nil {
+				return err
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		default:
+			return web.NewUnsupportedOperationError("GET", "DELETE")
+		}
+	})
+}
+
+func (c *Controller) UserMeSessionById() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "DELETE" {
+			return web.NewUnsupportedOperationError("DELETE")
+		}
+
+		sessionId := r.PathValue("id")
+		if sessionId == "" {
+			return infra.NotFoundError
+		}
+
+		err := c.service.SessionRevoke(ctx, sessionId)
+		if err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+}
+
+func (c *Controller) UserMeTokens() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		switch r.Method {
+		case "GET":
+			accessTokens, err := c.service.AccessTokenList(ctx)
+			if err != nil {
+				return err
+			}
+
+			for idx, accessToken := range accessTokens {
+				accessTokens[idx].Location = fmt.Sprintf("/user/me/tokens/%s", accessToken.Id)
+			}
+
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			err = json.NewEncoder(w).Encode(accessTokens)
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+			return nil
+		case "POST":
+			var accessTokenCreate models.AccessTokenCreate
+			err := json.NewDecoder(r.Body).Decode(&accessTokenCreate)
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+
+			accessToken, err := c.service.AccessTokenCreate(ctx, accessTokenCreate)
+			if err != nil {
+				return err
+			}
+
+			accessToken.Location = fmt.Sprintf("/user/me/tokens/%s", accessToken.Id)
+
+			w.Header().Set("Location", accessToken.Location)
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusCreated)
+			err = json.NewEncoder(w).Encode(accessToken)
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+			return nil
+		default:
+			return web.NewUnsupportedOperationError("GET", "POST")
+		}
+	})
+}
+
+func (c *Controller) UserMeTokenById() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "DELETE" {
+			return web.NewUnsupportedOperationError("DELETE")
+		}
+
+		tokenId := r.PathValue("id")
+		if tokenId == "" {
+			return infra.NotFoundError
+		}
+
+		err := c.service.AccessTokenRevoke(ctx, tokenId)
+		if err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return nil
 	})
 }
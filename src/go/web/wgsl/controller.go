@@ -0,0 +1,73 @@
+package wgsl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/sdedovic/wgsltoy-server/src/go/infra"
+	"github.com/sdedovic/wgsltoy-server/src/go/web"
+	"github.com/sdedovic/wgsltoy-server/src/go/wgsl"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// maxSourceBytes bounds the request body accepted for validation
+const maxSourceBytes = 64 * 1024
+
+type ValidateRequest struct {
+	Content string `json:"content"`
+}
+
+type ValidateResponse struct {
+	Valid       bool              `json:"valid"`
+	Diagnostics []wgsl.Diagnostic `json:"diagnostics"`
+}
+
+// Validate parses the WGSL source in the request body, which is either sent as is or as the 'content' field of a JSON
+// object, and responds with the problems found
+func Validate() http.HandlerFunc {
+	return web.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "POST" {
+			return web.NewUnsupportedOperationError("POST")
+		}
+
+		body := http.MaxBytesReader(w, r.Body, maxSourceBytes)
+		var maxBytesError *http.MaxBytesError
+
+		var source string
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == "application/json" {
+			var request ValidateRequest
+			err := json.NewDecoder(body).Decode(&request)
+			if errors.As(err, &maxBytesError) {
+				return web.NewRequestTooLargeError(maxSourceBytes)
+			}
+			if err != nil {
+				return infra.NewJsonParsingError(err)
+			}
+			source = request.Content
+		} else {
+			raw, err := io.ReadAll(body)
+			if errors.As(err, &maxBytesError) {
+				return web.NewRequestTooLargeError(maxSourceBytes)
+			}
+			if err != nil {
+				return err
+			}
+			source = string(raw)
+		}
+
+		_, diagnostics := wgsl.Parse(source)
+		if diagnostics == nil {
+			diagnostics = []wgsl.Diagnostic{}
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err := json.NewEncoder(w).Encode(ValidateResponse{len(diagnostics) == 0, diagnostics})
+		if err != nil {
+			return infra.NewJsonParsingError(err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,33 @@
+package wgsl
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidate_TooLarge(t *testing.T) {
+	content := strings.Repeat("// padding\n", maxSourceBytes/10)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"raw", "text/wgsl", content},
+		{"json", "application/json", `{"content":"` + strings.ReplaceAll(content, "\n", `\n`) + `"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/wgsl/validate", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			Validate()(w, r)
+
+			assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+			assert.JSONEq(t, `{"errorClass":"REQUEST_TOO_LARGE","causedBy":"Request body must not exceed 65536 bytes."}`, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package wgsl
+
+// Node is any element of the syntax tree
+type Node interface {
+	Pos() Position
+}
+
+// Module is the root of the syntax tree, a whole WGSL translation unit
+type Module struct {
+	Directives []*Directive
+	Decls      []Decl
+}
+
+// Directive is an 'enable', 'requires' or 'diagnostic' directive at the top of a module
+type Directive struct {
+	Start Position
+	Kind  string
+	Args  []string
+}
+
+func (n *Directive) Pos() Position { return n.Start }
+
+// Attribute is an '@name' or '@name(args)' annotation
+type Attribute struct {
+	Start Position
+	Name  string
+	Args  []Expr
+}
+
+func (n *Attribute) Pos() Position { return n.Start }
+
+//==== Declarations ====\\
+
+// Decl is a module scope declaration
+type Decl interface {
+	Node
+	declNode()
+}
+
+// VarDecl declares a 'var', 'let', 'const' or 'override', at module scope or inside a function. Type and Value are
+// nil when omitted.
+type VarDecl struct {
+	Start      Position
+	Attributes []*Attribute
+	Kind       string
+	Template   []Expr
+	Name       string
+	Type       *Ident
+	Value      Expr
+}
+
+// AliasDecl declares a named alias of a type
+type AliasDecl struct {
+	Start Position
+	Name  string
+	Type  *Ident
+}
+
+// StructDecl declares a structure type
+type StructDecl struct {
+	Start   Position
+	Name    string
+	Members []*StructMember
+}
+
+// StructMember is a single member of a structure type
+type StructMember struct {
+	Start      Position
+	Attributes []*Attribute
+	Name       string
+	Type       *Ident
+}
+
+// FuncDecl declares a function. ReturnType is nil for functions without a return value.
+type FuncDecl struct {
+	Start            Position
+	Attributes       []*Attribute
+	Name             string
+	Params           []*Param
+	ReturnAttributes []*Attribute
+	ReturnType       *Ident
+	Body             *BlockStmt
+}
+
+// Param is a single formal parameter of a function
+type Param struct {
+	Start      Position
+	Attributes []*Attribute
+	Name       string
+	Type       *Ident
+}
+
+// ConstAssertDecl is a 'const_assert', at module scope or inside a function
+type ConstAssertDecl struct {
+	Start Position
+	Cond  Expr
+}
+
+func (n *VarDecl) Pos() Position         { return n.Start }
+func (n *AliasDecl) Pos() Position       { return n.Start }
+func (n *StructDecl) Pos() Position      { return n.Start }
+func (n *StructMember) Pos() Position    { return n.Start }
+func (n *FuncDecl) Pos() Position        { return n.Start }
+func (n *Param) Pos() Position           { return n.Start }
+func (n *ConstAssertDecl) Pos() Position { return n.Start }
+
+func (*VarDecl) declNode()         {}
+func (*AliasDecl) declNode()       {}
+func (*StructDecl) declNode()      {}
+func (*FuncDecl) declNode()        {}
+func (*ConstAssertDecl) declNode() {}
+
+//==== Statements ====\\
+
+// Stmt is a statement inside a function body
+type Stmt interface {
+	Node
+	stmtNode()
+}
+
+// BlockStmt is a brace delimited sequence of statements
+type BlockStmt struct {
+	Start      Position
+	Attributes []*Attribute
+	Stmts      []Stmt
+}
+
+// ReturnStmt returns from a function, Value is nil for functions without a return value
+type ReturnStmt struct {
+	Start Position
+	Value Expr
+}
+
+// IfStmt is an 'if' with an optional 'else', which is either another *IfStmt or a *BlockStmt
+type IfStmt struct {
+	Start      Position
+	Attributes []*Attribute
+	Cond       Expr
+	Body       *BlockStmt
+	Else       Stmt
+}
+
+// SwitchStmt selects one of its clauses by value
+type SwitchStmt struct {
+	Start      Position
+	Attributes []*Attribute
+	Value      Expr
+	Clauses    []*CaseClause
+}
+
+// CaseClause is a single 'case' or 'default' clause of a switch. A nil selector stands for 'default'.
+type CaseClause struct {
+	Start     Position
+	Selectors []Expr
+	Body      *BlockStmt
+}
+
+// LoopStmt repeats its body until broken out of, Continuing is nil when omitted
+type LoopStmt struct {
+	Start      Position
+	Attributes []*Attribute
+	Body       *BlockStmt
+	Continuing *ContinuingStmt
+}
+
+// ContinuingStmt is the 'continuing' block of a loop, BreakIf is nil when omitted
+type ContinuingStmt struct {
+	Start   Position
+	Body    *BlockStmt
+	BreakIf Expr
+}
+
+// ForStmt is a 'for' loop, any of Init, Cond and Update may be nil
+type ForStmt struct {
+	Start      Position
+	Attributes []*Attribute
+	Init       Stmt
+	Cond       Expr
+	Update     Stmt
+	Body       *BlockStmt
+}
+
+// WhileStmt is a 'while' loop
+type WhileStmt struct {
+	Start      Position
+	Attributes []*Attribute
+	Cond       Expr
+	Body       *BlockStmt
+}
+
+// BranchStmt is one of 'break', 'continue' or 'discard'
+type BranchStmt struct {
+	Start Position
+	Kind  string
+}
+
+// DeclStmt declares a variable or value, or asserts a constant expression, inside a function
+type DeclStmt struct {
+	Decl Decl
+}
+
+// AssignStmt is a simple or compound assignment. Target is nil for the phony assignment '_ = value'.
+type AssignStmt struct {
+	Start  Position
+	Op     string
+	Target Expr
+	Value  Expr
+}
+
+// IncDecStmt is an increment '++' or decrement '--'
+type IncDecStmt struct {
+	Start  Position
+	Op     string
+	Target Expr
+}
+
+// CallStmt is a function call whose result, if any, is discarded
+type CallStmt struct {
+	Call *CallExpr
+}
+
+// EmptyStmt is a lone ';'
+type EmptyStmt struct {
+	Start Position
+}
+
+func (n *BlockStmt) Pos() Position      { return n.Start }
+func (n *ReturnStmt) Pos() Position     { return n.Start }
+func (n *IfStmt) Pos() Position         { return n.Start }
+func (n *SwitchStmt) Pos() Position     { return n.Start }
+func (n *CaseClause) Pos() Position     { return n.Start }
+func (n *LoopStmt) Pos() Position       { return n.Start }
+func (n *ContinuingStmt) Pos() Position { return n.Start }
+func (n *ForStmt) Pos() Position        { return n.Start }
+func (n *WhileStmt) Pos() Position      { return n.Start }
+func (n *BranchStmt) Pos() Position     { return n.Start }
+func (n *DeclStmt) Pos() Position       { return n.Decl.Pos() }
+func (n *AssignStmt) Pos() Position     { return n.Start }
+func (n *IncDecStmt) Pos() Position     { return n.Start }
+func (n *CallStmt) Pos() Position       { return n.Call.Pos() }
+func (n *EmptyStmt) Pos() Position      { return n.Start }
+
+func (*BlockStmt) stmtNode()      {}
+func (*ReturnStmt) stmtNode()     {}
+func (*IfStmt) stmtNode()         {}
+func (*SwitchStmt) stmtNode()     {}
+func (*LoopStmt) stmtNode()       {}
+func (*ContinuingStmt) stmtNode() {}
+func (*ForStmt) stmtNode()        {}
+func (*WhileStmt) stmtNode()      {}
+func (*BranchStmt) stmtNode()     {}
+func (*DeclStmt) stmtNode()       {}
+func (*AssignStmt) stmtNode()     {}
+func (*IncDecStmt) stmtNode()     {}
+func (*CallStmt) stmtNode()       {}
+func (*EmptyStmt) stmtNode()      {}
+
+//==== Expressions ====\\
+
+// Expr is an expression. Types are expressions as well, see Ident.
+type Expr interface {
+	Node
+	exprNode()
+}
+
+// Ident refers to a declaration by name, optionally followed by a template list as in 'vec3<f32>'
+type Ident struct {
+	Start        Position
+	Name         string
+	TemplateArgs []Expr
+}
+
+// LiteralExpr is a boolean, integer or float literal
+type LiteralExpr struct {
+	Start Position
+	Kind  TokenKind
+	Value string
+}
+
+// CallExpr is a function call or a value constructor such as 'vec3<f32>(1.0)'
+type CallExpr struct {
+	Callee *Ident
+	Args   []Expr
+}
+
+// ParenExpr is an expression in parentheses
+type ParenExpr struct {
+	Start Position
+	X     Expr
+}
+
+// UnaryExpr applies one of '-', '!', '~', '*' or '&'
+type UnaryExpr struct {
+	Start Position
+	Op    string
+	X     Expr
+}
+
+// BinaryExpr applies a binary operator
+type BinaryExpr struct {
+	Op string
+	X  Expr
+	Y  Expr
+}
+
+// IndexExpr accesses an element of an array, vector or matrix
+type IndexExpr struct {
+	X     Expr
+	Index Expr
+}
+
+// MemberExpr accesses a structure member or vector swizzle
+type MemberExpr struct {
+	X      Expr
+	Member string
+}
+
+func (n *Ident) Pos() Position       { return n.Start }
+func (n *LiteralExpr) Pos() Position { return n.Start }
+func (n *CallExpr) Pos() Position    { return n.Callee.Pos() }
+func (n *ParenExpr) Pos() Position   { return n.Start }
+func (n *UnaryExpr) Pos() Position   { return n.Start }
+func (n *BinaryExpr) Pos() Position  { return n.X.Pos() }
+func (n *IndexExpr) Pos() Position   { return n.X.Pos() }
+func (n *MemberExpr) Pos() Position  { return n.X.Pos() }
+
+func (*Ident) exprNode()       {}
+func (*LiteralExpr) exprNode() {}
+func (*CallExpr) exprNode()    {}
+func (*ParenExpr) exprNode()   {}
+func (*UnaryExpr) exprNode()   {}
+func (*BinaryExpr) exprNode()  {}
+func (*IndexExpr) exprNode()   {}
+func (*MemberExpr) exprNode()  {}
@@ -0,0 +1,41 @@
+package wgsl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiagnostics caps how many problems are reported for a single source, later ones are usually follow-up errors
+const maxDiagnostics = 50
+
+// Diagnostic is a problem found in WGSL source
+type Diagnostic struct {
+	Position
+	Message string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.Position, d.Message)
+}
+
+// Error reports the diagnostics of WGSL source which failed to parse
+type Error struct {
+	Diagnostics []Diagnostic
+}
+
+func (e Error) Error() string {
+	messages := make([]string, len(e.Diagnostics))
+	for idx, diagnostic := range e.Diagnostics {
+		messages[idx] = diagnostic.String()
+	}
+	return fmt.Sprintf("Invalid WGSL: %s", strings.Join(messages, "; "))
+}
+
+// Validate parses source, returning nil when it is syntactically valid WGSL and an Error listing the problems otherwise
+func Validate(source string) error {
+	_, diagnostics := Parse(source)
+	if len(diagnostics) == 0 {
+		return nil
+	}
+	return Error{diagnostics}
+}
@@ -0,0 +1,347 @@
+package wgsl
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type lexer struct {
+	source string
+	offset int
+	line   int
+	column int
+
+	tokens      []Token
+	diagnostics []Diagnostic
+}
+
+// Tokenize splits WGSL source into tokens, ending with a TokenEOF. Template lists are resolved, meaning the '<' and
+// '>' enclosing them are reported as TokenTemplateStart and TokenTemplateEnd.
+func Tokenize(source string) ([]Token, []Diagnostic) {
+	l := &lexer{source: source, line: 1, column: 1}
+	l.run()
+	l.tokens = discoverTemplateLists(l.tokens)
+	return l.tokens, l.diagnostics
+}
+
+func (l *lexer) position() Position {
+	return Position{Offset: l.offset, Line: l.line, Column: l.column}
+}
+
+func (l *lexer) errorf(position Position, format string, args ...any) {
+	if len(l.diagnostics) < maxDiagnostics {
+		l.diagnostics = append(l.diagnostics, Diagnostic{position, fmt.Sprintf(format, args...)})
+	}
+}
+
+func (l *lexer) peek() rune {
+	if l.offset >= len(l.source) {
+		return utf8.RuneError
+	}
+	r, _ := utf8.DecodeRuneInString(l.source[l.offset:])
+	return r
+}
+
+func (l *lexer) advance() rune {
+	r, size := utf8.DecodeRuneInString(l.source[l.offset:])
+	l.offset += size
+
+	// a line break is any of the blankspace line breaks, with CR LF counting once
+	switch r {
+	case '\r':
+		if l.peek() != '\n' {
+			l.line++
+			l.column = 1
+		} else {
+			l.column++
+		}
+	case '\n', '\v', '\f', '\u0085', '\u2028', '\u2029':
+		l.line++
+		l.column = 1
+	default:
+		l.column++
+	}
+	return r
+}
+
+func isLineBreak(r rune) bool {
+	switch r {
+	case '\n', '\v', '\f', '\r', '\u0085', '\u2028', '\u2029':
+		return true
+	}
+	return false
+}
+
+func isBlankspace(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\v', '\f', '\r', '\u0085', '\u200E', '\u200F', '\u2028', '\u2029':
+		return true
+	}
+	return false
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.Is(unicode.Nl, r)
+}
+
+func isIdentContinue(r rune) bool {
+	return isIdentStart(r) || unicode.IsDigit(r) || unicode.In(r, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc)
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isHexDigit(r rune) bool {
+	return isDigit(r) || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+}
+
+func (l *lexer) run() {
+	for l.offset < len(l.source) {
+		r, size := utf8.DecodeRuneInString(l.source[l.offset:])
+		start := l.position()
+
+		switch {
+		case r == utf8.RuneError && size == 1:
+			l.errorf(start, "invalid UTF-8 encoding")
+			l.offset++
+			l.column++
+		case isBlankspace(r):
+			l.advance()
+		case strings.HasPrefix(l.source[l.offset:], "//"):
+			for l.offset < len(l.source) {
+				if isLineBreak(l.peek()) {
+					break
+				}
+				l.advance()
+			}
+		case strings.HasPrefix(l.source[l.offset:], "/*"):
+			l.blockComment(start)
+		case isDigit(r) || (r == '.' && l.offset+1 < len(l.source) && isDigit(rune(l.source[l.offset+1]))):
+			l.number(start)
+		case isIdentStart(r):
+			l.word(start)
+		default:
+			l.operator(start)
+		}
+	}
+
+	l.tokens = append(l.tokens, Token{Kind: TokenEOF, Start: l.position()})
+}
+
+// blockComment skips a block comment, which may be nested
+func (l *lexer) blockComment(start Position) {
+	depth := 0
+	for l.offset < len(l.source) {
+		rest := l.source[l.offset:]
+		switch {
+		case strings.HasPrefix(rest, "/*"):
+			depth++
+			l.advance()
+			l.advance()
+		case strings.HasPrefix(rest, "*/"):
+			depth--
+			l.advance()
+			l.advance()
+			if depth == 0 {
+				return
+			}
+		default:
+			l.advance()
+		}
+	}
+	l.errorf(start, "unterminated block comment")
+}
+
+func (l *lexer) word(start Position) {
+	for l.offset < len(l.source) && isIdentContinue(l.peek()) {
+		l.advance()
+	}
+	text := l.source[start.Offset:l.offset]
+
+	switch {
+	case text == "_":
+		l.tokens = append(l.tokens, Token{Kind: TokenOperator, Text: text, Start: start})
+	case keywords[text]:
+		l.tokens = append(l.tokens, Token{Kind: TokenKeyword, Text: text, Start: start})
+	case reservedWords[text]:
+		l.errorf(start, "'%s' is a reserved word", text)
+		l.tokens = append(l.tokens, Token{Kind: TokenIdent, Text: text, Start: start})
+	case strings.HasPrefix(text, "__"):
+		l.errorf(start, "identifiers must not start with two underscores")
+		l.tokens = append(l.tokens, Token{Kind: TokenIdent, Text: text, Start: start})
+	default:
+		l.tokens = append(l.tokens, Token{Kind: TokenIdent, Text: text, Start: start})
+	}
+}
+
+func (l *lexer) digits(accept func(rune) bool) int {
+	count := 0
+	for l.offset < len(l.source) && accept(l.peek()) {
+		l.advance()
+		count++
+	}
+	return count
+}
+
+// number scans decimal and hexadecimal integer and float literals, including their suffixes
+func (l *lexer) number(start Position) {
+	kind := TokenIntLiteral
+	rest := l.source[l.offset:]
+
+	if strings.HasPrefix(rest, "0x") || strings.HasPrefix(rest, "0X") {
+		l.advance()
+		l.advance()
+		mantissa := l.digits(isHexDigit)
+		if l.peek() == '.' {
+			kind = TokenFloatLiteral
+			l.advance()
+			mantissa += l.digits(isHexDigit)
+		}
+		if mantissa == 0 {
+			l.errorf(start, "hexadecimal literal has no digits")
+		}
+		if r := l.peek(); r == 'p' || r == 'P' {
+			kind = TokenFloatLiteral
+			l.advance()
+			if r := l.peek(); r == '+' || r == '-' {
+				l.advance()
+			}
+			if l.digits(isDigit) == 0 {
+				l.errorf(start, "hexadecimal float literal has no exponent digits")
+			}
+		}
+	} else {
+		integer := l.digits(isDigit)
+		if l.peek() == '.' {
+			kind = TokenFloatLiteral
+			l.advance()
+			l.digits(isDigit)
+		}
+		if r := l.peek(); r == 'e' || r == 'E' {
+			kind = TokenFloatLiteral
+			l.advance()
+			if r := l.peek(); r == '+' || r == '-' {
+				l.advance()
+			}
+			if l.digits(isDigit) == 0 {
+				l.errorf(start, "float literal has no exponent digits")
+			}
+		}
+		if kind == TokenIntLiteral && integer > 1 && rest[0] == '0' {
+			l.errorf(start, "integer literals must not have leading zeros")
+		}
+	}
+
+	switch l.peek() {
+	case 'i', 'u':
+		if kind == TokenFloatLiteral {
+			l.errorf(l.position(), "float literals must not have an integer suffix")
+		}
+		l.advance()
+	case 'f', 'h':
+		if kind == TokenIntLiteral && rest[0] == '0' && l.offset-start.Offset > 1 {
+			l.errorf(start, "float literals must not have leading zeros")
+		}
+		kind = TokenFloatLiteral
+		l.advance()
+	}
+
+	if l.offset < len(l.source) && isIdentContinue(l.peek()) {
+		l.errorf(l.position(), "invalid suffix on numeric literal")
+		l.digits(isIdentContinue)
+	}
+
+	l.tokens = append(l.tokens, Token{Kind: kind, Text: l.source[start.Offset:l.offset], Start: start})
+}
+
+func (l *lexer) operator(start Position) {
+	rest := l.source[l.offset:]
+	for _, operator := range operators {
+		if strings.HasPrefix(rest, operator) {
+			for range operator {
+				l.advance()
+			}
+			l.tokens = append(l.tokens, Token{Kind: TokenOperator, Text: operator, Start: start})
+			return
+		}
+	}
+
+	r := l.advance()
+	l.errorf(start, "unexpected character %q", r)
+}
+
+// discoverTemplateLists implements template list discovery as defined by the WGSL specification, determining which
+// '<' and '>' delimit template lists rather than compare or shift. A '>' operator token that also starts a longer
+// operator, such as '>>' or '>=', is split when its first character closes a template list.
+func discoverTemplateLists(tokens []Token) []Token {
+	type candidate struct {
+		index int
+		depth int
+	}
+
+	var pending []candidate
+	depth := 0
+
+	popDeeper := func() {
+		for len(pending) > 0 && pending[len(pending)-1].depth >= depth {
+			pending = pending[:len(pending)-1]
+		}
+	}
+
+	for idx := 0; idx < len(tokens); idx++ {
+		token := tokens[idx]
+
+		if token.Kind == TokenIdent || token.Kind == TokenKeyword {
+			if idx+1 < len(tokens) && tokens[idx+1].is("<") {
+				pending = append(pending, candidate{idx + 1, depth})
+				idx++
+			}
+			continue
+		}
+
+		if token.Kind != TokenOperator {
+			continue
+		}
+
+		switch token.Text {
+		case ">", ">>", ">=", ">>=":
+			if len(pending) == 0 || pending[len(pending)-1].depth != depth {
+				continue
+			}
+
+			start := pending[len(pending)-1]
+			pending = pending[:len(pending)-1]
+			tokens[start.index].Kind = TokenTemplateStart
+			tokens[idx] = Token{Kind: TokenTemplateEnd, Text: ">", Start: token.Start}
+
+			if token.Text != ">" {
+				rest := Token{
+					Kind: TokenOperator,
+					Text: token.Text[1:],
+					Start: Position{
+						Offset: token.Start.Offset + 1,
+						Line:   token.Start.Line,
+						Column: token.Start.Column + 1,
+					},
+				}
+				tokens = append(tokens[:idx+1], append([]Token{rest}, tokens[idx+1:]...)...)
+			}
+		case "(", "[":
+			depth++
+		case ")", "]":
+			popDeeper()
+			depth = max(0, depth-1)
+		case "=", ";", "{", ":",
+			"+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "<<=":
+			depth = 0
+			pending = pending[:0]
+		case "&&", "||":
+			popDeeper()
+		}
+	}
+
+	return tokens
+}
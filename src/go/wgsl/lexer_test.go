@@ -0,0 +1,91 @@
+package wgsl
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func kinds(tokens []Token) []TokenKind {
+	result := make([]TokenKind, len(tokens))
+	for idx, token := range tokens {
+		result[idx] = token.Kind
+	}
+	return result
+}
+
+func TestTokenizePositions(t *testing.T) {
+	tokens, diagnostics := Tokenize("fn main() {\n  /* a /* nested */ comment */ return;\n}")
+
+	assert.Empty(t, diagnostics)
+	assert.Equal(t, "return", tokens[5].Text)
+	assert.Equal(t, Position{Offset: 43, Line: 2, Column: 32}, tokens[5].Start)
+	assert.Equal(t, TokenEOF, tokens[len(tokens)-1].Kind)
+}
+
+func TestTokenizeNumbers(t *testing.T) {
+	tests := []struct {
+		source string
+		kind   TokenKind
+	}{
+		{"0", TokenIntLiteral},
+		{"123u", TokenIntLiteral},
+		{"0x1Fi", TokenIntLiteral},
+		{"1.", TokenFloatLiteral},
+		{".5", TokenFloatLiteral},
+		{"1e-3f", TokenFloatLiteral},
+		{"2h", TokenFloatLiteral},
+		{"0x1p4", TokenFloatLiteral},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			tokens, diagnostics := Tokenize(tt.source)
+			assert.Empty(t, diagnostics)
+			assert.Equal(t, []TokenKind{tt.kind, TokenEOF}, kinds(tokens))
+			assert.Equal(t, tt.source, tokens[0].Text)
+		})
+	}
+}
+
+func TestTokenizeTemplateLists(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected []TokenKind
+	}{
+		{"comparison", "a<b", []TokenKind{TokenIdent, TokenOperator, TokenIdent, TokenEOF}},
+		{"template", "vec3<f32>", []TokenKind{TokenIdent, TokenTemplateStart, TokenIdent, TokenTemplateEnd, TokenEOF}},
+		{"nested split shift", "array<vec4<f32>>", []TokenKind{
+			TokenIdent, TokenTemplateStart, TokenIdent, TokenTemplateStart, TokenIdent, TokenTemplateEnd, TokenTemplateEnd, TokenEOF,
+		}},
+		{"comparison inside parentheses", "a<(b>c)>", []TokenKind{
+			TokenIdent, TokenTemplateStart, TokenOperator, TokenIdent, TokenOperator, TokenIdent, TokenOperator, TokenTemplateEnd, TokenEOF,
+		}},
+		{"shift", "a<<b", []TokenKind{TokenIdent, TokenOperator, TokenIdent, TokenEOF}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, diagnostics := Tokenize(tt.source)
+			assert.Empty(t, diagnostics)
+			assert.Equal(t, tt.expected, kinds(tokens))
+		})
+	}
+}
+
+func TestTokenizeDiagnostics(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected []Diagnostic
+	}{
+		{"unterminated comment", "a /* b", []Diagnostic{{Position{2, 1, 3}, "unterminated block comment"}}},
+		{"reserved word", "let\n  typedef = 1;", []Diagnostic{{Position{6, 2, 3}, "'typedef' is a reserved word"}}},
+		{"leading zero", "007", []Diagnostic{{Position{0, 1, 1}, "integer literals must not have leading zeros"}}},
+		{"unexpected character", "let x = 1 $ 2;", []Diagnostic{{Position{10, 1, 11}, "unexpected character '$'"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, diagnostics := Tokenize(tt.source)
+			assert.Equal(t, tt.expected, diagnostics)
+		})
+	}
+}
@@ -0,0 +1,803 @@
+package wgsl
+
+import "fmt"
+
+// bailout unwinds the parser to the closest point of recovery after a syntax error
+type bailout struct{}
+
+type parser struct {
+	tokens      []Token
+	pos         int
+	diagnostics []Diagnostic
+}
+
+// Parse tokenizes and parses WGSL source into a syntax tree. Parsing recovers from syntax errors at statement and
+// declaration boundaries, so the returned module is partial whenever diagnostics are reported.
+func Parse(source string) (*Module, []Diagnostic) {
+	tokens, diagnostics := Tokenize(source)
+	p := &parser{tokens: tokens, diagnostics: diagnostics}
+	module := p.parseModule()
+	return module, p.diagnostics
+}
+
+//==== Helpers ====\\
+
+func (p *parser) peek() Token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peekAt(n int) Token {
+	return p.tokens[min(p.pos+n, len(p.tokens)-1)]
+}
+
+func (p *parser) next() Token {
+	token := p.tokens[p.pos]
+	if token.Kind != TokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+func (p *parser) accept(text string) bool {
+	if p.peek().is(text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// report records a diagnostic without interrupting parsing, at most one per position
+func (p *parser) report(position Position, format string, args ...any) {
+	for _, diagnostic := range p.diagnostics {
+		if diagnostic.Offset == position.Offset {
+			return
+		}
+	}
+
+	p.diagnostics = append(p.diagnostics, Diagnostic{position, fmt.Sprintf(format, args...)})
+	if len(p.diagnostics) >= maxDiagnostics {
+		// give up, skipping to the end so that every parsing loop terminates
+		p.pos = len(p.tokens) - 1
+	}
+}
+
+// errorf records a diagnostic and bails out to the closest point of recovery
+func (p *parser) errorf(position Position, format string, args ...any) {
+	p.report(position, format, args...)
+	panic(bailout{})
+}
+
+func (p *parser) expect(text string) Token {
+	token := p.peek()
+	if !token.is(text) {
+		p.errorf(token.Start, "expected '%s', found %s", text, token)
+	}
+	return p.next()
+}
+
+func (p *parser) expectKind(kind TokenKind) Token {
+	token := p.peek()
+	if token.Kind != kind {
+		p.errorf(token.Start, "expected %s, found %s", kind, token)
+	}
+	return p.next()
+}
+
+// recover runs parse and, should it bail out, synchronize to continue after the broken construct
+func (p *parser) recover(parse func(), synchronize func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(bailout); !ok {
+				panic(r)
+			}
+			synchronize()
+		}
+	}()
+	parse()
+}
+
+// synchronizeDecl skips to the start of the next module scope declaration
+func (p *parser) synchronizeDecl() {
+	start := p.pos
+	depth := 0
+	for {
+		token := p.peek()
+		switch {
+		case token.Kind == TokenEOF:
+			return
+		case token.is("{"):
+			depth++
+		case token.is("}"):
+			depth--
+			if depth <= 0 {
+				p.next()
+				return
+			}
+		case token.is(";") && depth == 0:
+			p.next()
+			return
+		case depth == 0 && p.pos > start && startsDecl(token):
+			return
+		}
+		p.next()
+	}
+}
+
+// synchronizeStmt skips to the start of the next statement, stopping in front of the '}' closing the current block
+func (p *parser) synchronizeStmt() {
+	depth := 0
+	for {
+		token := p.peek()
+		switch {
+		case token.Kind == TokenEOF:
+			return
+		case token.is("{"):
+			depth++
+		case token.is("}"):
+			if depth == 0 {
+				return
+			}
+			depth--
+			if depth == 0 {
+				p.next()
+				return
+			}
+		case token.is(";") && depth == 0:
+			p.next()
+			return
+		}
+		p.next()
+	}
+}
+
+func startsDecl(token Token) bool {
+	for _, keyword := range []string{"fn", "struct", "var", "const", "override", "alias", "const_assert", "@"} {
+		if token.is(keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+//==== Module ====\\
+
+func (p *parser) parseModule() *Module {
+	module := &Module{}
+
+	for isDirective(p.peek()) {
+		p.recover(func() {
+			module.Directives = append(module.Directives, p.parseDirective())
+		}, p.synchronizeDecl)
+	}
+
+	for p.peek().Kind != TokenEOF {
+		p.recover(func() {
+			if decl := p.parseGlobalDecl(); decl != nil {
+				module.Decls = append(module.Decls, decl)
+			}
+		}, p.synchronizeDecl)
+	}
+
+	return module
+}
+
+func isDirective(token Token) bool {
+	return token.is("enable") || token.is("requires") || token.is("diagnostic")
+}
+
+func (p *parser) parseDirective() *Directive {
+	keyword := p.next()
+	directive := &Directive{Start: keyword.Start, Kind: keyword.Text}
+
+	if keyword.Text == "diagnostic" {
+		p.expect("(")
+		directive.Args = append(directive.Args, p.expectKind(TokenIdent).Text)
+		p.expect(",")
+		rule := p.expectKind(TokenIdent).Text
+		if p.accept(".") {
+			rule += "." + p.expectKind(TokenIdent).Text
+		}
+		directive.Args = append(directive.Args, rule)
+		p.accept(",")
+		p.expect(")")
+	} else {
+		for {
+			directive.Args = append(directive.Args, p.expectKind(TokenIdent).Text)
+			if !p.accept(",") || p.peek().is(";") {
+				break
+			}
+		}
+	}
+
+	p.expect(";")
+	return directive
+}
+
+func (p *parser) parseGlobalDecl() Decl {
+	if p.accept(";") {
+		return nil
+	}
+
+	start := p.peek()
+	if isDirective(start) {
+		p.report(start.Start, "directives must appear before any declarations")
+		p.parseDirective()
+		return nil
+	}
+
+	attributes := p.parseAttributes()
+	token := p.peek()
+
+	switch {
+	case token.is("fn"):
+		return p.parseFunc(attributes)
+	case token.is("var"), token.is("override"):
+		decl := p.parseVarDecl(attributes)
+		p.expect(";")
+		return decl
+	case token.is("let"):
+		p.errorf(token.Start, "'let' declarations are only allowed inside functions")
+	}
+
+	if len(attributes) > 0 {
+		p.report(attributes[0].Start, "attributes are not allowed on %s", token)
+	}
+
+	switch {
+	case token.is("const"):
+		decl := p.parseVarDecl(nil)
+		p.expect(";")
+		return decl
+	case token.is("alias"):
+		p.next()
+		decl := &AliasDecl{Start: token.Start}
+		decl.Name = p.expectKind(TokenIdent).Text
+		p.expect("=")
+		decl.Type = p.parseType()
+		p.expect(";")
+		return decl
+	case token.is("struct"):
+		return p.parseStruct()
+	case token.is("const_assert"):
+		decl := p.parseConstAssert()
+		p.expect(";")
+		return decl
+	}
+
+	p.errorf(token.Start, "expected a declaration, found %s", token)
+	return nil
+}
+
+func (p *parser) parseAttributes() []*Attribute {
+	var attributes []*Attribute
+	for p.peek().is("@") {
+		at := p.next()
+		attribute := &Attribute{Start: at.Start}
+
+		name := p.peek()
+		if name.Kind != TokenIdent && name.Kind != TokenKeyword {
+			p.errorf(name.Start, "expected attribute name, found %s", name)
+		}
+		attribute.Name = p.next().Text
+
+		if p.peek().is("(") {
+			attribute.Args = p.parseArgs()
+		}
+		attributes = append(attributes, attribute)
+	}
+	return attributes
+}
+
+// parseVarDecl parses a 'var', 'let', 'const' or 'override' declaration, without the trailing ';'
+func (p *parser) parseVarDecl(attributes []*Attribute) *VarDecl {
+	keyword := p.next()
+	decl := &VarDecl{Start: keyword.Start, Attributes: attributes, Kind: keyword.Text}
+
+	if keyword.Text == "var" && p.peek().Kind == TokenTemplateStart {
+		decl.Template = p.parseTemplateArgs()
+	}
+
+	decl.Name = p.expectKind(TokenIdent).Text
+	if p.accept(":") {
+		decl.Type = p.parseType()
+	}
+	if p.accept("=") {
+		decl.Value = p.parseExpr()
+	}
+
+	if decl.Value == nil && (decl.Kind == "let" || decl.Kind == "const") {
+		p.report(p.peek().Start, "'%s' declarations require an initializer", decl.Kind)
+	}
+	if decl.Value == nil && decl.Type == nil {
+		p.report(p.peek().Start, "'%s' declarations require a type or an initializer", decl.Kind)
+	}
+
+	return decl
+}
+
+func (p *parser) parseConstAssert() *ConstAssertDecl {
+	keyword := p.expect("const_assert")
+	return &ConstAssertDecl{Start: keyword.Start, Cond: p.parseExpr()}
+}
+
+func (p *parser) parseStruct() *StructDecl {
+	keyword := p.expect("struct")
+	decl := &StructDecl{Start: keyword.Start}
+	decl.Name = p.expectKind(TokenIdent).Text
+
+	p.expect("{")
+	for !p.peek().is("}") {
+		member := &StructMember{Start: p.peek().Start}
+		member.Attributes = p.parseAttributes()
+		member.Name = p.expectKind(TokenIdent).Text
+		p.expect(":")
+		member.Type = p.parseType()
+		decl.Members = append(decl.Members, member)
+
+		if !p.accept(",") {
+			break
+		}
+	}
+	closing := p.expect("}")
+
+	if len(decl.Members) == 0 {
+		p.report(closing.Start, "structures must have at least one member")
+	}
+	return decl
+}
+
+func (p *parser) parseFunc(attributes []*Attribute) *FuncDecl {
+	keyword := p.expect("fn")
+	decl := &FuncDecl{Start: keyword.Start, Attributes: attributes}
+	decl.Name = p.expectKind(TokenIdent).Text
+
+	p.expect("(")
+	for !p.peek().is(")") {
+		param := &Param{Start: p.peek().Start}
+		param.Attributes = p.parseAttributes()
+		param.Name = p.expectKind(TokenIdent).Text
+		p.expect(":")
+		param.Type = p.parseType()
+		decl.Params = append(decl.Params, param)
+
+		if !p.accept(",") {
+			break
+		}
+	}
+	p.expect(")")
+
+	if p.accept("->") {
+		decl.ReturnAttributes = p.parseAttributes()
+		decl.ReturnType = p.parseType()
+	}
+
+	decl.Body = p.parseBlock(nil)
+	return decl
+}
+
+// parseType parses a type specifier such as 'f32' or 'array<vec4<f32>, 4>'
+func (p *parser) parseType() *Ident {
+	name := p.expectKind(TokenIdent)
+	ident := &Ident{Start: name.Start, Name: name.Text}
+	if p.peek().Kind == TokenTemplateStart {
+		ident.TemplateArgs = p.parseTemplateArgs()
+	}
+	return ident
+}
+
+func (p *parser) parseTemplateArgs() []Expr {
+	p.expectKind(TokenTemplateStart)
+
+	var args []Expr
+	for p.peek().Kind != TokenTemplateEnd {
+		args = append(args, p.parseExpr())
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	end := p.expectKind(TokenTemplateEnd)
+	if len(args) == 0 {
+		p.report(end.Start, "template lists must not be empty")
+	}
+	return args
+}
+
+//==== Statements ====\\
+
+func (p *parser) parseBlock(attributes []*Attribute) *BlockStmt {
+	open := p.expect("{")
+	block := &BlockStmt{Start: open.Start, Attributes: attributes}
+	block.Stmts = p.parseStatements(func(token Token) bool { return false })
+	p.expect("}")
+	return block
+}
+
+// parseStatements parses statements up to the '}' closing the current block, or until stop returns true
+func (p *parser) parseStatements(stop func(Token) bool) []Stmt {
+	var stmts []Stmt
+	for {
+		token := p.peek()
+		if token.Kind == TokenEOF || token.is("}") || stop(token) {
+			return stmts
+		}
+
+		p.recover(func() {
+			stmts = append(stmts, p.parseStatement())
+		}, p.synchronizeStmt)
+	}
+}
+
+func (p *parser) parseStatement() Stmt {
+	if token := p.peek(); token.is(";") {
+		p.next()
+		return &EmptyStmt{Start: token.Start}
+	}
+
+	attributes := p.parseAttributes()
+	token := p.peek()
+
+	switch {
+	case token.is("{"):
+		return p.parseBlock(attributes)
+	case token.is("if"):
+		return p.parseIf(attributes)
+	case token.is("switch"):
+		return p.parseSwitch(attributes)
+	case token.is("loop"):
+		return p.parseLoop(attributes)
+	case token.is("for"):
+		return p.parseFor(attributes)
+	case token.is("while"):
+		p.next()
+		stmt := &WhileStmt{Start: token.Start, Attributes: attributes}
+		stmt.Cond = p.parseExpr()
+		stmt.Body = p.parseBlock(nil)
+		return stmt
+	}
+
+	if len(attributes) > 0 {
+		p.report(attributes[0].Start, "attributes are not allowed on %s", token)
+	}
+
+	var stmt Stmt
+	switch {
+	case token.is("return"):
+		p.next()
+		returnStmt := &ReturnStmt{Start: token.Start}
+		if !p.peek().is(";") {
+			returnStmt.Value = p.parseExpr()
+		}
+		stmt = returnStmt
+	case token.is("break"):
+		p.next()
+		if p.peek().is("if") {
+			p.errorf(token.Start, "'break if' is only allowed at the end of a continuing block")
+		}
+		stmt = &BranchStmt{Start: token.Start, Kind: token.Text}
+	case token.is("continue"), token.is("discard"):
+		p.next()
+		stmt = &BranchStmt{Start: token.Start, Kind: token.Text}
+	case token.is("var"), token.is("let"), token.is("const"):
+		stmt = &DeclStmt{Decl: p.parseVarDecl(nil)}
+	case token.is("const_assert"):
+		stmt = &DeclStmt{Decl: p.parseConstAssert()}
+	case token.is("continuing"):
+		p.errorf(token.Start, "'continuing' is only allowed at the end of a loop")
+	case token.is("else"):
+		p.errorf(token.Start, "'else' without a preceding 'if'")
+	case token.is("case"), token.is("default"):
+		p.errorf(token.Start, "'%s' is only allowed inside a switch", token.Text)
+	default:
+		stmt = p.parseSimpleStatement()
+	}
+
+	p.expect(";")
+	return stmt
+}
+
+// parseSimpleStatement parses an assignment, increment, decrement or function call, without the trailing ';'
+func (p *parser) parseSimpleStatement() Stmt {
+	start := p.peek()
+
+	if start.is("_") {
+		p.next()
+		p.expect("=")
+		return &AssignStmt{Start: start.Start, Op: "=", Value: p.parseExpr()}
+	}
+
+	target := p.parseUnary()
+	token := p.peek()
+
+	switch {
+	case token.is("++"), token.is("--"):
+		p.next()
+		return &IncDecStmt{Start: start.Start, Op: token.Text, Target: target}
+	case isAssignment(token):
+		p.next()
+		return &AssignStmt{Start: start.Start, Op: token.Text, Target: target, Value: p.parseExpr()}
+	}
+
+	if call, ok := target.(*CallExpr); ok {
+		return &CallStmt{Call: call}
+	}
+
+	p.errorf(token.Start, "expected assignment, increment, decrement or function call, found %s", token)
+	return nil
+}
+
+func isAssignment(token Token) bool {
+	for _, operator := range []string{"=", "+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", ">>=", "<<="} {
+		if token.is(operator) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) parseIf(attributes []*Attribute) *IfStmt {
+	keyword := p.expect("if")
+	stmt := &IfStmt{Start: keyword.Start, Attributes: attributes}
+	stmt.Cond = p.parseExpr()
+	stmt.Body = p.parseBlock(nil)
+
+	if p.accept("else") {
+		if p.peek().is("if") {
+			stmt.Else = p.parseIf(nil)
+		} else {
+			stmt.Else = p.parseBlock(nil)
+		}
+	}
+	return stmt
+}
+
+func (p *parser) parseSwitch(attributes []*Attribute) *SwitchStmt {
+	keyword := p.expect("switch")
+	stmt := &SwitchStmt{Start: keyword.Start, Attributes: attributes}
+	stmt.Value = p.parseExpr()
+	p.parseAttributes()
+
+	p.expect("{")
+	for !p.peek().is("}") {
+		token := p.peek()
+		clause := &CaseClause{Start: token.Start}
+
+		switch {
+		case p.accept("default"):
+			clause.Selectors = []Expr{nil}
+		case p.accept("case"):
+			for !p.peek().is(":") && !p.peek().is("{") {
+				if p.accept("default") {
+					clause.Selectors = append(clause.Selectors, nil)
+				} else {
+					clause.Selectors = append(clause.Selectors, p.parseExpr())
+				}
+				if !p.accept(",") {
+					break
+				}
+			}
+			if len(clause.Selectors) == 0 {
+				p.report(p.peek().Start, "'case' requires at least one selector")
+			}
+		default:
+			p.errorf(token.Start, "expected 'case' or 'default', found %s", token)
+		}
+
+		p.accept(":")
+		clause.Body = p.parseBlock(p.parseAttributes())
+		stmt.Clauses = append(stmt.Clauses, clause)
+	}
+	p.expect("}")
+
+	return stmt
+}
+
+func (p *parser) parseLoop(attributes []*Attribute) *LoopStmt {
+	keyword := p.expect("loop")
+	stmt := &LoopStmt{Start: keyword.Start, Attributes: attributes}
+
+	open := p.expect("{")
+	stmt.Body = &BlockStmt{Start: open.Start, Attributes: p.parseAttributes()}
+	stmt.Body.Stmts = p.parseStatements(func(token Token) bool { return token.is("continuing") })
+
+	if continuing := p.peek(); continuing.is("continuing") {
+		p.next()
+		stmt.Continuing = &ContinuingStmt{Start: continuing.Start}
+
+		block := p.expect("{")
+		stmt.Continuing.Body = &BlockStmt{Start: block.Start}
+		stmt.Continuing.Body.Stmts = p.parseStatements(func(token Token) bool {
+			return token.is("break") && p.peekAt(1).is("if")
+		})
+
+		if p.accept("break") {
+			p.expect("if")
+			stmt.Continuing.BreakIf = p.parseExpr()
+			p.expect(";")
+		}
+		p.expect("}")
+	}
+
+	p.expect("}")
+	return stmt
+}
+
+func (p *parser) parseFor(attributes []*Attribute) *ForStmt {
+	keyword := p.expect("for")
+	stmt := &ForStmt{Start: keyword.Start, Attributes: attributes}
+
+	p.expect("(")
+	if token := p.peek(); !token.is(";") {
+		if token.is("var") || token.is("let") || token.is("const") {
+			stmt.Init = &DeclStmt{Decl: p.parseVarDecl(nil)}
+		} else {
+			stmt.Init = p.parseSimpleStatement()
+		}
+	}
+	p.expect(";")
+
+	if !p.peek().is(";") {
+		stmt.Cond = p.parseExpr()
+	}
+	p.expect(";")
+
+	if !p.peek().is(")") {
+		stmt.Update = p.parseSimpleStatement()
+	}
+	p.expect(")")
+
+	stmt.Body = p.parseBlock(nil)
+	return stmt
+}
+
+//==== Expressions ====\\
+
+// parseExpr parses an expression following the WGSL grammar, which requires parentheses when mixing logical or
+// bitwise operators with other operators and does not allow chaining comparisons or shifts
+func (p *parser) parseExpr() Expr {
+	x := p.parseUnary()
+
+	if op := p.peek(); op.is("&") || op.is("|") || op.is("^") {
+		for p.peek().is(op.Text) {
+			p.next()
+			x = &BinaryExpr{Op: op.Text, X: x, Y: p.parseUnary()}
+		}
+		if next := p.peek(); next.Kind == TokenOperator && isBinaryOperator(next.Text) {
+			p.errorf(next.Start, "mixing '%s' and '%s' requires parentheses", op.Text, next.Text)
+		}
+		return x
+	}
+
+	x = p.parseRelational(x)
+
+	if op := p.peek(); op.is("&&") || op.is("||") {
+		for p.peek().is(op.Text) {
+			p.next()
+			x = &BinaryExpr{Op: op.Text, X: x, Y: p.parseRelational(p.parseUnary())}
+		}
+		if next := p.peek(); next.is("&&") || next.is("||") {
+			p.errorf(next.Start, "mixing '%s' and '%s' requires parentheses", op.Text, next.Text)
+		}
+	}
+
+	return x
+}
+
+func isBinaryOperator(text string) bool {
+	switch text {
+	case "||", "&&", "|", "^", "&", "==", "!=", "<", ">", "<=", ">=", "<<", ">>", "+", "-", "*", "/", "%":
+		return true
+	}
+	return false
+}
+
+func isRelational(token Token) bool {
+	return token.is("==") || token.is("!=") || token.is("<") || token.is(">") || token.is("<=") || token.is(">=")
+}
+
+// parseRelational continues parsing an expression starting with the already parsed unary expression x
+func (p *parser) parseRelational(x Expr) Expr {
+	x = p.parseShift(x)
+
+	if op := p.peek(); isRelational(op) {
+		p.next()
+		x = &BinaryExpr{Op: op.Text, X: x, Y: p.parseShift(p.parseUnary())}
+
+		if next := p.peek(); isRelational(next) {
+			p.errorf(next.Start, "comparisons cannot be chained, use parentheses")
+		}
+	}
+	return x
+}
+
+func (p *parser) parseShift(x Expr) Expr {
+	if op := p.peek(); op.is("<<") || op.is(">>") {
+		p.next()
+		x = &BinaryExpr{Op: op.Text, X: x, Y: p.parseUnary()}
+
+		if next := p.peek(); next.is("<<") || next.is(">>") || next.is("+") || next.is("-") || next.is("*") || next.is("/") || next.is("%") {
+			p.errorf(next.Start, "mixing '%s' and '%s' requires parentheses", op.Text, next.Text)
+		}
+		return x
+	}
+
+	x = p.parseMultiplicative(x)
+	for op := p.peek(); op.is("+") || op.is("-"); op = p.peek() {
+		p.next()
+		x = &BinaryExpr{Op: op.Text, X: x, Y: p.parseMultiplicative(p.parseUnary())}
+	}
+	return x
+}
+
+func (p *parser) parseMultiplicative(x Expr) Expr {
+	for op := p.peek(); op.is("*") || op.is("/") || op.is("%"); op = p.peek() {
+		p.next()
+		x = &BinaryExpr{Op: op.Text, X: x, Y: p.parseUnary()}
+	}
+	return x
+}
+
+func (p *parser) parseUnary() Expr {
+	token := p.peek()
+	if token.is("-") || token.is("!") || token.is("~") || token.is("*") || token.is("&") {
+		p.next()
+		return &UnaryExpr{Start: token.Start, Op: token.Text, X: p.parseUnary()}
+	}
+
+	x := p.parsePrimary()
+	for {
+		switch {
+		case p.accept("["):
+			index := p.parseExpr()
+			p.expect("]")
+			x = &IndexExpr{X: x, Index: index}
+		case p.accept("."):
+			x = &MemberExpr{X: x, Member: p.expectKind(TokenIdent).Text}
+		default:
+			return x
+		}
+	}
+}
+
+func (p *parser) parsePrimary() Expr {
+	token := p.peek()
+
+	switch {
+	case token.Kind == TokenIntLiteral, token.Kind == TokenFloatLiteral, token.is("true"), token.is("false"):
+		p.next()
+		return &LiteralExpr{Start: token.Start, Kind: token.Kind, Value: token.Text}
+	case token.is("("):
+		p.next()
+		x := p.parseExpr()
+		p.expect(")")
+		return &ParenExpr{Start: token.Start, X: x}
+	case token.Kind == TokenIdent:
+		p.next()
+		ident := &Ident{Start: token.Start, Name: token.Text}
+		if p.peek().Kind == TokenTemplateStart {
+			ident.TemplateArgs = p.parseTemplateArgs()
+		}
+		if p.peek().is("(") {
+			return &CallExpr{Callee: ident, Args: p.parseArgs()}
+		}
+		return ident
+	}
+
+	p.errorf(token.Start, "expected expression, found %s", token)
+	return nil
+}
+
+func (p *parser) parseArgs() []Expr {
+	p.expect("(")
+
+	var args []Expr
+	for !p.peek().is(")") {
+		args = append(args, p.parseExpr())
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	p.expect(")")
+	return args
+}
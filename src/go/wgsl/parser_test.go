@@ -0,0 +1,146 @@
+package wgsl
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const exampleShader = `
+enable f16;
+diagnostic(off, derivative_uniformity);
+
+struct Uniforms {
+	resolution: vec2<f32>,
+	time: f32,
+}
+
+@group(0) @binding(0) var<uniform> uniforms: Uniforms;
+@group(0) @binding(1) var<storage, read_write> samples: array<vec4<f32>>;
+
+alias Palette = array<vec3f, 4>;
+const PI = 3.14159;
+override steps: u32 = 64u;
+const_assert PI > 3.0;
+
+fn rotate(a: f32) -> mat2x2<f32> {
+	let c = cos(a);
+	let s = sin(a);
+	return mat2x2<f32>(c, -s, s, c);
+}
+
+@fragment
+fn main(@builtin(position) position: vec4<f32>) -> @location(0) vec4<f32> {
+	var uv = (position.xy - 0.5 * uniforms.resolution) / uniforms.resolution.y;
+	var color = vec3f(0.0);
+	for (var i = 0u; i < steps; i++) {
+		color += vec3(f32(i) / f32(steps));
+		if (i > 5u) {
+			break;
+		} else if i == 2u {
+			continue;
+		}
+	}
+	loop {
+		color *= 0.5;
+		continuing {
+			_ = color;
+			break if color.x < 0.1;
+		}
+	}
+	switch i32(uv.x) {
+		case 0, 1: { color.r = 1.0; }
+		default { }
+	}
+	while color.x > 1.0 {
+		color.x -= 1.0;
+	}
+	samples[0] = vec4(color, f32(samples[1].x >> 1u));
+	return vec4<f32>(color, 1.0);
+}
+`
+
+func TestParseValid(t *testing.T) {
+	module, diagnostics := Parse(exampleShader)
+
+	assert.Empty(t, diagnostics)
+	assert.Len(t, module.Directives, 2)
+	assert.Len(t, module.Decls, 9)
+
+	main, ok := module.Decls[8].(*FuncDecl)
+	if assert.True(t, ok) {
+		assert.Equal(t, "main", main.Name)
+		assert.Equal(t, "fragment", main.Attributes[0].Name)
+		assert.Equal(t, "vec4", main.ReturnType.Name)
+		assert.Len(t, main.Body.Stmts, 8)
+	}
+}
+
+func TestParseExpressionPrecedence(t *testing.T) {
+	module, diagnostics := Parse("const x = 1 + 2 * 3 < 4 && !y;")
+	assert.Empty(t, diagnostics)
+
+	decl := module.Decls[0].(*VarDecl)
+	and := decl.Value.(*BinaryExpr)
+	assert.Equal(t, "&&", and.Op)
+	assert.IsType(t, &UnaryExpr{}, and.Y)
+
+	less := and.X.(*BinaryExpr)
+	assert.Equal(t, "<", less.Op)
+
+	plus := less.X.(*BinaryExpr)
+	assert.Equal(t, "+", plus.Op)
+	assert.Equal(t, "*", plus.Y.(*BinaryExpr).Op)
+}
+
+func TestParseDiagnostics(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected []Diagnostic
+	}{
+		{"missing semicolon", "fn f() {\n\tlet x = 1\n}", []Diagnostic{{Position{20, 3, 1}, "expected ';', found '}'"}}},
+		{"missing expression", "fn f() { return 1 + ; }", []Diagnostic{{Position{20, 1, 21}, "expected expression, found ';'"}}},
+		{"let at module scope", "let x = 1;", []Diagnostic{{Position{0, 1, 1}, "'let' declarations are only allowed inside functions"}}},
+		{"empty struct", "struct S {}", []Diagnostic{{Position{10, 1, 11}, "structures must have at least one member"}}},
+		{"mixed logical operators", "const x = a || b && c;", []Diagnostic{{Position{17, 1, 18}, "mixing '||' and '&&' requires parentheses"}}},
+		{"mixed bitwise operators", "const x = a & b + c;", []Diagnostic{{Position{16, 1, 17}, "mixing '&' and '+' requires parentheses"}}},
+		{"chained comparison", "const x = a < b < c;", []Diagnostic{{Position{16, 1, 17}, "comparisons cannot be chained, use parentheses"}}},
+		{"misplaced break if", "fn f() { break if true; }", []Diagnostic{{Position{9, 1, 10}, "'break if' is only allowed at the end of a continuing block"}}},
+		{"directive after declaration", "const x = 1;\nenable f16;", []Diagnostic{{Position{13, 2, 1}, "directives must appear before any declarations"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, diagnostics := Parse(tt.source)
+			assert.Equal(t, tt.expected, diagnostics)
+		})
+	}
+}
+
+func TestParseRecovers(t *testing.T) {
+	module, diagnostics := Parse("fn a() { let x = ; let y = 2; }\nfn b( { }\nfn c() -> f32 { return 1.0; }")
+
+	assert.Equal(t, []Diagnostic{
+		{Position{17, 1, 18}, "expected expression, found ';'"},
+		{Position{38, 2, 7}, "expected identifier, found '{'"},
+	}, diagnostics)
+
+	assert.Len(t, module.Decls, 2)
+	assert.Equal(t, "a", module.Decls[0].(*FuncDecl).Name)
+	assert.Len(t, module.Decls[0].(*FuncDecl).Body.Stmts, 1)
+	assert.Equal(t, "c", module.Decls[1].(*FuncDecl).Name)
+}
+
+func TestParseDiagnosticLimit(t *testing.T) {
+	source := ""
+	for range 2 * maxDiagnostics {
+		source += "fn ;\n"
+	}
+
+	_, diagnostics := Parse(source)
+	assert.Len(t, diagnostics, maxDiagnostics)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(exampleShader))
+	assert.EqualError(t, Validate("fn f() {"), "Invalid WGSL: 1:9: expected '}', found end of file")
+}
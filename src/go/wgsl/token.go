@@ -0,0 +1,122 @@
+package wgsl
+
+import "fmt"
+
+// TokenKind classifies a lexical token
+type TokenKind int
+
+const (
+	TokenEOF TokenKind = iota
+	TokenIdent
+	TokenKeyword
+	TokenIntLiteral
+	TokenFloatLiteral
+	TokenOperator
+
+	// TokenTemplateStart and TokenTemplateEnd replace the '<' and '>' operators which enclose a template list, as
+	// determined by template list discovery
+	TokenTemplateStart
+	TokenTemplateEnd
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case TokenEOF:
+		return "end of file"
+	case TokenIdent:
+		return "identifier"
+	case TokenKeyword:
+		return "keyword"
+	case TokenIntLiteral:
+		return "integer literal"
+	case TokenFloatLiteral:
+		return "float literal"
+	case TokenOperator:
+		return "operator"
+	case TokenTemplateStart:
+		return "template list start"
+	case TokenTemplateEnd:
+		return "template list end"
+	default:
+		return fmt.Sprintf("TokenKind(%d)", int(k))
+	}
+}
+
+// Position is a location in WGSL source. Line and Column are 1-based, Column counts runes.
+type Position struct {
+	Offset int `json:"-"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Token is a single lexical token of WGSL source
+type Token struct {
+	Kind  TokenKind
+	Text  string
+	Start Position
+}
+
+func (t Token) String() string {
+	switch t.Kind {
+	case TokenEOF:
+		return "end of file"
+	case TokenTemplateStart:
+		return "'<'"
+	case TokenTemplateEnd:
+		return "'>'"
+	default:
+		return fmt.Sprintf("'%s'", t.Text)
+	}
+}
+
+// is reports whether the token is the keyword or operator text
+func (t Token) is(text string) bool {
+	return (t.Kind == TokenKeyword || t.Kind == TokenOperator) && t.Text == text
+}
+
+var keywords = map[string]bool{
+	"alias": true, "break": true, "case": true, "const": true, "const_assert": true, "continue": true,
+	"continuing": true, "default": true, "diagnostic": true, "discard": true, "else": true, "enable": true,
+	"false": true, "fn": true, "for": true, "if": true, "let": true, "loop": true, "override": true,
+	"requires": true, "return": true, "struct": true, "switch": true, "true": true, "var": true, "while": true,
+}
+
+var reservedWords = map[string]bool{
+	"NULL": true, "Self": true, "abstract": true, "active": true, "alignas": true, "alignof": true, "as": true,
+	"asm": true, "asm_fragment": true, "async": true, "attribute": true, "auto": true, "await": true,
+	"become": true, "binding_array": true, "cast": true, "catch": true, "class": true, "co_await": true,
+	"co_return": true, "co_yield": true, "coherent": true, "column_major": true, "common": true, "compile": true,
+	"compile_fragment": true, "concept": true, "const_cast": true, "consteval": true, "constexpr": true,
+	"constinit": true, "crate": true, "debugger": true, "decltype": true, "delete": true, "demote": true,
+	"demote_to_helper": true, "do": true, "dynamic_cast": true, "enum": true, "explicit": true, "export": true,
+	"extends": true, "extern": true, "external": true, "fallthrough": true, "filter": true, "final": true,
+	"finally": true, "friend": true, "from": true, "fxgroup": true, "get": true, "goto": true,
+	"groupshared": true, "highp": true, "impl": true, "implements": true, "import": true, "inline": true,
+	"instanceof": true, "interface": true, "layout": true, "lowp": true, "macro": true, "macro_rules": true,
+	"match": true, "mediump": true, "meta": true, "mod": true, "module": true, "move": true, "mut": true,
+	"mutable": true, "namespace": true, "new": true, "nil": true, "noexcept": true, "noinline": true,
+	"nointerpolation": true, "noperspective": true, "null": true, "nullptr": true, "of": true, "operator": true,
+	"package": true, "packoffset": true, "partition": true, "pass": true, "patch": true, "pixelfragment": true,
+	"precise": true, "precision": true, "premerge": true, "priv": true, "protected": true, "pub": true,
+	"public": true, "readonly": true, "ref": true, "regardless": true, "register": true,
+	"reinterpret_cast": true, "require": true, "resource": true, "restrict": true, "self": true, "set": true,
+	"shared": true, "sizeof": true, "smooth": true, "snorm": true, "static": true, "static_assert": true,
+	"static_cast": true, "std": true, "subroutine": true, "super": true, "target": true, "template": true,
+	"this": true, "thread_local": true, "throw": true, "trait": true, "try": true, "type": true, "typedef": true,
+	"typeid": true, "typename": true, "typeof": true, "union": true, "unless": true, "unorm": true,
+	"unsafe": true, "unsized": true, "use": true, "using": true, "varying": true, "virtual": true,
+	"volatile": true, "wgsl": true, "where": true, "with": true, "writeonly": true, "yield": true,
+}
+
+// operators lists every operator and punctuation token, longest first so the lexer can match greedily
+var operators = []string{
+	">>=", "<<=",
+	"&&", "||", "->", "==", "!=", ">=", "<=", ">>", "<<", "--", "++",
+	"+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=",
+	"&", "@", "/", "!", "[", "]", "{", "}", ":", ",", "=", ">", "<", "%", "-", ".", "+", "|", "(", ")", ";",
+	"*", "~", "^", "_",
+}
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS shaders_deleted_at_idx;
+
+ALTER TABLE shaders
+    DROP COLUMN IF EXISTS deleted_at;
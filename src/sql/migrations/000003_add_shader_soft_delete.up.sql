@@ -0,0 +1,4 @@
+ALTER TABLE shaders
+    ADD COLUMN IF NOT EXISTS deleted_at timestamp with time zone NULL;
+
+CREATE INDEX IF NOT EXISTS shaders_deleted_at_idx ON shaders (deleted_at) WHERE deleted_at IS NOT NULL;
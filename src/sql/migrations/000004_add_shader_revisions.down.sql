@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS shader_revisions;
+
+ALTER TABLE shaders
+    DROP COLUMN IF EXISTS revision;
@@ -0,0 +1,20 @@
+ALTER TABLE shaders
+    ADD COLUMN IF NOT EXISTS revision integer NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS shader_revisions (
+    shader_id           character(22) REFERENCES shaders (shader_id) ON DELETE CASCADE  NOT NULL     ,
+    revision            integer                                                         NOT NULL     ,
+    created_at          timestamp with time zone                                        NOT NULL     ,
+
+    created_by          character(22) REFERENCES users (user_id)                        NOT NULL     ,
+    content             text                                                            NOT NULL     ,
+    reverted_from       integer                                                         NULL         ,
+
+    PRIMARY KEY (shader_id, revision)
+);
+
+-- existing shaders start their history at the current content
+INSERT INTO shader_revisions (shader_id, revision, created_at, created_by, content)
+SELECT shader_id, revision, updated_at, created_by, content
+FROM shaders
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS shaders_forked_from_idx;
+
+ALTER TABLE shaders
+    DROP COLUMN IF EXISTS forked_from;
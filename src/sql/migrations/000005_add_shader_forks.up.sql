@@ -0,0 +1,5 @@
+-- intentionally not a foreign key, attribution must survive the parent being purged
+ALTER TABLE shaders
+    ADD COLUMN IF NOT EXISTS forked_from character(22) NULL;
+
+CREATE INDEX IF NOT EXISTS shaders_forked_from_idx ON shaders (forked_from) WHERE forked_from IS NOT NULL;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS shaders_created_by_updated_at_idx;
+DROP INDEX IF EXISTS shaders_public_name_idx;
+DROP INDEX IF EXISTS shaders_public_updated_at_idx;
+DROP INDEX IF EXISTS shaders_public_created_at_idx;
@@ -0,0 +1,9 @@
+CREATE INDEX IF NOT EXISTS shaders_public_created_at_idx ON shaders (created_at DESC, shader_id DESC)
+    WHERE visibility = 'public' AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS shaders_public_updated_at_idx ON shaders (updated_at DESC, shader_id DESC)
+    WHERE visibility = 'public' AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS shaders_public_name_idx ON shaders (name, shader_id)
+    WHERE visibility = 'public' AND deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS shaders_created_by_updated_at_idx ON shaders (created_by, updated_at DESC, shader_id DESC)
+    WHERE deleted_at IS NULL;
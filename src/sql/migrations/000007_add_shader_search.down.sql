@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS shaders_tags_idx;
+DROP INDEX IF EXISTS shaders_search_content_idx;
+DROP INDEX IF EXISTS shaders_search_text_idx;
+
+DROP TRIGGER IF EXISTS shaders_search_update ON shaders;
+DROP FUNCTION IF EXISTS shaders_search_update();
+
+ALTER TABLE shaders
+    DROP COLUMN IF EXISTS search_content,
+    DROP COLUMN IF EXISTS search_text;
@@ -0,0 +1,27 @@
+ALTER TABLE shaders
+    ADD COLUMN IF NOT EXISTS search_text     tsvector  NOT NULL  DEFAULT ''::tsvector,
+    ADD COLUMN IF NOT EXISTS search_content  tsvector  NOT NULL  DEFAULT ''::tsvector;
+
+-- name, tags and description are natural language, WGSL content is indexed verbatim
+CREATE OR REPLACE FUNCTION shaders_search_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_text :=
+        setweight(to_tsvector('english', NEW.name), 'A') ||
+        setweight(to_tsvector('simple', array_to_string(NEW.tags, ' ')), 'B') ||
+        setweight(to_tsvector('english', NEW.description), 'C');
+    NEW.search_content := to_tsvector('simple', NEW.content);
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS shaders_search_update ON shaders;
+CREATE TRIGGER shaders_search_update
+    BEFORE INSERT OR UPDATE OF name, tags, description, content ON shaders
+    FOR EACH ROW EXECUTE FUNCTION shaders_search_update();
+
+-- backfill existing rows through the trigger
+UPDATE shaders SET name = name;
+
+CREATE INDEX IF NOT EXISTS shaders_search_text_idx ON shaders USING GIN (search_text);
+CREATE INDEX IF NOT EXISTS shaders_search_content_idx ON shaders USING GIN (search_content);
+CREATE INDEX IF NOT EXISTS shaders_tags_idx ON shaders USING GIN (tags);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS password_resets (
+    token_hash          text                                                        PRIMARY KEY  ,
+    user_id             character(22) REFERENCES users (user_id) ON DELETE CASCADE  NOT NULL     ,
+    created_at          timestamp with time zone                                    NOT NULL     ,
+    expires_at          timestamp with time zone                                    NOT NULL     ,
+    used_at             timestamp with time zone                                    NULL
+);
+
+CREATE INDEX IF NOT EXISTS password_resets_user_id_idx ON password_resets (user_id, created_at);
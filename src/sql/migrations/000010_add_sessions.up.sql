@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    session_id          character(22)                                               PRIMARY KEY  ,
+    user_id             character(22) REFERENCES users (user_id) ON DELETE CASCADE  NOT NULL     ,
+    created_at          timestamp with time zone                                    NOT NULL     ,
+    last_used_at        timestamp with time zone                                    NOT NULL     ,
+    expires_at          timestamp with time zone                                    NOT NULL     ,
+
+    user_agent          text                                                        NOT NULL     ,
+    refresh_token_hash  text                                                        NOT NULL     ,
+    previous_token_hash text                                                        NULL         ,
+    revoked_at          timestamp with time zone                                    NULL
+);
+
+ALTER TABLE sessions
+    ADD CONSTRAINT unique_refresh_token_hash UNIQUE (refresh_token_hash);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS sessions_previous_token_hash_idx ON sessions (previous_token_hash) WHERE previous_token_hash IS NOT NULL;
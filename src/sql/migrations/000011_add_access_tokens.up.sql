@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS access_tokens (
+    token_id            character(22)                                               PRIMARY KEY  ,
+    user_id             character(22) REFERENCES users (user_id) ON DELETE CASCADE  NOT NULL     ,
+    created_at          timestamp with time zone                                    NOT NULL     ,
+    expires_at          timestamp with time zone                                    NOT NULL     ,
+    last_used_at        timestamp with time zone                                    NULL         ,
+
+    name                text                                                        NOT NULL     ,
+    scopes              text[]                                                      NOT NULL     ,
+    token_hash          text                                                        NOT NULL     ,
+    revoked_at          timestamp with time zone                                    NULL
+);
+
+ALTER TABLE access_tokens
+    ADD CONSTRAINT unique_access_token_hash UNIQUE (token_hash);
+
+CREATE INDEX IF NOT EXISTS access_tokens_user_id_idx ON access_tokens (user_id) WHERE revoked_at IS NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE shaders
+    DROP COLUMN IF EXISTS like_count;
+
+DROP TABLE IF EXISTS shader_likes;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS shader_likes (
+    shader_id           character(22) REFERENCES shaders (shader_id) ON DELETE CASCADE  NOT NULL     ,
+    user_id             character(22) REFERENCES users (user_id) ON DELETE CASCADE      NOT NULL     ,
+    created_at          timestamp with time zone                                        NOT NULL     ,
+
+    PRIMARY KEY (shader_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS shader_likes_user_id_idx ON shader_likes (user_id, created_at DESC);
+
+-- denormalized count of shader_likes, kept in step by the repository so listings need not aggregate
+ALTER TABLE shaders
+    ADD COLUMN IF NOT EXISTS like_count integer NOT NULL DEFAULT 0;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS shader_comments (
+    comment_id          character(22)                                                           PRIMARY KEY  ,
+    shader_id           character(22) REFERENCES shaders (shader_id) ON DELETE CASCADE          NOT NULL     ,
+    parent_id           character(22) REFERENCES shader_comments (comment_id) ON DELETE CASCADE NULL         ,
+    created_by          character(22) REFERENCES users (user_id) ON DELETE CASCADE              NOT NULL     ,
+    created_at          timestamp with time zone                                                NOT NULL     ,
+    updated_at          timestamp with time zone                                                NOT NULL     ,
+
+    body                text                                                                    NOT NULL     ,
+    hidden_at           timestamp with time zone                                                NULL         ,
+    deleted_at          timestamp with time zone                                                NULL
+);
+
+CREATE INDEX IF NOT EXISTS shader_comments_shader_id_idx ON shader_comments (shader_id, created_at, comment_id);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS collections (
+    collection_id       character(22)                                               PRIMARY KEY  ,
+    created_by          character(22) REFERENCES users (user_id) ON DELETE CASCADE  NOT NULL     ,
+    created_at          timestamp with time zone                                    NOT NULL     ,
+    updated_at          timestamp with time zone                                    NOT NULL     ,
+
+    name                text                                                        NOT NULL     ,
+    visibility          shader_visibility_type                                      NOT NULL     ,
+    description         text                                                        NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS collections_created_by_idx ON collections (created_by, updated_at DESC);
+
+CREATE TABLE IF NOT EXISTS collection_items (
+    collection_id       character(22) REFERENCES collections (collection_id) ON DELETE CASCADE  NOT NULL     ,
+    shader_id           character(22) REFERENCES shaders (shader_id) ON DELETE CASCADE          NOT NULL     ,
+    position            integer                                                                 NOT NULL     ,
+    added_at            timestamp with time zone                                                NOT NULL     ,
+
+    PRIMARY KEY (collection_id, shader_id)
+);
+
+CREATE INDEX IF NOT EXISTS collection_items_position_idx ON collection_items (collection_id, position);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS shader_collaborators;
+DROP TYPE IF EXISTS collaborator_role_type;
@@ -0,0 +1,12 @@
+CREATE TYPE collaborator_role_type as ENUM ('viewer', 'editor');
+
+CREATE TABLE IF NOT EXISTS shader_collaborators (
+    shader_id           character(22) REFERENCES shaders (shader_id) ON DELETE CASCADE  NOT NULL     ,
+    user_id             character(22) REFERENCES users (user_id) ON DELETE CASCADE      NOT NULL     ,
+    created_at          timestamp with time zone                                        NOT NULL     ,
+    role                collaborator_role_type                                          NOT NULL     ,
+
+    PRIMARY KEY (shader_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS shader_collaborators_user_id_idx ON shader_collaborators (user_id);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS share_links (
+    link_id             character(22)                                                   PRIMARY KEY  ,
+    shader_id           character(22) REFERENCES shaders (shader_id) ON DELETE CASCADE  NOT NULL     ,
+    created_by          character(22) REFERENCES users (user_id) ON DELETE CASCADE      NOT NULL     ,
+    created_at          timestamp with time zone                                        NOT NULL     ,
+    expires_at          timestamp with time zone                                        NULL         ,
+    last_used_at        timestamp with time zone                                        NULL         ,
+
+    token_hash          text                                                            NOT NULL     ,
+    revoked_at          timestamp with time zone                                        NULL
+);
+
+ALTER TABLE share_links
+    ADD CONSTRAINT unique_share_link_hash UNIQUE (token_hash);
+
+CREATE INDEX IF NOT EXISTS share_links_shader_id_idx ON share_links (shader_id) WHERE revoked_at IS NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE shaders
+    DROP COLUMN IF EXISTS version;
@@ -0,0 +1,3 @@
+-- bumped on every change to the shader, exposed as its ETag for optimistic concurrency
+ALTER TABLE shaders
+    ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
@@ -0,0 +1,9 @@
+// Package migrations embeds the SQL migrations of the database schema, so they ship inside the server binary
+package migrations
+
+import "embed"
+
+// FS holds the migration files, named '<version>_<name>.up.sql' and '<version>_<name>.down.sql'
+//
+//go:embed *.sql
+var FS embed.FS